@@ -1,14 +1,21 @@
 package main
 
 import (
+	"PeopleCRUD/internal/api/middleware"
 	"PeopleCRUD/internal/api/routes"
+	"PeopleCRUD/internal/auth"
 	"PeopleCRUD/internal/cache"
 	"PeopleCRUD/internal/config"
 	"PeopleCRUD/internal/database"
+	"PeopleCRUD/internal/jobs"
+	"PeopleCRUD/internal/metrics"
+	"PeopleCRUD/internal/ratelimit"
 	"PeopleCRUD/internal/repository"
 	"PeopleCRUD/internal/service"
+	"PeopleCRUD/internal/tracing"
 	"PeopleCRUD/internal/utils"
 	"context"
+	"fmt"
 	"net/http"
 	"os"
 	"os/signal"
@@ -16,6 +23,8 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+	"github.com/sirupsen/logrus"
 )
 
 func main() {
@@ -23,24 +32,108 @@ func main() {
 
 	cfg := config.Load()
 
+	var tracingService string
+	if cfg.Tracing.Enabled {
+		shutdownTracing, err := tracing.Setup(context.Background(), tracing.Options{
+			ServiceName: cfg.Tracing.ServiceName,
+			Exporter:    cfg.Tracing.Exporter,
+			Endpoint:    cfg.Tracing.Endpoint,
+			Insecure:    cfg.Tracing.Insecure,
+			File:        cfg.Tracing.File,
+			SampleRatio: cfg.Tracing.SampleRatio,
+		})
+		if err != nil {
+			logger.Fatal("Failed to initialize tracing:", err)
+		}
+		defer func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if err := shutdownTracing(ctx); err != nil {
+				logger.WithError(err).Warn("Failed to flush traces")
+			}
+		}()
+		tracingService = cfg.Tracing.ServiceName
+	}
+
 	db, err := database.Connect(cfg.DatabaseURL())
 	if err != nil {
 		logger.Fatal("Failed to connect to database:", err)
 	}
 	defer db.Close()
 
-	cacheInst := cache.NewMemoryCache()
+	cacheInst, err := newCache(cfg.Cache, logger)
+	if err != nil {
+		logger.Fatal("Failed to initialize cache:", err)
+	}
+	defer cacheInst.Stop()
+
+	policies := make(map[string]cache.Policy, len(cfg.Cache.Policies))
+	for family, policy := range cfg.Cache.Policies {
+		policies[family] = cache.Policy{TTL: policy.TTL, StaleTTL: policy.StaleTTL, EarlyRefresh: policy.EarlyRefresh}
+	}
+	cacheLoader := cache.NewLoader(cacheInst, policies, logger)
 
 	// Инициализация слоев
 	personRepo := repository.NewPersonRepository(db)
-	personService := service.NewPersonService(personRepo, cacheInst, logger)
+	auditRepo := repository.NewAuditRepository(db)
+	personService := service.NewPersonService(personRepo, auditRepo, cacheLoader, logger)
+	if cfg.Tracing.Enabled {
+		personService = service.WithTracing(personService)
+	}
+	auditService := service.NewAuditService(auditRepo, logger)
+	idempotencyRepo := repository.NewIdempotencyRepository(db)
+	authService := service.NewAuthService(repository.NewAPIKeyRepository(db), cfg.Auth.BootstrapKey, logger)
+
+	authenticator, err := newAuthenticator(cfg.Auth, authService)
+	if err != nil {
+		logger.Fatal("Failed to initialize authentication:", err)
+	}
+	if authenticator == nil {
+		logger.Warn("Authentication is disabled, the API is open to everyone")
+	}
+
+	var limiter *ratelimit.Limiter
+	if cfg.RateLimit.Enabled {
+		store := ratelimit.NewMemoryStore(time.Minute)
+		defer store.Stop()
+		limiter = ratelimit.NewLimiter(store, map[string]ratelimit.Limit{
+			ratelimit.ClassDefault: rateLimit(cfg.RateLimit.Default),
+			ratelimit.ClassWrite:   rateLimit(cfg.RateLimit.Write),
+			ratelimit.ClassEnrich:  rateLimit(cfg.RateLimit.Enrich),
+			ratelimit.ClassIP:      rateLimit(cfg.RateLimit.IP),
+		})
+	}
 
 	if cfg.Environment == "production" {
 		gin.SetMode(gin.ReleaseMode)
 	}
 
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+	jobs.StartPurge(jobsCtx, personService, cfg.Purge.Retention, cfg.Purge.Interval, logger)
+	jobs.StartGraphAnalytics(jobsCtx, personService, cfg.Analytics.Interval, logger)
+	jobs.StartIdempotencyCleanup(jobsCtx, idempotencyRepo, cfg.Idempotency.CleanupInterval, logger)
+	jobs.StartImportReportCleanup(jobsCtx, personRepo, cfg.Import.ReportRetention, cfg.Import.CleanupInterval, logger)
+
+	var metricsHandler http.Handler
+	if cfg.Metrics.Enabled {
+		metrics.RegisterDB(db, "postgres")
+		metrics.RegisterCache(cacheInst)
+		metrics.RegisterPeopleCount(personRepo.CountAllPeople)
+		metricsHandler = metrics.Handler()
+	}
+
 	router := gin.New()
-	routes.SetupRoutes(router, personService, logger)
+	// Без доверенных прокси gin верит X-Forwarded-For от всех, и клиент сменой заголовка обходил бы лимит по IP
+	if err := router.SetTrustedProxies(cfg.Server.TrustedProxies); err != nil {
+		logger.Fatal("Invalid TRUSTED_PROXIES:", err)
+	}
+	routes.SetupRoutes(router, personService, auditService, authService, authenticator, limiter,
+		idempotencyRepo, middleware.IdempotencyOptions{
+			TTL:         cfg.Idempotency.TTL,
+			LockTimeout: cfg.Idempotency.LockTimeout,
+			Wait:        cfg.Idempotency.Wait,
+		}, metricsHandler, tracingService, logger)
 
 	server := &http.Server{
 		Addr:           ":" + cfg.Server.Port,
@@ -64,6 +157,7 @@ func main() {
 	<-quit
 
 	logger.Info("Server shutting down...")
+	stopJobs()
 
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
@@ -74,3 +168,57 @@ func main() {
 
 	logger.Info("Server exited")
 }
+
+func newCache(cfg config.CacheConfig, logger *logrus.Logger) (cache.Cache, error) {
+	switch cfg.Backend {
+	case "memory":
+		return cache.NewMemoryCache(cache.Options{
+			MaxEntries: cfg.MaxEntries,
+			MaxBytes:   cfg.MaxBytes,
+			Shards:     cfg.Shards,
+		}), nil
+	case "redis":
+		options, err := redis.ParseURL(cfg.RedisURL)
+		if err != nil {
+			return nil, err
+		}
+		client := redis.NewClient(options)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := client.Ping(ctx).Err(); err != nil {
+			client.Close()
+			return nil, err
+		}
+		return cache.NewRedisCache(client, cfg.RedisPrefix, logger), nil
+	default:
+		return nil, fmt.Errorf("unknown cache backend: %s", cfg.Backend)
+	}
+}
+
+func rateLimit(rule config.RateLimitRule) ratelimit.Limit {
+	return ratelimit.PerMinute(rule.PerMinute, rule.Burst)
+}
+
+// newAuthenticator возвращает nil, если аутентификация выключена
+func newAuthenticator(cfg config.AuthConfig, keys auth.APIKeyVerifier) (*auth.Authenticator, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+
+	var verifier *auth.JWTVerifier
+	if cfg.JWTSecret != "" || cfg.JWKSFile != "" {
+		var err error
+		verifier, err = auth.NewJWTVerifier(auth.JWTOptions{
+			Secret:   cfg.JWTSecret,
+			JWKSFile: cfg.JWKSFile,
+			Issuer:   cfg.JWTIssuer,
+			Audience: cfg.JWTAudience,
+			Leeway:   cfg.JWTLeeway,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return auth.NewAuthenticator(keys, verifier, cfg.SelfService), nil
+}
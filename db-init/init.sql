@@ -1,5 +1,15 @@
+-- Метки времени хранятся в TIMESTAMP без зоны и читаются приложением как UTC, поэтому база работает в UTC
+DO $$
+BEGIN
+    EXECUTE format('ALTER DATABASE %I SET timezone TO ''UTC''', current_database());
+END $$;
+SET timezone TO 'UTC';
+
+-- Данные людей разделены по тенантам (командам). Составные внешние ключи (id, tenant_id) не дают
+-- связать email или связь с человеком чужого тенанта
 CREATE TABLE IF NOT EXISTS people (
     id SERIAL PRIMARY KEY,
+    tenant_id VARCHAR(64) NOT NULL DEFAULT 'default',
     first_name VARCHAR(100) NOT NULL,
     last_name VARCHAR(100) NOT NULL,
     middle_name VARCHAR(100),
@@ -7,30 +17,100 @@ CREATE TABLE IF NOT EXISTS people (
     gender VARCHAR(10),
     nationality VARCHAR(3),
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP,
+    UNIQUE (id, tenant_id)
     );
 
 CREATE TABLE IF NOT EXISTS emails (
     id SERIAL PRIMARY KEY,
-    person_id INTEGER REFERENCES people(id) ON DELETE CASCADE,
-    email VARCHAR(255) NOT NULL UNIQUE,
+    tenant_id VARCHAR(64) NOT NULL DEFAULT 'default',
+    person_id INTEGER,
+    email VARCHAR(255) NOT NULL,
     is_primary BOOLEAN DEFAULT FALSE,
-    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (tenant_id, email),
+    FOREIGN KEY (person_id, tenant_id) REFERENCES people(id, tenant_id) ON DELETE CASCADE
     );
 
-CREATE TABLE IF NOT EXISTS friendships (
+-- Связи между людьми. Каждая связь хранится в обе стороны: симметричные типы (friend, sibling, spouse,
+-- colleague) с одинаковым type, направленные - парой взаимно обратных (parent/child, manager/report).
+-- type задает роль person_id по отношению к related_id: 'parent' - person_id родитель related_id.
+CREATE TABLE IF NOT EXISTS relationships (
     id SERIAL PRIMARY KEY,
-    person_id INTEGER REFERENCES people(id) ON DELETE CASCADE,
-    friend_id INTEGER REFERENCES people(id) ON DELETE CASCADE,
+    tenant_id VARCHAR(64) NOT NULL DEFAULT 'default',
+    person_id INTEGER NOT NULL,
+    related_id INTEGER NOT NULL,
+    type VARCHAR(20) NOT NULL DEFAULT 'friend'
+        CHECK (type IN ('friend', 'sibling', 'spouse', 'colleague', 'parent', 'child', 'manager', 'report')),
+    -- pending/rejected - заявка в друзья от person_id к related_id, для остальных типов всегда accepted
+    status VARCHAR(10) NOT NULL DEFAULT 'accepted' CHECK (status IN ('pending', 'accepted', 'rejected')),
+    weight REAL NOT NULL DEFAULT 1 CHECK (weight >= 0 AND weight <= 1),
+    started_on DATE,
+    ended_on DATE,
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-    UNIQUE(person_id, friend_id)
+    responded_at TIMESTAMP,
+    UNIQUE(person_id, related_id, type),
+    FOREIGN KEY (person_id, tenant_id) REFERENCES people(id, tenant_id) ON DELETE CASCADE,
+    FOREIGN KEY (related_id, tenant_id) REFERENCES people(id, tenant_id) ON DELETE CASCADE,
+    CHECK (person_id <> related_id),
+    CHECK (ended_on IS NULL OR started_on IS NULL OR ended_on >= started_on)
     );
 
-CREATE INDEX IF NOT EXISTS idx_people_last_name ON people(last_name);
+-- Обновление баз, созданных до разделения по тенантам: существующие данные попадают в тенант по умолчанию
+ALTER TABLE people ADD COLUMN IF NOT EXISTS tenant_id VARCHAR(64) NOT NULL DEFAULT 'default';
+ALTER TABLE emails ADD COLUMN IF NOT EXISTS tenant_id VARCHAR(64) NOT NULL DEFAULT 'default';
+ALTER TABLE relationships ADD COLUMN IF NOT EXISTS tenant_id VARCHAR(64) NOT NULL DEFAULT 'default';
+DO $$
+BEGIN
+IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'people_id_tenant_id_key') THEN
+    ALTER TABLE people ADD CONSTRAINT people_id_tenant_id_key UNIQUE (id, tenant_id);
+END IF;
+IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'emails_tenant_id_email_key') THEN
+    ALTER TABLE emails DROP CONSTRAINT IF EXISTS emails_email_key;
+    ALTER TABLE emails ADD CONSTRAINT emails_tenant_id_email_key UNIQUE (tenant_id, email);
+END IF;
+IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'emails_person_id_tenant_id_fkey') THEN
+    ALTER TABLE emails DROP CONSTRAINT IF EXISTS emails_person_id_fkey;
+    ALTER TABLE emails ADD CONSTRAINT emails_person_id_tenant_id_fkey
+        FOREIGN KEY (person_id, tenant_id) REFERENCES people(id, tenant_id) ON DELETE CASCADE;
+END IF;
+IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'relationships_person_id_tenant_id_fkey') THEN
+    ALTER TABLE relationships DROP CONSTRAINT IF EXISTS relationships_person_id_fkey;
+    ALTER TABLE relationships ADD CONSTRAINT relationships_person_id_tenant_id_fkey
+        FOREIGN KEY (person_id, tenant_id) REFERENCES people(id, tenant_id) ON DELETE CASCADE;
+END IF;
+IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'relationships_related_id_tenant_id_fkey') THEN
+    ALTER TABLE relationships DROP CONSTRAINT IF EXISTS relationships_related_id_fkey;
+    ALTER TABLE relationships ADD CONSTRAINT relationships_related_id_tenant_id_fkey
+        FOREIGN KEY (related_id, tenant_id) REFERENCES people(id, tenant_id) ON DELETE CASCADE;
+END IF;
+END $$;
+
+-- Прежняя таблица дружбы как представление над типом friend. Представление пересоздается:
+-- CREATE OR REPLACE VIEW не позволяет вставить колонку tenant_id перед существующими
+DROP VIEW IF EXISTS friendships;
+CREATE VIEW friendships AS
+SELECT id, tenant_id, person_id, related_id AS friend_id, status, weight, created_at, responded_at
+FROM relationships WHERE type = 'friend';
+
+-- Нормализованное полное имя для поиска дубликатов по триграммам
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+ALTER TABLE people ADD COLUMN IF NOT EXISTS normalized_name TEXT
+    GENERATED ALWAYS AS (translate(lower(first_name || ' ' || last_name), 'ё', 'е')) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_people_tenant ON people(tenant_id, id);
+-- Индексы без tenant_id, оставшиеся от прежней схемы
+DROP INDEX IF EXISTS idx_people_last_name;
+DROP INDEX IF EXISTS idx_emails_lower_email;
+CREATE INDEX IF NOT EXISTS idx_people_tenant_last_name ON people(tenant_id, last_name);
+CREATE INDEX IF NOT EXISTS idx_people_normalized_name_trgm ON people USING gin (normalized_name gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_emails_tenant_lower_email ON emails(tenant_id, lower(email));
 CREATE INDEX IF NOT EXISTS idx_people_full_name ON people(first_name, last_name);
+CREATE INDEX IF NOT EXISTS idx_people_deleted_at ON people(deleted_at) WHERE deleted_at IS NOT NULL;
 CREATE INDEX IF NOT EXISTS idx_emails_person_id ON emails(person_id);
-CREATE INDEX IF NOT EXISTS idx_friendships_person_id ON friendships(person_id);
-CREATE INDEX IF NOT EXISTS idx_friendships_friend_id ON friendships(friend_id);
+CREATE INDEX IF NOT EXISTS idx_relationships_related_id ON relationships(related_id);
+CREATE INDEX IF NOT EXISTS idx_relationships_pending ON relationships(related_id) WHERE status = 'pending';
 
 -- Trigger для автоматического обновления updated_at
 CREATE OR REPLACE FUNCTION update_updated_at_column()
@@ -41,5 +121,212 @@ RETURN NEW;
 END;
 $$ language 'plpgsql';
 
-CREATE TRIGGER update_people_updated_at BEFORE UPDATE ON people
-    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
\ No newline at end of file
+CREATE OR REPLACE TRIGGER update_people_updated_at BEFORE UPDATE ON people
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+-- Журнал аудита: только добавление, изменения и удаление запрещены
+CREATE TABLE IF NOT EXISTS audit_events (
+    id BIGSERIAL PRIMARY KEY,
+    tenant_id VARCHAR(64) NOT NULL DEFAULT 'default',
+    entity_type VARCHAR(20) NOT NULL,
+    entity_id INTEGER NOT NULL,
+    person_id INTEGER NOT NULL,
+    action VARCHAR(20) NOT NULL,
+    actor VARCHAR(255) NOT NULL,
+    request_id VARCHAR(64),
+    changes JSONB,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+    );
+
+ALTER TABLE audit_events ADD COLUMN IF NOT EXISTS tenant_id VARCHAR(64) NOT NULL DEFAULT 'default';
+
+CREATE INDEX IF NOT EXISTS idx_audit_events_person_id ON audit_events(person_id, created_at);
+DROP INDEX IF EXISTS idx_audit_events_created_at;
+CREATE INDEX IF NOT EXISTS idx_audit_events_tenant_created_at ON audit_events(tenant_id, created_at);
+
+CREATE OR REPLACE RULE audit_events_no_update AS ON UPDATE TO audit_events DO INSTEAD NOTHING;
+CREATE OR REPLACE RULE audit_events_no_delete AS ON DELETE TO audit_events DO INSTEAD NOTHING;
+
+
+-- Версионированная история: каждая строка действует в интервале [valid_from, valid_to)
+CREATE TABLE IF NOT EXISTS people_history (
+    person_id INTEGER NOT NULL,
+    tenant_id VARCHAR(64) NOT NULL,
+    version INTEGER NOT NULL,
+    first_name VARCHAR(100) NOT NULL,
+    last_name VARCHAR(100) NOT NULL,
+    middle_name VARCHAR(100),
+    age INTEGER,
+    gender VARCHAR(10),
+    nationality VARCHAR(3),
+    created_at TIMESTAMP,
+    updated_at TIMESTAMP,
+    deleted_at TIMESTAMP,
+    valid_from TIMESTAMP NOT NULL,
+    valid_to TIMESTAMP,
+    PRIMARY KEY (person_id, version)
+    );
+
+-- Для баз до разделения по тенантам: прежние версии относятся к тенанту по умолчанию
+ALTER TABLE people_history ADD COLUMN IF NOT EXISTS tenant_id VARCHAR(64) NOT NULL DEFAULT 'default';
+ALTER TABLE people_history ALTER COLUMN tenant_id DROP DEFAULT;
+
+CREATE TABLE IF NOT EXISTS emails_history (
+    id BIGSERIAL PRIMARY KEY,
+    email_id INTEGER NOT NULL,
+    person_id INTEGER NOT NULL,
+    email VARCHAR(255) NOT NULL,
+    is_primary BOOLEAN,
+    valid_from TIMESTAMP NOT NULL,
+    valid_to TIMESTAMP
+    );
+
+CREATE TABLE IF NOT EXISTS relationships_history (
+    id BIGSERIAL PRIMARY KEY,
+    person_id INTEGER NOT NULL,
+    related_id INTEGER NOT NULL,
+    type VARCHAR(20) NOT NULL,
+    valid_from TIMESTAMP NOT NULL,
+    valid_to TIMESTAMP
+    );
+
+CREATE INDEX IF NOT EXISTS idx_people_history_valid ON people_history(person_id, valid_from);
+CREATE INDEX IF NOT EXISTS idx_emails_history_person ON emails_history(person_id, valid_from);
+CREATE INDEX IF NOT EXISTS idx_relationships_history_person ON relationships_history(person_id, valid_from);
+
+CREATE OR REPLACE FUNCTION people_history_trigger()
+RETURNS TRIGGER AS $$
+BEGIN
+IF TG_OP <> 'INSERT' THEN
+    UPDATE people_history SET valid_to = CURRENT_TIMESTAMP
+    WHERE person_id = OLD.id AND valid_to IS NULL;
+END IF;
+
+IF TG_OP = 'DELETE' THEN
+    RETURN OLD;
+END IF;
+
+INSERT INTO people_history (person_id, tenant_id, version, first_name, last_name, middle_name, age, gender,
+                            nationality, created_at, updated_at, deleted_at, valid_from)
+SELECT NEW.id, NEW.tenant_id, COALESCE(MAX(version), 0) + 1, NEW.first_name, NEW.last_name, NEW.middle_name, NEW.age,
+       NEW.gender, NEW.nationality, NEW.created_at, NEW.updated_at, NEW.deleted_at, CURRENT_TIMESTAMP
+FROM people_history WHERE person_id = NEW.id;
+RETURN NEW;
+END;
+$$ language 'plpgsql';
+
+CREATE OR REPLACE FUNCTION emails_history_trigger()
+RETURNS TRIGGER AS $$
+BEGIN
+IF TG_OP <> 'INSERT' THEN
+    UPDATE emails_history SET valid_to = CURRENT_TIMESTAMP
+    WHERE email_id = OLD.id AND valid_to IS NULL;
+END IF;
+
+IF TG_OP = 'DELETE' THEN
+    RETURN OLD;
+END IF;
+
+INSERT INTO emails_history (email_id, person_id, email, is_primary, valid_from)
+VALUES (NEW.id, NEW.person_id, NEW.email, NEW.is_primary, CURRENT_TIMESTAMP);
+RETURN NEW;
+END;
+$$ language 'plpgsql';
+
+-- В историю попадают только принятые дружбы, заявки в ней не отражаются
+CREATE OR REPLACE FUNCTION relationships_history_trigger()
+RETURNS TRIGGER AS $$
+BEGIN
+IF TG_OP <> 'INSERT' AND OLD.status = 'accepted' THEN
+    UPDATE relationships_history SET valid_to = CURRENT_TIMESTAMP
+    WHERE person_id = OLD.person_id AND related_id = OLD.related_id AND type = OLD.type AND valid_to IS NULL;
+END IF;
+
+IF TG_OP = 'DELETE' THEN
+    RETURN OLD;
+END IF;
+
+IF NEW.status = 'accepted' THEN
+    INSERT INTO relationships_history (person_id, related_id, type, valid_from)
+    VALUES (NEW.person_id, NEW.related_id, NEW.type, CURRENT_TIMESTAMP);
+END IF;
+RETURN NEW;
+END;
+$$ language 'plpgsql';
+
+CREATE OR REPLACE TRIGGER people_history_changes AFTER INSERT OR UPDATE OR DELETE ON people
+    FOR EACH ROW EXECUTE FUNCTION people_history_trigger();
+
+CREATE OR REPLACE TRIGGER emails_history_changes AFTER INSERT OR UPDATE OR DELETE ON emails
+    FOR EACH ROW EXECUTE FUNCTION emails_history_trigger();
+
+CREATE OR REPLACE TRIGGER relationships_history_changes AFTER INSERT OR UPDATE OF status OR DELETE ON relationships
+    FOR EACH ROW EXECUTE FUNCTION relationships_history_trigger();
+
+
+-- Ключи API: хранится только SHA-256 ключа, префикс открыт и служит для поиска
+CREATE TABLE IF NOT EXISTS api_keys (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    prefix VARCHAR(16) NOT NULL UNIQUE,
+    key_hash CHAR(64) NOT NULL,
+    role VARCHAR(10) NOT NULL DEFAULT 'reader' CHECK (role IN ('reader', 'editor', 'admin')),
+    -- Ключ действует только в своем тенанте. Выбирать тенант заголовком X-Tenant-ID может лишь ключ начальной настройки
+    tenant_id VARCHAR(64) NOT NULL DEFAULT 'default',
+    person_id INTEGER,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    last_used_at TIMESTAMP,
+    revoked_at TIMESTAMP,
+    FOREIGN KEY (person_id, tenant_id) REFERENCES people(id, tenant_id) ON DELETE SET NULL (person_id)
+    );
+
+-- Обновление баз, созданных до разделения по тенантам. Ключи без тенанта попадают в тенант по умолчанию,
+-- связь с человеком из другого тенанта снимается
+ALTER TABLE api_keys ADD COLUMN IF NOT EXISTS tenant_id VARCHAR(64);
+UPDATE api_keys SET tenant_id = 'default' WHERE tenant_id IS NULL;
+ALTER TABLE api_keys ALTER COLUMN tenant_id SET DEFAULT 'default', ALTER COLUMN tenant_id SET NOT NULL;
+DO $$
+BEGIN
+IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'api_keys_person_id_tenant_id_fkey') THEN
+    ALTER TABLE api_keys DROP CONSTRAINT IF EXISTS api_keys_person_id_fkey;
+    UPDATE api_keys k SET person_id = NULL
+    WHERE person_id IS NOT NULL
+      AND NOT EXISTS (SELECT 1 FROM people p WHERE p.id = k.person_id AND p.tenant_id = k.tenant_id);
+    ALTER TABLE api_keys ADD CONSTRAINT api_keys_person_id_tenant_id_fkey
+        FOREIGN KEY (person_id, tenant_id) REFERENCES people(id, tenant_id) ON DELETE SET NULL (person_id);
+END IF;
+END $$;
+
+-- Ответы на POST-запросы с заголовком Idempotency-Key. scope - тенант и вызывающий, чтобы ключи разных
+-- клиентов не пересекались. status_code IS NULL - запрос еще выполняется, expires_at для него - срок блокировки
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    scope VARCHAR(400) NOT NULL,
+    idempotency_key VARCHAR(255) NOT NULL,
+    fingerprint CHAR(64) NOT NULL,
+    status_code INTEGER,
+    content_type VARCHAR(255),
+    body BYTEA,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (scope, idempotency_key)
+    );
+
+CREATE INDEX IF NOT EXISTS idx_idempotency_keys_expires_at ON idempotency_keys(expires_at);
+
+-- Последний результат задания аналитики графа дружбы по тенантам. Запрос метрик только читает его
+CREATE TABLE IF NOT EXISTS graph_analytics (
+    tenant_id VARCHAR(64) PRIMARY KEY,
+    result JSONB NOT NULL,
+    computed_at TIMESTAMP NOT NULL
+    );
+
+-- Отчеты об отклоненных строках импорта. Хранятся IMPORT_REPORT_RETENTION, старые удаляет фоновое задание
+CREATE TABLE IF NOT EXISTS import_reports (
+    tenant_id VARCHAR(64) NOT NULL,
+    id CHAR(32) NOT NULL,
+    rejections JSONB NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (tenant_id, id)
+    );
+
+CREATE INDEX IF NOT EXISTS idx_import_reports_created_at ON import_reports(created_at);
@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"PeopleCRUD/internal/models"
+	"PeopleCRUD/internal/service"
+	"PeopleCRUD/pkg/errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+type AuditHandler struct {
+	service service.AuditService
+	logger  *logrus.Logger
+}
+
+func NewAuditHandler(service service.AuditService, logger *logrus.Logger) *AuditHandler {
+	return &AuditHandler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// GetPersonHistory - GET /api/v1/people/:id/history
+func (h *AuditHandler) GetPersonHistory(c *gin.Context) {
+	personID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		requestLog(c, h.logger).WithField("id", c.Param("id")).Warn("Invalid person ID format")
+		writeError(c, errors.NewValidationError("Invalid person ID"))
+		return
+	}
+
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+
+	ctx := c.Request.Context()
+	events, total, err := h.service.GetPersonHistory(ctx, personID, limit, offset)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":   events,
+		"total":  total,
+		"limit":  limit,
+		"offset": offset,
+	})
+}
+
+// GetEvents - GET /api/v1/audit
+func (h *AuditHandler) GetEvents(c *gin.Context) {
+	filter := models.AuditFilter{
+		EntityType: c.Query("entity_type"),
+		Action:     c.Query("action"),
+		Actor:      c.Query("actor"),
+		RequestID:  c.Query("request_id"),
+	}
+	filter.Limit, _ = strconv.Atoi(c.DefaultQuery("limit", "50"))
+	filter.Offset, _ = strconv.Atoi(c.DefaultQuery("offset", "0"))
+
+	if value := c.Query("person_id"); value != "" {
+		personID, err := strconv.Atoi(value)
+		if err != nil {
+			writeError(c, errors.NewValidationError("Invalid person_id"))
+			return
+		}
+		filter.PersonID = &personID
+	}
+
+	for param, target := range map[string]**time.Time{"from": &filter.From, "to": &filter.To} {
+		value := c.Query(param)
+		if value == "" {
+			continue
+		}
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			writeError(c, errors.NewValidationError("Invalid "+param+": expected RFC3339 timestamp"))
+			return
+		}
+		*target = &parsed
+	}
+
+	ctx := c.Request.Context()
+	events, total, err := h.service.GetEvents(ctx, filter)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":   events,
+		"total":  total,
+		"limit":  filter.Limit,
+		"offset": filter.Offset,
+	})
+}
+
+func (h *AuditHandler) handleError(c *gin.Context, err error) {
+	respondError(c, h.logger, err)
+}
@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"PeopleCRUD/internal/models"
+	"PeopleCRUD/internal/service"
+	"PeopleCRUD/pkg/errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+type AuthHandler struct {
+	service service.AuthService
+	logger  *logrus.Logger
+}
+
+func NewAuthHandler(service service.AuthService, logger *logrus.Logger) *AuthHandler {
+	return &AuthHandler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// CreateAPIKey - POST /api/v1/admin/api-keys
+func (h *AuthHandler) CreateAPIKey(c *gin.Context) {
+	var req models.CreateAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		requestLog(c, h.logger).WithError(err).Error("Failed to bind JSON")
+		writeError(c, errors.NewValidationError(err.Error()))
+		return
+	}
+
+	ctx := c.Request.Context()
+	key, err := h.service.CreateAPIKey(ctx, &req)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, key)
+}
+
+// GetAPIKeys - GET /api/v1/admin/api-keys
+func (h *AuthHandler) GetAPIKeys(c *gin.Context) {
+	ctx := c.Request.Context()
+	keys, err := h.service.GetAPIKeys(ctx)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": keys})
+}
+
+// RevokeAPIKey - DELETE /api/v1/admin/api-keys/:keyId
+func (h *AuthHandler) RevokeAPIKey(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("keyId"))
+	if err != nil {
+		writeError(c, errors.NewValidationError("Invalid API key ID"))
+		return
+	}
+
+	ctx := c.Request.Context()
+	if err := h.service.RevokeAPIKey(ctx, id); err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (h *AuthHandler) handleError(c *gin.Context, err error) {
+	respondError(c, h.logger, err)
+}
@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"PeopleCRUD/internal/models"
+	"PeopleCRUD/pkg/errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// BulkCreatePeople - POST /api/v1/people/bulk?mode=all_or_nothing|best_effort
+func (h *PeopleHandler) BulkCreatePeople(c *gin.Context) {
+	var reqs []models.CreatePersonRequest
+	if err := c.ShouldBindJSON(&reqs); err != nil {
+		requestLog(c, h.logger).WithError(err).Error("Failed to bind JSON")
+		writeError(c, errors.NewValidationError(err.Error()))
+		return
+	}
+
+	ctx := c.Request.Context()
+	result, err := h.service.BulkCreatePeople(ctx, reqs, bulkMode(c))
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(bulkStatus(result, http.StatusCreated), result)
+}
+
+// BulkUpdatePeople - PUT /api/v1/people/bulk?mode=all_or_nothing|best_effort
+func (h *PeopleHandler) BulkUpdatePeople(c *gin.Context) {
+	var items []models.BulkUpdateItem
+	if err := c.ShouldBindJSON(&items); err != nil {
+		requestLog(c, h.logger).WithError(err).Error("Failed to bind JSON")
+		writeError(c, errors.NewValidationError(err.Error()))
+		return
+	}
+
+	ctx := c.Request.Context()
+	result, err := h.service.BulkUpdatePeople(ctx, items, bulkMode(c))
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(bulkStatus(result, http.StatusOK), result)
+}
+
+// BulkDeletePeople - DELETE /api/v1/people/bulk?mode=all_or_nothing|best_effort
+func (h *PeopleHandler) BulkDeletePeople(c *gin.Context) {
+	var req models.BulkDeleteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		requestLog(c, h.logger).WithError(err).Error("Failed to bind JSON")
+		writeError(c, errors.NewValidationError(err.Error()))
+		return
+	}
+
+	ctx := c.Request.Context()
+	result, err := h.service.BulkDeletePeople(ctx, req.IDs, bulkMode(c))
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(bulkStatus(result, http.StatusOK), result)
+}
+
+func bulkMode(c *gin.Context) string {
+	return c.DefaultQuery("mode", models.BulkModeAtomic)
+}
+
+// bulkStatus: полный успех - successStatus, частичный - 207, ничего не применено - 422
+func bulkStatus(result *models.BulkResponse, successStatus int) int {
+	switch {
+	case result.Failed == 0:
+		return successStatus
+	case result.Succeeded == 0:
+		return http.StatusUnprocessableEntity
+	default:
+		return http.StatusMultiStatus
+	}
+}
@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"PeopleCRUD/internal/exporter"
+	"PeopleCRUD/pkg/errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ExportPeople - GET /api/v1/people/export?format=csv|ndjson|xlsx
+// Принимает те же фильтры, что и листинг. Строки пишутся в ответ по мере чтения из курсора.
+func (h *PeopleHandler) ExportPeople(c *gin.Context) {
+	format := c.DefaultQuery("format", exporter.FormatCSV)
+	if !exporter.Supported(format) {
+		writeError(c, errors.NewValidationError("format must be csv, ndjson or xlsx"))
+		return
+	}
+
+	filter, filterErr := parsePeopleFilter(c)
+	if filterErr != nil {
+		writeError(c, filterErr)
+		return
+	}
+
+	// Выгрузка может идти дольше общего WriteTimeout сервера
+	if err := http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{}); err != nil {
+		requestLog(c, h.logger).WithError(err).Warn("Failed to reset write deadline for export")
+	}
+
+	c.Header("Content-Type", exporter.ContentType(format))
+	c.Header("Content-Disposition", `attachment; filename="people-`+time.Now().Format("20060102-150405")+`.`+format+`"`)
+	c.Status(http.StatusOK)
+
+	writer, err := exporter.NewWriter(format, c.Writer)
+	if err != nil {
+		requestLog(c, h.logger).WithError(err).Error("Failed to start export")
+		return
+	}
+
+	ctx := c.Request.Context()
+	if err := h.service.ExportPeople(ctx, filter, writer.Write); err != nil {
+		// Статус уже отправлен: обрываем поток без закрывающей части формата,
+		// чтобы клиент не принял неполную выгрузку за целую
+		requestLog(c, h.logger).WithError(err).Error("Export interrupted")
+		c.Abort()
+		return
+	}
+
+	if err := writer.Close(); err != nil {
+		requestLog(c, h.logger).WithError(err).Error("Failed to finish export")
+	}
+}
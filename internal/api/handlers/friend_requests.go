@@ -0,0 +1,130 @@
+package handlers
+
+import (
+	"PeopleCRUD/pkg/errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SendFriendRequest - POST /api/v1/people/:id/friend-requests/:friendId
+func (h *PeopleHandler) SendFriendRequest(c *gin.Context) {
+	personID, friendID, ok := h.friendRequestIDs(c)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	request, err := h.service.SendFriendRequest(ctx, personID, friendID)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, request)
+}
+
+// AcceptFriendRequest - POST /api/v1/people/:id/friend-requests/:friendId/accept
+func (h *PeopleHandler) AcceptFriendRequest(c *gin.Context) {
+	personID, friendID, ok := h.friendRequestIDs(c)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	if err := h.service.AcceptFriendRequest(ctx, personID, friendID); err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// RejectFriendRequest - POST /api/v1/people/:id/friend-requests/:friendId/reject
+func (h *PeopleHandler) RejectFriendRequest(c *gin.Context) {
+	personID, friendID, ok := h.friendRequestIDs(c)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	if err := h.service.RejectFriendRequest(ctx, personID, friendID); err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// CancelFriendRequest - DELETE /api/v1/people/:id/friend-requests/:friendId
+func (h *PeopleHandler) CancelFriendRequest(c *gin.Context) {
+	personID, friendID, ok := h.friendRequestIDs(c)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	if err := h.service.CancelFriendRequest(ctx, personID, friendID); err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// GetIncomingFriendRequests - GET /api/v1/people/:id/friend-requests/incoming
+func (h *PeopleHandler) GetIncomingFriendRequests(c *gin.Context) {
+	personID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		requestLog(c, h.logger).WithField("id", c.Param("id")).Warn("Invalid person ID format")
+		writeError(c, errors.NewValidationError("Invalid person ID"))
+		return
+	}
+
+	ctx := c.Request.Context()
+	requests, err := h.service.GetIncomingFriendRequests(ctx, personID)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, requests)
+}
+
+// GetOutgoingFriendRequests - GET /api/v1/people/:id/friend-requests/outgoing
+func (h *PeopleHandler) GetOutgoingFriendRequests(c *gin.Context) {
+	personID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		requestLog(c, h.logger).WithField("id", c.Param("id")).Warn("Invalid person ID format")
+		writeError(c, errors.NewValidationError("Invalid person ID"))
+		return
+	}
+
+	ctx := c.Request.Context()
+	requests, err := h.service.GetOutgoingFriendRequests(ctx, personID)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, requests)
+}
+
+func (h *PeopleHandler) friendRequestIDs(c *gin.Context) (int, int, bool) {
+	personID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		requestLog(c, h.logger).WithField("id", c.Param("id")).Warn("Invalid person ID format")
+		writeError(c, errors.NewValidationError("Invalid person ID"))
+		return 0, 0, false
+	}
+
+	friendID, err := strconv.Atoi(c.Param("friendId"))
+	if err != nil {
+		requestLog(c, h.logger).WithField("friendId", c.Param("friendId")).Warn("Invalid friend ID format")
+		writeError(c, errors.NewValidationError("Invalid friend ID"))
+		return 0, 0, false
+	}
+
+	return personID, friendID, true
+}
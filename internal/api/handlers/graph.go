@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"PeopleCRUD/pkg/errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetMutualFriends - GET /api/v1/people/:id/friends/mutual/:otherId
+func (h *PeopleHandler) GetMutualFriends(c *gin.Context) {
+	personID, otherID, ok := h.personPairIDs(c)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	friends, err := h.service.GetMutualFriends(ctx, personID, otherID)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, friends)
+}
+
+// GetFriendPath - GET /api/v1/people/:id/path/:otherId?max_depth=
+func (h *PeopleHandler) GetFriendPath(c *gin.Context) {
+	personID, otherID, ok := h.personPairIDs(c)
+	if !ok {
+		return
+	}
+
+	maxDepth, err := strconv.Atoi(c.DefaultQuery("max_depth", "0"))
+	if err != nil {
+		writeError(c, errors.NewValidationError("Invalid max_depth"))
+		return
+	}
+
+	ctx := c.Request.Context()
+	path, err := h.service.GetFriendPath(ctx, personID, otherID, maxDepth)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, path)
+}
+
+// GetFriendSuggestions - GET /api/v1/people/:id/suggestions?limit=
+func (h *PeopleHandler) GetFriendSuggestions(c *gin.Context) {
+	personID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		requestLog(c, h.logger).WithField("id", c.Param("id")).Warn("Invalid person ID format")
+		writeError(c, errors.NewValidationError("Invalid person ID"))
+		return
+	}
+
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+
+	ctx := c.Request.Context()
+	suggestions, err := h.service.GetFriendSuggestions(ctx, personID, limit)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, suggestions)
+}
+
+func (h *PeopleHandler) personPairIDs(c *gin.Context) (int, int, bool) {
+	personID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		requestLog(c, h.logger).WithField("id", c.Param("id")).Warn("Invalid person ID format")
+		writeError(c, errors.NewValidationError("Invalid person ID"))
+		return 0, 0, false
+	}
+
+	otherID, err := strconv.Atoi(c.Param("otherId"))
+	if err != nil {
+		requestLog(c, h.logger).WithField("otherId", c.Param("otherId")).Warn("Invalid other person ID format")
+		writeError(c, errors.NewValidationError("Invalid other person ID"))
+		return 0, 0, false
+	}
+
+	return personID, otherID, true
+}
+
+// GetGraphAnalytics - GET /api/v1/graph/analytics?top=
+// Метрики считаются фоновым заданием, запрос отдает последний сохраненный результат, до первого расчета - 503.
+func (h *PeopleHandler) GetGraphAnalytics(c *gin.Context) {
+	top, _ := strconv.Atoi(c.DefaultQuery("top", "10"))
+
+	ctx := c.Request.Context()
+	analytics, err := h.service.GetGraphAnalytics(ctx, top)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, analytics)
+}
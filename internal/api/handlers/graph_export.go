@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"PeopleCRUD/internal/exporter"
+	"PeopleCRUD/internal/models"
+	"PeopleCRUD/pkg/errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ExportGraph - GET /api/v1/graph/export?format=graphml|dot|jsongraph&person_id=&depth=
+// С person_id выгружается только окрестность человека радиусом depth шагов дружбы.
+func (h *PeopleHandler) ExportGraph(c *gin.Context) {
+	format := c.DefaultQuery("format", exporter.GraphFormatGraphML)
+	if !exporter.GraphSupported(format) {
+		writeError(c, errors.NewValidationError("format must be graphml, dot or jsongraph"))
+		return
+	}
+
+	var egoID *int
+	if raw := c.Query("person_id"); raw != "" {
+		id, err := strconv.Atoi(raw)
+		if err != nil {
+			writeError(c, errors.NewValidationError("Invalid person_id"))
+			return
+		}
+		egoID = &id
+	}
+
+	depth, err := strconv.Atoi(c.DefaultQuery("depth", "0"))
+	if err != nil {
+		writeError(c, errors.NewValidationError("Invalid depth"))
+		return
+	}
+
+	if err := http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{}); err != nil {
+		requestLog(c, h.logger).WithError(err).Warn("Failed to reset write deadline for graph export")
+	}
+
+	// Ответ начинается с первой вершины, чтобы ошибки проверки ушли обычным JSON
+	var writer exporter.GraphWriter
+	start := func() error {
+		if writer != nil {
+			return nil
+		}
+		c.Header("Content-Type", exporter.GraphContentType(format))
+		c.Header("Content-Disposition", `attachment; filename="graph-`+time.Now().Format("20060102-150405")+`.`+exporter.GraphFileExtension(format)+`"`)
+		c.Status(http.StatusOK)
+
+		var err error
+		writer, err = exporter.NewGraphWriter(format, c.Writer)
+		return err
+	}
+
+	nodeFn := func(person *models.Person) error {
+		if err := start(); err != nil {
+			return err
+		}
+		return writer.WriteNode(person)
+	}
+	edgeFn := func(edge *models.GraphEdge) error {
+		if err := start(); err != nil {
+			return err
+		}
+		return writer.WriteEdge(edge)
+	}
+
+	ctx := c.Request.Context()
+	if err := h.service.ExportGraph(ctx, egoID, depth, nodeFn, edgeFn); err != nil {
+		if writer == nil {
+			h.handleError(c, err)
+			return
+		}
+		requestLog(c, h.logger).WithError(err).Error("Graph export interrupted")
+		c.Abort()
+		return
+	}
+
+	if err := start(); err != nil {
+		requestLog(c, h.logger).WithError(err).Error("Failed to start graph export")
+		return
+	}
+	if err := writer.Close(); err != nil {
+		requestLog(c, h.logger).WithError(err).Error("Failed to finish graph export")
+	}
+}
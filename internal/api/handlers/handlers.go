@@ -2,10 +2,12 @@ package handlers
 
 import (
 	"PeopleCRUD/internal/models"
+	"PeopleCRUD/internal/reqctx"
 	"PeopleCRUD/internal/service"
 	"PeopleCRUD/pkg/errors"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
@@ -23,22 +25,30 @@ func NewPeopleHandler(service service.PersonService, logger *logrus.Logger) *Peo
 	}
 }
 
-// CreatePerson - POST /api/v1/people
+// CreatePerson - POST /api/v1/people?on_duplicate=warn|reject|ignore
 func (h *PeopleHandler) CreatePerson(c *gin.Context) {
+	onDuplicate := c.DefaultQuery("on_duplicate", models.OnDuplicateWarn)
+	switch onDuplicate {
+	case models.OnDuplicateWarn, models.OnDuplicateReject, models.OnDuplicateIgnore:
+	default:
+		writeError(c, errors.NewValidationError("on_duplicate must be warn, reject or ignore"))
+		return
+	}
+
 	var req models.CreatePersonRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		h.logger.WithError(err).Error("Failed to bind JSON")
-		c.JSON(http.StatusBadRequest, errors.NewValidationError(err.Error()))
+		requestLog(c, h.logger).WithError(err).Error("Failed to bind JSON")
+		writeError(c, errors.NewValidationError(err.Error()))
 		return
 	}
 
 	if err := req.Validate(); err != nil {
-		c.JSON(http.StatusBadRequest, err)
+		writeError(c, err)
 		return
 	}
 
 	ctx := c.Request.Context()
-	person, err := h.service.CreatePerson(ctx, &req)
+	person, err := h.service.CreatePerson(ctx, &req, onDuplicate)
 	if err != nil {
 		h.handleError(c, err)
 		return
@@ -47,16 +57,32 @@ func (h *PeopleHandler) CreatePerson(c *gin.Context) {
 	c.JSON(http.StatusCreated, person)
 }
 
-// GetPerson - GET /api/v1/people/:id
+// GetPerson - GET /api/v1/people/:id[?as_of=RFC3339]
 func (h *PeopleHandler) GetPerson(c *gin.Context) {
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		h.logger.WithField("id", c.Param("id")).Warn("Invalid person ID format")
-		c.JSON(http.StatusBadRequest, errors.NewValidationError("Invalid person ID"))
+		requestLog(c, h.logger).WithField("id", c.Param("id")).Warn("Invalid person ID format")
+		writeError(c, errors.NewValidationError("Invalid person ID"))
 		return
 	}
 
 	ctx := c.Request.Context()
+	if value := c.Query("as_of"); value != "" {
+		asOf, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			writeError(c, errors.NewValidationError("Invalid as_of: expected RFC3339 timestamp"))
+			return
+		}
+
+		person, err := h.service.GetPersonAsOf(ctx, id, asOf)
+		if err != nil {
+			h.handleError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, person)
+		return
+	}
+
 	person, err := h.service.GetPersonByID(ctx, id)
 	if err != nil {
 		h.handleError(c, err)
@@ -70,7 +96,7 @@ func (h *PeopleHandler) GetPerson(c *gin.Context) {
 func (h *PeopleHandler) GetPeopleByLastName(c *gin.Context) {
 	lastName := c.Param("lastname")
 	if lastName == "" {
-		c.JSON(http.StatusBadRequest, errors.NewValidationError("Last name is required"))
+		writeError(c, errors.NewValidationError("Last name is required"))
 		return
 	}
 
@@ -89,8 +115,14 @@ func (h *PeopleHandler) GetAllPeople(c *gin.Context) {
 	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
 	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
 
+	filter, filterErr := parsePeopleFilter(c)
+	if filterErr != nil {
+		writeError(c, filterErr)
+		return
+	}
+
 	ctx := c.Request.Context()
-	people, total, err := h.service.GetAllPeople(ctx, limit, offset)
+	people, total, err := h.service.GetAllPeople(ctx, filter, limit, offset)
 	if err != nil {
 		h.handleError(c, err)
 		return
@@ -108,20 +140,20 @@ func (h *PeopleHandler) GetAllPeople(c *gin.Context) {
 func (h *PeopleHandler) UpdatePerson(c *gin.Context) {
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		h.logger.WithField("id", c.Param("id")).Warn("Invalid person ID format")
-		c.JSON(http.StatusBadRequest, errors.NewValidationError("Invalid person ID"))
+		requestLog(c, h.logger).WithField("id", c.Param("id")).Warn("Invalid person ID format")
+		writeError(c, errors.NewValidationError("Invalid person ID"))
 		return
 	}
 
 	var req models.UpdatePersonRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		h.logger.WithError(err).Error("Failed to bind JSON")
-		c.JSON(http.StatusBadRequest, errors.NewValidationError(err.Error()))
+		requestLog(c, h.logger).WithError(err).Error("Failed to bind JSON")
+		writeError(c, errors.NewValidationError(err.Error()))
 		return
 	}
 
 	if err := req.Validate(); err != nil {
-		c.JSON(http.StatusBadRequest, err)
+		writeError(c, err)
 		return
 	}
 
@@ -139,8 +171,8 @@ func (h *PeopleHandler) UpdatePerson(c *gin.Context) {
 func (h *PeopleHandler) DeletePerson(c *gin.Context) {
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		h.logger.WithField("id", c.Param("id")).Warn("Invalid person ID format")
-		c.JSON(http.StatusBadRequest, errors.NewValidationError("Invalid person ID"))
+		requestLog(c, h.logger).WithField("id", c.Param("id")).Warn("Invalid person ID format")
+		writeError(c, errors.NewValidationError("Invalid person ID"))
 		return
 	}
 
@@ -157,15 +189,15 @@ func (h *PeopleHandler) DeletePerson(c *gin.Context) {
 func (h *PeopleHandler) AddEmail(c *gin.Context) {
 	personID, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		h.logger.WithField("id", c.Param("id")).Warn("Invalid person ID format")
-		c.JSON(http.StatusBadRequest, errors.NewValidationError("Invalid person ID"))
+		requestLog(c, h.logger).WithField("id", c.Param("id")).Warn("Invalid person ID format")
+		writeError(c, errors.NewValidationError("Invalid person ID"))
 		return
 	}
 
 	var req models.AddEmailRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		h.logger.WithError(err).Error("Failed to bind JSON")
-		c.JSON(http.StatusBadRequest, errors.NewValidationError(err.Error()))
+		requestLog(c, h.logger).WithError(err).Error("Failed to bind JSON")
+		writeError(c, errors.NewValidationError(err.Error()))
 		return
 	}
 
@@ -178,87 +210,225 @@ func (h *PeopleHandler) AddEmail(c *gin.Context) {
 	c.Status(http.StatusCreated)
 }
 
-// AddFriend - POST /api/v1/people/:id/friends/:friendId
-func (h *PeopleHandler) AddFriend(c *gin.Context) {
+// GetFriends - GET /api/v1/people/:id/friends
+func (h *PeopleHandler) GetFriends(c *gin.Context) {
 	personID, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		h.logger.WithField("id", c.Param("id")).Warn("Invalid person ID format")
-		c.JSON(http.StatusBadRequest, errors.NewValidationError("Invalid person ID"))
+		requestLog(c, h.logger).WithField("id", c.Param("id")).Warn("Invalid person ID format")
+		writeError(c, errors.NewValidationError("Invalid person ID"))
+		return
+	}
+
+	ctx := c.Request.Context()
+	friends, err := h.service.GetFriends(ctx, personID)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, friends)
+}
+
+// RemoveFriend - DELETE /api/v1/people/:id/friends/:friendId
+func (h *PeopleHandler) RemoveFriend(c *gin.Context) {
+	personID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		requestLog(c, h.logger).WithField("id", c.Param("id")).Warn("Invalid person ID format")
+		writeError(c, errors.NewValidationError("Invalid person ID"))
 		return
 	}
 
 	friendID, err := strconv.Atoi(c.Param("friendId"))
 	if err != nil {
-		h.logger.WithField("friendId", c.Param("friendId")).Warn("Invalid friend ID format")
-		c.JSON(http.StatusBadRequest, errors.NewValidationError("Invalid friend ID"))
+		requestLog(c, h.logger).WithField("friendId", c.Param("friendId")).Warn("Invalid friend ID format")
+		writeError(c, errors.NewValidationError("Invalid friend ID"))
+		return
+	}
+
+	ctx := c.Request.Context()
+	if err := h.service.RemoveFriend(ctx, personID, friendID); err != nil {
+		h.handleError(c, err)
 		return
 	}
 
+	c.Status(http.StatusNoContent)
+}
+
+// GetTrash - GET /api/v1/people/trash
+func (h *PeopleHandler) GetTrash(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+
 	ctx := c.Request.Context()
-	if err := h.service.AddFriend(ctx, personID, friendID); err != nil {
+	people, total, err := h.service.GetDeletedPeople(ctx, limit, offset)
+	if err != nil {
 		h.handleError(c, err)
 		return
 	}
 
-	c.Status(http.StatusCreated)
+	c.JSON(http.StatusOK, gin.H{
+		"data":   people,
+		"total":  total,
+		"limit":  limit,
+		"offset": offset,
+	})
 }
 
-// GetFriends - GET /api/v1/people/:id/friends
-func (h *PeopleHandler) GetFriends(c *gin.Context) {
-	personID, err := strconv.Atoi(c.Param("id"))
+// RestorePerson - POST /api/v1/people/:id/restore
+func (h *PeopleHandler) RestorePerson(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		h.logger.WithField("id", c.Param("id")).Warn("Invalid person ID format")
-		c.JSON(http.StatusBadRequest, errors.NewValidationError("Invalid person ID"))
+		requestLog(c, h.logger).WithField("id", c.Param("id")).Warn("Invalid person ID format")
+		writeError(c, errors.NewValidationError("Invalid person ID"))
 		return
 	}
 
 	ctx := c.Request.Context()
-	friends, err := h.service.GetFriends(ctx, personID)
+	person, err := h.service.RestorePerson(ctx, id)
 	if err != nil {
 		h.handleError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, friends)
+	c.JSON(http.StatusOK, person)
 }
 
-// RemoveFriend - DELETE /api/v1/people/:id/friends/:friendId
-func (h *PeopleHandler) RemoveFriend(c *gin.Context) {
-	personID, err := strconv.Atoi(c.Param("id"))
+// GetPersonVersions - GET /api/v1/people/:id/versions
+func (h *PeopleHandler) GetPersonVersions(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		h.logger.WithField("id", c.Param("id")).Warn("Invalid person ID format")
-		c.JSON(http.StatusBadRequest, errors.NewValidationError("Invalid person ID"))
+		requestLog(c, h.logger).WithField("id", c.Param("id")).Warn("Invalid person ID format")
+		writeError(c, errors.NewValidationError("Invalid person ID"))
 		return
 	}
 
-	friendID, err := strconv.Atoi(c.Param("friendId"))
+	ctx := c.Request.Context()
+	versions, err := h.service.GetPersonVersions(ctx, id)
 	if err != nil {
-		h.logger.WithField("friendId", c.Param("friendId")).Warn("Invalid friend ID format")
-		c.JSON(http.StatusBadRequest, errors.NewValidationError("Invalid friend ID"))
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, versions)
+}
+
+// RevertPerson - POST /api/v1/people/:id/revert?version=N
+func (h *PeopleHandler) RevertPerson(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		requestLog(c, h.logger).WithField("id", c.Param("id")).Warn("Invalid person ID format")
+		writeError(c, errors.NewValidationError("Invalid person ID"))
+		return
+	}
+
+	version, err := strconv.Atoi(c.Query("version"))
+	if err != nil || version <= 0 {
+		writeError(c, errors.NewValidationError("Invalid version"))
 		return
 	}
 
 	ctx := c.Request.Context()
-	if err := h.service.RemoveFriend(ctx, personID, friendID); err != nil {
+	person, err := h.service.RevertPerson(ctx, id, version)
+	if err != nil {
 		h.handleError(c, err)
 		return
 	}
 
-	c.Status(http.StatusNoContent)
+	c.JSON(http.StatusOK, person)
+}
+
+// parsePeopleFilter читает фильтры листинга: last_name, gender, nationality, min_age, max_age
+func parsePeopleFilter(c *gin.Context) (models.PeopleFilter, *errors.AppError) {
+	filter := models.PeopleFilter{
+		LastName:    c.Query("last_name"),
+		Gender:      c.Query("gender"),
+		Nationality: c.Query("nationality"),
+	}
+
+	for param, target := range map[string]**int{"min_age": &filter.MinAge, "max_age": &filter.MaxAge} {
+		value := c.Query(param)
+		if value == "" {
+			continue
+		}
+		age, err := strconv.Atoi(value)
+		if err != nil || age < 0 {
+			return filter, errors.NewValidationError("Invalid " + param)
+		}
+		*target = &age
+	}
+
+	return filter, nil
+}
+
+// GetDuplicates - GET /api/v1/people/duplicates
+func (h *PeopleHandler) GetDuplicates(c *gin.Context) {
+	ctx := c.Request.Context()
+	clusters, err := h.service.GetDuplicateClusters(ctx)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, clusters)
+}
+
+// MergePeople - POST /api/v1/people/:id/merge/:otherId
+func (h *PeopleHandler) MergePeople(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		requestLog(c, h.logger).WithField("id", c.Param("id")).Warn("Invalid person ID format")
+		writeError(c, errors.NewValidationError("Invalid person ID"))
+		return
+	}
+
+	otherID, err := strconv.Atoi(c.Param("otherId"))
+	if err != nil {
+		requestLog(c, h.logger).WithField("otherId", c.Param("otherId")).Warn("Invalid other person ID format")
+		writeError(c, errors.NewValidationError("Invalid other person ID"))
+		return
+	}
+
+	ctx := c.Request.Context()
+	person, err := h.service.MergePeople(ctx, id, otherID)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, person)
 }
 
 func (h *PeopleHandler) handleError(c *gin.Context, err error) {
+	respondError(c, h.logger, err)
+}
+
+// requestLog возвращает запись лога с полями запроса (request_id, principal, tenant)
+func requestLog(c *gin.Context, logger *logrus.Logger) *logrus.Entry {
+	return reqctx.Logger(c.Request.Context(), logger)
+}
+
+// respondError отдает ошибку сервиса в стандартном формате AppError
+func respondError(c *gin.Context, logger *logrus.Logger, err error) {
+	entry := requestLog(c, logger)
 	if appErr, ok := err.(*errors.AppError); ok {
-		h.logger.WithFields(logrus.Fields{
+		entry.WithFields(logrus.Fields{
 			"error":   appErr.Error(),
 			"code":    appErr.Code,
 			"details": appErr.Details,
 		}).Error("Handler error")
-		c.JSON(appErr.Code, appErr)
 	} else {
-		h.logger.WithError(err).Error("Unexpected handler error")
-		c.JSON(http.StatusInternalServerError, errors.NewInternalServerError("Internal server error"))
+		entry.WithError(err).Error("Unexpected handler error")
+	}
+	writeError(c, err)
+}
+
+// writeError отвечает ошибкой в стандартном формате с id запроса. Ошибки не из errors скрываются за 500
+func writeError(c *gin.Context, err error) {
+	appErr, ok := err.(*errors.AppError)
+	if !ok {
+		appErr = errors.NewInternalServerError("Internal server error")
 	}
+	c.JSON(appErr.Code, appErr.WithRequestID(reqctx.RequestID(c.Request.Context())))
 }
 
 func (h *PeopleHandler) HealthCheck(c *gin.Context) {
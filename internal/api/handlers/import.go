@@ -0,0 +1,130 @@
+package handlers
+
+import (
+	"PeopleCRUD/internal/importer"
+	"PeopleCRUD/pkg/errors"
+	"encoding/csv"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ImportPeople - POST /api/v1/people/import?format=csv|ndjson&dry_run=true&map[first_name]=Имя
+// Файл передается телом запроса или полем "file" в multipart/form-data и читается потоково.
+func (h *PeopleHandler) ImportPeople(c *gin.Context) {
+	dryRun, _ := strconv.ParseBool(c.DefaultQuery("dry_run", "false"))
+	format := c.Query("format")
+
+	// Большой файл может загружаться дольше общего ReadTimeout сервера
+	if err := http.NewResponseController(c.Writer).SetReadDeadline(time.Time{}); err != nil {
+		requestLog(c, h.logger).WithError(err).Warn("Failed to reset read deadline for import")
+	}
+
+	var source io.Reader = c.Request.Body
+	mediaType, _, _ := mime.ParseMediaType(c.GetHeader("Content-Type"))
+	if mediaType == "multipart/form-data" {
+		part, err := filePart(c)
+		if err != nil {
+			writeError(c, errors.NewValidationError(err.Error()))
+			return
+		}
+		defer part.Close()
+		source = part
+		if format == "" {
+			format = formatFromName(part.FileName())
+		}
+	}
+	if format == "" {
+		format = formatFromMediaType(mediaType)
+	}
+	if format == "" {
+		writeError(c, errors.NewValidationError("Cannot detect file format: pass format=csv or format=ndjson"))
+		return
+	}
+
+	ctx := c.Request.Context()
+	result, err := h.service.ImportPeople(ctx, format, source, c.QueryMap("map"), dryRun)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	status := http.StatusOK
+	if !dryRun && result.Accepted > 0 {
+		status = http.StatusCreated
+	}
+	c.JSON(status, result)
+}
+
+// GetImportReport - GET /api/v1/people/import/reports/:reportId[?format=json]
+func (h *PeopleHandler) GetImportReport(c *gin.Context) {
+	ctx := c.Request.Context()
+	rejections, err := h.service.GetImportReport(ctx, c.Param("reportId"))
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	if c.Query("format") == "json" {
+		c.JSON(http.StatusOK, rejections)
+		return
+	}
+
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", `attachment; filename="import-report-`+c.Param("reportId")+`.csv"`)
+	c.Status(http.StatusOK)
+
+	writer := csv.NewWriter(c.Writer)
+	writer.Write([]string{"line", "reason", "raw"})
+	for _, rejection := range rejections {
+		writer.Write([]string{strconv.Itoa(rejection.Line), rejection.Reason, rejection.Raw})
+	}
+	writer.Flush()
+}
+
+func filePart(c *gin.Context) (*multipart.Part, error) {
+	reader, err := c.Request.MultipartReader()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			return nil, errors.NewValidationError("multipart body must contain a \"file\" field")
+		}
+		if err != nil {
+			return nil, err
+		}
+		if part.FormName() == "file" {
+			return part, nil
+		}
+		part.Close()
+	}
+}
+
+func formatFromName(name string) string {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".csv":
+		return importer.FormatCSV
+	case ".ndjson", ".jsonl":
+		return importer.FormatNDJSON
+	}
+	return ""
+}
+
+func formatFromMediaType(mediaType string) string {
+	switch mediaType {
+	case "text/csv", "application/csv":
+		return importer.FormatCSV
+	case "application/x-ndjson", "application/ndjson", "application/jsonl":
+		return importer.FormatNDJSON
+	}
+	return ""
+}
@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"PeopleCRUD/internal/models"
+	"PeopleCRUD/pkg/errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CreateRelationship - POST /api/v1/people/:id/relationships
+func (h *PeopleHandler) CreateRelationship(c *gin.Context) {
+	personID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		requestLog(c, h.logger).WithField("id", c.Param("id")).Warn("Invalid person ID format")
+		writeError(c, errors.NewValidationError("Invalid person ID"))
+		return
+	}
+
+	var req models.CreateRelationshipRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		requestLog(c, h.logger).WithError(err).Error("Failed to bind JSON")
+		writeError(c, errors.NewValidationError(err.Error()))
+		return
+	}
+
+	ctx := c.Request.Context()
+	relationship, err := h.service.CreateRelationship(ctx, personID, &req)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, relationship)
+}
+
+// GetRelationships - GET /api/v1/people/:id/relationships?type=
+func (h *PeopleHandler) GetRelationships(c *gin.Context) {
+	personID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		requestLog(c, h.logger).WithField("id", c.Param("id")).Warn("Invalid person ID format")
+		writeError(c, errors.NewValidationError("Invalid person ID"))
+		return
+	}
+
+	ctx := c.Request.Context()
+	relationships, err := h.service.GetRelationships(ctx, personID, c.Query("type"))
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, relationships)
+}
+
+// UpdateRelationship - PUT /api/v1/people/:id/relationships/:relatedId/:type
+func (h *PeopleHandler) UpdateRelationship(c *gin.Context) {
+	personID, relatedID, ok := h.relationshipIDs(c)
+	if !ok {
+		return
+	}
+
+	var req models.UpdateRelationshipRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		requestLog(c, h.logger).WithError(err).Error("Failed to bind JSON")
+		writeError(c, errors.NewValidationError(err.Error()))
+		return
+	}
+
+	ctx := c.Request.Context()
+	relationship, err := h.service.UpdateRelationship(ctx, personID, relatedID, c.Param("type"), &req)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, relationship)
+}
+
+// DeleteRelationship - DELETE /api/v1/people/:id/relationships/:relatedId/:type
+func (h *PeopleHandler) DeleteRelationship(c *gin.Context) {
+	personID, relatedID, ok := h.relationshipIDs(c)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	if err := h.service.DeleteRelationship(ctx, personID, relatedID, c.Param("type")); err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (h *PeopleHandler) relationshipIDs(c *gin.Context) (int, int, bool) {
+	personID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		requestLog(c, h.logger).WithField("id", c.Param("id")).Warn("Invalid person ID format")
+		writeError(c, errors.NewValidationError("Invalid person ID"))
+		return 0, 0, false
+	}
+
+	relatedID, err := strconv.Atoi(c.Param("relatedId"))
+	if err != nil {
+		requestLog(c, h.logger).WithField("relatedId", c.Param("relatedId")).Warn("Invalid related person ID format")
+		writeError(c, errors.NewValidationError("Invalid related person ID"))
+		return 0, 0, false
+	}
+
+	return personID, relatedID, true
+}
@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"PeopleCRUD/pkg/errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetStats - GET /api/v1/stats?age_buckets=0,18,30,60&period=day|week
+// Принимает те же фильтры, что и листинг.
+func (h *PeopleHandler) GetStats(c *gin.Context) {
+	filter, filterErr := parsePeopleFilter(c)
+	if filterErr != nil {
+		writeError(c, filterErr)
+		return
+	}
+
+	var ageBuckets []int
+	if raw := c.Query("age_buckets"); raw != "" {
+		for _, part := range strings.Split(raw, ",") {
+			bound, err := strconv.Atoi(strings.TrimSpace(part))
+			if err != nil {
+				writeError(c, errors.NewValidationError("Invalid age_buckets"))
+				return
+			}
+			ageBuckets = append(ageBuckets, bound)
+		}
+	}
+
+	ctx := c.Request.Context()
+	stats, err := h.service.GetStats(ctx, filter, ageBuckets, c.Query("period"))
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, stats)
+}
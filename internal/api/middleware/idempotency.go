@@ -0,0 +1,181 @@
+package middleware
+
+import (
+	"PeopleCRUD/internal/models"
+	"PeopleCRUD/internal/reqctx"
+	"PeopleCRUD/pkg/errors"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// Как часто повтор проверяет, не закончился ли выполняющийся запрос с тем же ключом
+const idempotencyPollInterval = 100 * time.Millisecond
+
+// IdempotencyStore хранит ответы по ключам, см. repository.IdempotencyRepository
+type IdempotencyStore interface {
+	Begin(ctx context.Context, scope, key, fingerprint string, lockTTL time.Duration) (bool, error)
+	Get(ctx context.Context, scope, key string) (*models.IdempotencyRecord, error)
+	Complete(ctx context.Context, scope, key string, record *models.IdempotencyRecord, ttl time.Duration) error
+	Release(ctx context.Context, scope, key string) error
+}
+
+// IdempotencyOptions: TTL - сколько хранится ответ, LockTimeout - через сколько блокировка
+// упавшего запроса снимается, Wait - сколько повтор ждет выполняющийся запрос перед 409
+type IdempotencyOptions struct {
+	TTL         time.Duration
+	LockTimeout time.Duration
+	Wait        time.Duration
+}
+
+// Idempotency обрабатывает POST-запросы с заголовком Idempotency-Key. Первый ответ сохраняется вместе
+// с отпечатком запроса (метод, путь, тело), повторы с тем же ключом получают его без выполнения запроса.
+// Повтор во время выполнения первого ждет его до Wait, затем получает 409. Тот же ключ с другим запросом - 422.
+// Ответы 5xx не сохраняются, такой запрос можно повторить. Ключи отдельны для каждого тенанта и вызывающего,
+// поэтому middleware должен стоять после Authenticate и Tenant. store == nil - заголовок игнорируется
+func Idempotency(store IdempotencyStore, opts IdempotencyOptions, logger *logrus.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader("Idempotency-Key")
+		if store == nil || c.Request.Method != http.MethodPost || key == "" {
+			c.Next()
+			return
+		}
+		if len(key) > 255 {
+			abortWithError(c, errors.NewValidationError("Idempotency-Key must be at most 255 characters"))
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			abortWithError(c, errors.NewValidationError("Failed to read request body"))
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		ctx := c.Request.Context()
+		scope := idempotencyScope(c)
+		fingerprint := requestFingerprint(c.Request, body)
+		entry := reqctx.Logger(ctx, logger).WithFields(logrus.Fields{"idempotency_key": key, "scope": scope})
+
+		deadline := time.Now().Add(opts.Wait)
+		for {
+			acquired, err := store.Begin(ctx, scope, key, fingerprint, opts.LockTimeout)
+			if err != nil {
+				entry.WithError(err).Error("Failed to lock idempotency key")
+				abortWithError(c, err)
+				return
+			}
+			if acquired {
+				break
+			}
+
+			record, err := store.Get(ctx, scope, key)
+			if err != nil {
+				entry.WithError(err).Error("Failed to get idempotency key")
+				abortWithError(c, err)
+				return
+			}
+			// Первый запрос освободил ключ или запись истекла - после паузы пробуем занять снова
+			if record == nil {
+				if !waitIdempotencyRetry(ctx, deadline) {
+					abortWithError(c, errors.NewConflictError("A request with this Idempotency-Key is still in progress"))
+					return
+				}
+				continue
+			}
+			if record.Fingerprint != fingerprint {
+				abortWithError(c, errors.NewAppError(http.StatusUnprocessableEntity, "Idempotency key reuse",
+					"Idempotency-Key was already used with a different request"))
+				return
+			}
+			if record.StatusCode != 0 {
+				if record.ContentType != "" {
+					c.Header("Content-Type", record.ContentType)
+				}
+				c.Header("Idempotent-Replayed", "true")
+				c.Data(record.StatusCode, record.ContentType, record.Body)
+				c.Abort()
+				return
+			}
+
+			if !waitIdempotencyRetry(ctx, deadline) {
+				abortWithError(c, errors.NewConflictError("A request with this Idempotency-Key is still in progress"))
+				return
+			}
+		}
+
+		writer := &recordingWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+		c.Next()
+
+		// Клиент мог уже отключиться, а ответ нужен его повторам
+		saveCtx := context.WithoutCancel(ctx)
+		if c.Writer.Status() >= http.StatusInternalServerError {
+			if err := store.Release(saveCtx, scope, key); err != nil {
+				entry.WithError(err).Warn("Failed to release idempotency key")
+			}
+			return
+		}
+
+		record := &models.IdempotencyRecord{
+			Fingerprint: fingerprint,
+			StatusCode:  c.Writer.Status(),
+			ContentType: c.Writer.Header().Get("Content-Type"),
+			Body:        writer.body.Bytes(),
+		}
+		if err := store.Complete(saveCtx, scope, key, record, opts.TTL); err != nil {
+			entry.WithError(err).Warn("Failed to save idempotent response")
+		}
+	}
+}
+
+// waitIdempotencyRetry ждет перед следующей попыткой. false - ожидание истекло или клиент отключился
+func waitIdempotencyRetry(ctx context.Context, deadline time.Time) bool {
+	if !time.Now().Before(deadline) {
+		return false
+	}
+	select {
+	case <-ctx.Done():
+		return false
+	case <-time.After(idempotencyPollInterval):
+		return true
+	}
+}
+
+func idempotencyScope(c *gin.Context) string {
+	subject := reqctx.AnonymousActor
+	if principal, ok := GetPrincipal(c); ok {
+		subject = principal.Subject
+	}
+	return reqctx.Tenant(c.Request.Context()) + "|" + subject
+}
+
+func requestFingerprint(r *http.Request, body []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(r.Method + " " + r.URL.RequestURI() + "\n"))
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// recordingWriter копирует тело ответа, чтобы сохранить его для повторов
+type recordingWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *recordingWriter) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *recordingWriter) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
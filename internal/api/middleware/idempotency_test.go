@@ -0,0 +1,213 @@
+package middleware
+
+import (
+	"PeopleCRUD/internal/models"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// memoryIdempotency - IdempotencyStore в памяти с той же логикой сроков, что у таблицы idempotency_keys
+type memoryIdempotency struct {
+	mu      sync.Mutex
+	records map[string]models.IdempotencyRecord
+	expires map[string]time.Time
+}
+
+func newMemoryIdempotency() *memoryIdempotency {
+	return &memoryIdempotency{records: make(map[string]models.IdempotencyRecord), expires: make(map[string]time.Time)}
+}
+
+func (s *memoryIdempotency) Begin(_ context.Context, scope, key, fingerprint string, lockTTL time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id := scope + "/" + key
+	if expires, ok := s.expires[id]; ok && time.Now().Before(expires) {
+		return false, nil
+	}
+	s.records[id] = models.IdempotencyRecord{Fingerprint: fingerprint}
+	s.expires[id] = time.Now().Add(lockTTL)
+	return true, nil
+}
+
+func (s *memoryIdempotency) Get(_ context.Context, scope, key string) (*models.IdempotencyRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id := scope + "/" + key
+	if expires, ok := s.expires[id]; !ok || !time.Now().Before(expires) {
+		return nil, nil
+	}
+	record := s.records[id]
+	return &record, nil
+}
+
+func (s *memoryIdempotency) Complete(_ context.Context, scope, key string, record *models.IdempotencyRecord, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records[scope+"/"+key] = *record
+	s.expires[scope+"/"+key] = time.Now().Add(ttl)
+	return nil
+}
+
+func (s *memoryIdempotency) Release(_ context.Context, scope, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.records, scope+"/"+key)
+	delete(s.expires, scope+"/"+key)
+	return nil
+}
+
+func (s *memoryIdempotency) len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.records)
+}
+
+// idempotencyRouter отвечает на POST /people номером вызова обработчика. handle вызывается перед ответом
+// и может задержать его или вернуть свой код
+func idempotencyRouter(store IdempotencyStore, wait time.Duration, handle func(call int64) int) (*gin.Engine, *atomic.Int64) {
+	gin.SetMode(gin.TestMode)
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	var calls atomic.Int64
+	opts := IdempotencyOptions{TTL: time.Hour, LockTimeout: time.Minute, Wait: wait}
+	router := gin.New()
+	router.POST("/people", Idempotency(store, opts, logger), func(c *gin.Context) {
+		call := calls.Add(1)
+		status := http.StatusCreated
+		if handle != nil {
+			status = handle(call)
+		}
+		c.JSON(status, gin.H{"call": call})
+	})
+	return router, &calls
+}
+
+func postWithKey(router http.Handler, key, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/people", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if key != "" {
+		req.Header.Set("Idempotency-Key", key)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestIdempotencyReplaysStoredResponse(t *testing.T) {
+	router, calls := idempotencyRouter(newMemoryIdempotency(), time.Second, nil)
+
+	first := postWithKey(router, "key-1", `{"first_name":"Ivan"}`)
+	second := postWithKey(router, "key-1", `{"first_name":"Ivan"}`)
+
+	if first.Code != http.StatusCreated || second.Code != http.StatusCreated {
+		t.Fatalf("statuses = %d, %d, want 201 twice", first.Code, second.Code)
+	}
+	if calls.Load() != 1 {
+		t.Errorf("handler called %d times, want 1", calls.Load())
+	}
+	if second.Body.String() != first.Body.String() {
+		t.Errorf("replayed body = %s, want %s", second.Body.String(), first.Body.String())
+	}
+	if second.Header().Get("Idempotent-Replayed") != "true" || first.Header().Get("Idempotent-Replayed") != "" {
+		t.Error("only the replay should carry Idempotent-Replayed")
+	}
+	if got := second.Header().Get("Content-Type"); !strings.HasPrefix(got, "application/json") {
+		t.Errorf("replayed Content-Type = %q", got)
+	}
+
+	// Без ключа и с другим ключом запрос выполняется заново
+	postWithKey(router, "", `{"first_name":"Ivan"}`)
+	postWithKey(router, "key-2", `{"first_name":"Ivan"}`)
+	if calls.Load() != 3 {
+		t.Errorf("handler called %d times, want 3", calls.Load())
+	}
+}
+
+func TestIdempotencyRejectsKeyReuseWithAnotherRequest(t *testing.T) {
+	router, calls := idempotencyRouter(newMemoryIdempotency(), time.Second, nil)
+
+	postWithKey(router, "key-1", `{"first_name":"Ivan"}`)
+	w := postWithKey(router, "key-1", `{"first_name":"Petr"}`)
+
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("status = %d, want 422", w.Code)
+	}
+	if calls.Load() != 1 {
+		t.Errorf("handler called %d times, want 1", calls.Load())
+	}
+}
+
+func TestIdempotencyWaitsForRequestInProgress(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+	store := newMemoryIdempotency()
+	hold := func(call int64) int {
+		if call == 1 {
+			close(started)
+			<-release
+		}
+		return http.StatusCreated
+	}
+	impatient, calls := idempotencyRouter(store, 50*time.Millisecond, hold)
+	patient, _ := idempotencyRouter(store, 5*time.Second, nil)
+
+	firstDone := make(chan *httptest.ResponseRecorder)
+	go func() { firstDone <- postWithKey(impatient, "key-1", `{}`) }()
+	<-started
+
+	if w := postWithKey(impatient, "key-1", `{}`); w.Code != http.StatusConflict {
+		t.Errorf("retry past Wait: status = %d, want 409", w.Code)
+	}
+
+	replayDone := make(chan *httptest.ResponseRecorder)
+	go func() { replayDone <- postWithKey(patient, "key-1", `{}`) }()
+	time.Sleep(2 * idempotencyPollInterval)
+	close(release)
+
+	first, replay := <-firstDone, <-replayDone
+	if replay.Code != http.StatusCreated || replay.Header().Get("Idempotent-Replayed") != "true" {
+		t.Errorf("retry within Wait: status %d, replayed %q, want the stored 201", replay.Code, replay.Header().Get("Idempotent-Replayed"))
+	}
+	if replay.Body.String() != first.Body.String() {
+		t.Errorf("replayed body = %s, want %s", replay.Body.String(), first.Body.String())
+	}
+	if calls.Load() != 1 {
+		t.Errorf("handler called %d times, want 1", calls.Load())
+	}
+}
+
+func TestIdempotencyReleasesKeyOnServerError(t *testing.T) {
+	store := newMemoryIdempotency()
+	router, calls := idempotencyRouter(store, time.Second, func(call int64) int {
+		if call == 1 {
+			return http.StatusInternalServerError
+		}
+		return http.StatusCreated
+	})
+
+	if w := postWithKey(router, "key-1", `{}`); w.Code != http.StatusInternalServerError {
+		t.Fatalf("first status = %d, want 500", w.Code)
+	}
+	if store.len() != 0 {
+		t.Error("key stays locked after a 5xx response")
+	}
+
+	w := postWithKey(router, "key-1", `{}`)
+	if w.Code != http.StatusCreated || w.Header().Get("Idempotent-Replayed") != "" {
+		t.Errorf("retry after 5xx: status %d, replayed %q, want a fresh 201", w.Code, w.Header().Get("Idempotent-Replayed"))
+	}
+	if calls.Load() != 2 || !strings.Contains(w.Body.String(), strconv.Itoa(2)) {
+		t.Errorf("handler called %d times, body %s, want the second call", calls.Load(), w.Body.String())
+	}
+}
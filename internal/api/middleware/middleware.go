@@ -1,12 +1,24 @@
 package middleware
 
 import (
+	"PeopleCRUD/internal/auth"
+	"PeopleCRUD/internal/metrics"
+	"PeopleCRUD/internal/ratelimit"
+	"PeopleCRUD/internal/reqctx"
 	"PeopleCRUD/pkg/errors"
 	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"net/http"
+	"regexp"
+	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // Logger middleware для логирования HTTP запросов
@@ -20,7 +32,8 @@ func Logger(logger *logrus.Logger) gin.HandlerFunc {
 		// Log request
 		duration := time.Since(startTime)
 
-		entry := logger.WithFields(logrus.Fields{
+		// Запись из контекста уже несет request_id, а после аутентификации - principal и tenant
+		entry := reqctx.Logger(c.Request.Context(), logger).WithFields(logrus.Fields{
 			"method":     c.Request.Method,
 			"path":       c.Request.URL.Path,
 			"status":     c.Writer.Status(),
@@ -39,18 +52,32 @@ func Logger(logger *logrus.Logger) gin.HandlerFunc {
 	}
 }
 
+// Metrics учитывает запросы в метриках Prometheus по шаблону маршрута. Запросы мимо маршрутов
+// собираются под одним значением, чтобы сканеры путей не плодили ряды
+func Metrics() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		startTime := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		metrics.ObserveHTTP(c.Request.Method, route, c.Writer.Status(), time.Since(startTime))
+	}
+}
+
 // Recovery middleware для обработки паники
 func Recovery(logger *logrus.Logger) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		defer func() {
 			if err := recover(); err != nil {
-				logger.WithFields(logrus.Fields{
+				reqctx.Logger(c.Request.Context(), logger).WithFields(logrus.Fields{
 					"error": err,
 					"path":  c.Request.URL.Path,
 				}).Error("Panic recovered")
 
-				c.JSON(500, errors.NewInternalServerError("Internal server error"))
-				c.Abort()
+				abortWithError(c, errors.NewInternalServerError("Internal server error"))
 			}
 		}()
 
@@ -63,7 +90,8 @@ func CORS() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		c.Header("Access-Control-Allow-Origin", "*")
 		c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		c.Header("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Requested-With")
+		c.Header("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Requested-With, X-Actor, X-Request-ID, X-API-Key, X-Tenant-ID, Idempotency-Key, traceparent, tracestate")
+		c.Header("Access-Control-Expose-Headers", "X-Request-ID, X-RateLimit-Limit, X-RateLimit-Remaining, X-RateLimit-Reset, Retry-After, Idempotent-Replayed")
 		c.Header("Access-Control-Max-Age", "86400")
 
 		if c.Request.Method == "OPTIONS" {
@@ -75,6 +103,173 @@ func CORS() gin.HandlerFunc {
 	}
 }
 
+// RequestContext middleware переносит в контекст запроса данные о вызывающем для аудита и запись лога
+// с id запроса. Id берется из X-Request-ID, если он подходит по формату, иначе генерируется, и возвращается
+// в заголовке ответа
+func RequestContext(logger *logrus.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		if actor := c.GetHeader("X-Actor"); actor != "" {
+			ctx = reqctx.WithActor(ctx, actor)
+		}
+
+		requestID := c.GetHeader("X-Request-ID")
+		if !requestIDRegex.MatchString(requestID) {
+			requestID = newRequestID()
+		}
+		ctx = reqctx.WithRequestID(ctx, requestID)
+		fields := logrus.Fields{"request_id": requestID}
+		if span := trace.SpanContextFromContext(ctx); span.IsValid() {
+			fields["trace_id"] = span.TraceID().String()
+		}
+		ctx = reqctx.WithLogger(ctx, logger.WithFields(fields))
+		c.Header("X-Request-ID", requestID)
+
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
+
+// Id запроса попадает в логи и аудит, поэтому принимаются только короткие безопасные значения
+var requestIDRegex = regexp.MustCompile(`^[a-zA-Z0-9._:-]{1,64}$`)
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 36)
+	}
+	return hex.EncodeToString(b)
+}
+
+// abortWithError прерывает запрос ответом в стандартном формате ошибок с id запроса
+func abortWithError(c *gin.Context, err error) {
+	appErr, ok := err.(*errors.AppError)
+	if !ok {
+		appErr = errors.NewInternalServerError("Internal server error")
+	}
+	c.AbortWithStatusJSON(appErr.Code, appErr.WithRequestID(reqctx.RequestID(c.Request.Context())))
+}
+
+// Authenticate пропускает только запросы с действующим API-ключом или JWT. Вызывающий кладется
+// в gin.Context и в контекст запроса, а его Subject становится автором изменений в аудите вместо X-Actor.
+// authenticator == nil отключает проверку
+func Authenticate(authenticator *auth.Authenticator, logger *logrus.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if authenticator == nil {
+			c.Next()
+			return
+		}
+
+		ctx := c.Request.Context()
+		principal, err := authenticator.Authenticate(ctx, c.Request)
+		if err != nil {
+			appErr, ok := err.(*errors.AppError)
+			if !ok {
+				appErr = errors.NewInternalServerError("Authentication failed")
+			}
+			if appErr.Code == http.StatusUnauthorized {
+				c.Header("WWW-Authenticate", `Bearer realm="api"`)
+			}
+			reqctx.Logger(ctx, logger).WithFields(logrus.Fields{
+				"error": err.Error(),
+				"path":  c.Request.URL.Path,
+			}).Warn("Authentication failed")
+			abortWithError(c, appErr)
+			return
+		}
+
+		c.Set(auth.GinKey, principal)
+		ctx = auth.WithPrincipal(ctx, principal)
+		ctx = reqctx.WithActor(ctx, principal.Subject)
+		ctx = reqctx.WithLogFields(ctx, logrus.Fields{"principal": principal.Subject})
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
+
+// Tenant определяет тенанта запроса. Вызывающий работает только в своем тенанте (без привязки - в тенанте
+// по умолчанию): заголовок X-Tenant-ID с другим значением отклоняется. Выбирать тенант заголовком может
+// только вызывающий с CrossTenant и любой запрос при выключенной аутентификации. Должен стоять после Authenticate
+func Tenant() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		header := c.GetHeader("X-Tenant-ID")
+		tenant := header
+
+		if principal, ok := GetPrincipal(c); ok && !principal.CrossTenant {
+			tenant = principal.TenantID
+			if tenant == "" {
+				tenant = reqctx.DefaultTenant
+			}
+			if header != "" && header != tenant {
+				abortWithError(c, errors.NewForbiddenError("Access to this tenant is not allowed"))
+				return
+			}
+		}
+
+		if tenant == "" {
+			tenant = reqctx.DefaultTenant
+		}
+		if !reqctx.ValidTenant(tenant) {
+			abortWithError(c, errors.NewValidationError("X-Tenant-ID must be 1-64 letters, digits, '_' or '-'"))
+			return
+		}
+
+		ctx := reqctx.WithTenant(c.Request.Context(), tenant)
+		c.Request = c.Request.WithContext(reqctx.WithLogFields(ctx, logrus.Fields{"tenant": tenant}))
+		c.Next()
+	}
+}
+
+// GetPrincipal возвращает вызывающего, сохраненного Authenticate
+func GetPrincipal(c *gin.Context) (*auth.Principal, bool) {
+	value, exists := c.Get(auth.GinKey)
+	if !exists {
+		return nil, false
+	}
+	principal, ok := value.(*auth.Principal)
+	return principal, ok
+}
+
+// Require пропускает только вызывающих с разрешением permission, остальным отвечает 403.
+// При выключенной аутентификации (authenticator == nil) пропускает всех
+func Require(authenticator *auth.Authenticator, permission auth.Permission) gin.HandlerFunc {
+	return authorize(authenticator, permission, "")
+}
+
+// RequireOrSelf как Require, но при включенном самообслуживании пропускает и вызывающего,
+// связанного с человеком из параметра маршрута param
+func RequireOrSelf(authenticator *auth.Authenticator, permission auth.Permission, param string) gin.HandlerFunc {
+	return authorize(authenticator, permission, param)
+}
+
+func authorize(authenticator *auth.Authenticator, permission auth.Permission, param string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if authenticator == nil {
+			c.Next()
+			return
+		}
+
+		principal, ok := GetPrincipal(c)
+		if !ok {
+			abortWithError(c, errors.NewUnauthorizedError("Authentication required"))
+			return
+		}
+
+		var selfPersonID *int
+		if param != "" {
+			if personID, err := strconv.Atoi(c.Param(param)); err == nil {
+				selfPersonID = &personID
+			}
+		}
+
+		if err := authenticator.Authorize(principal, permission, selfPersonID); err != nil {
+			abortWithError(c, err)
+			return
+		}
+		c.Next()
+	}
+}
+
 // Timeout middleware для ограничения времени выполнения запросов
 func Timeout(timeout time.Duration) gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -87,12 +282,66 @@ func Timeout(timeout time.Duration) gin.HandlerFunc {
 	}
 }
 
-// RateLimiter middleware для ограничения количества запросов
-func RateLimiter() gin.HandlerFunc {
-	// Простая реализация с использованием in-memory хранилища
-	// В продакшене лучше использовать Redis или подобное
+// RateLimiter ограничивает частоту запросов ведром токенов на каждого вызывающего: аутентифицированные
+// считаются по subject, остальные по IP. Лимит выбирается по классу маршрута. Должен стоять после Authenticate.
+// limiter == nil - ограничение выключено. При сбое хранилища запрос пропускается
+func RateLimiter(limiter *ratelimit.Limiter, logger *logrus.Logger) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// Здесь можно добавить логику rate limiting
-		c.Next()
+		if limiter == nil {
+			c.Next()
+			return
+		}
+
+		client := "ip:" + c.ClientIP()
+		if principal, ok := GetPrincipal(c); ok {
+			client = "subject:" + principal.Subject
+		}
+		if takeRateLimit(c, limiter, limiter.Class(c.Request.Method, c.FullPath()), client, logger) {
+			c.Next()
+		}
+	}
+}
+
+// IPRateLimiter ограничивает все запросы с одного адреса классом ip. Стоит до Authenticate,
+// чтобы потоки запросов с неверными ключами тоже упирались в лимит. limiter == nil - ограничение выключено
+func IPRateLimiter(limiter *ratelimit.Limiter, logger *logrus.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if limiter == nil || takeRateLimit(c, limiter, ratelimit.ClassIP, "ip:"+c.ClientIP(), logger) {
+			c.Next()
+		}
+	}
+}
+
+// takeRateLimit берет токен и выставляет заголовки X-RateLimit-*. false - лимит исчерпан, запрос прерван с 429
+func takeRateLimit(c *gin.Context, limiter *ratelimit.Limiter, class, client string, logger *logrus.Logger) bool {
+	result, limited, err := limiter.Take(c.Request.Context(), class, client)
+	if err != nil {
+		reqctx.Logger(c.Request.Context(), logger).WithError(err).WithField("class", class).Warn("Rate limit store failed")
+		return true
 	}
+	if !limited {
+		return true
+	}
+
+	c.Header("X-RateLimit-Limit", strconv.Itoa(result.Limit))
+	c.Header("X-RateLimit-Remaining", strconv.Itoa(result.Remaining))
+	c.Header("X-RateLimit-Reset", strconv.Itoa(ceilSeconds(result.Reset)))
+
+	if !result.Allowed {
+		retryAfter := max(ceilSeconds(result.RetryAfter), 1)
+		c.Header("Retry-After", strconv.Itoa(retryAfter))
+		reqctx.Logger(c.Request.Context(), logger).WithFields(logrus.Fields{
+			"class":  class,
+			"client": client,
+			"path":   c.Request.URL.Path,
+		}).Warn("Rate limit exceeded")
+		abortWithError(c, errors.NewAppError(http.StatusTooManyRequests, "Too many requests",
+			fmt.Sprintf("Rate limit exceeded, retry in %d seconds", retryAfter)))
+		return false
+	}
+	return true
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
 }
@@ -0,0 +1,99 @@
+package middleware
+
+import (
+	"PeopleCRUD/internal/ratelimit"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+func rateLimitRouter(t *testing.T, limits map[string]ratelimit.Limit, enrich ...string) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	store := ratelimit.NewMemoryStore(time.Minute)
+	t.Cleanup(store.Stop)
+	limiter := ratelimit.NewLimiter(store, limits)
+	limiter.Assign(ratelimit.ClassEnrich, enrich...)
+
+	router := gin.New()
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	group := router.Group("/api/v1", RateLimiter(limiter, logger))
+	group.GET("/people/:id", ok)
+	group.PUT("/people/:id", ok)
+	group.POST("/people", ok)
+	return router
+}
+
+func sendRateLimited(router http.Handler, method, path string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	req.RemoteAddr = "192.0.2.1:1234"
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestRateLimiterHeaders(t *testing.T) {
+	router := rateLimitRouter(t, map[string]ratelimit.Limit{ratelimit.ClassEnrich: ratelimit.PerMinute(6, 2)}, "POST /api/v1/people")
+
+	first := sendRateLimited(router, http.MethodPost, "/api/v1/people")
+	if first.Code != http.StatusOK {
+		t.Fatalf("first status = %d", first.Code)
+	}
+	want := map[string]string{"X-RateLimit-Limit": "2", "X-RateLimit-Remaining": "1", "X-RateLimit-Reset": "10"}
+	for header, value := range want {
+		if got := first.Header().Get(header); got != value {
+			t.Errorf("%s = %q, want %q", header, got, value)
+		}
+	}
+	if first.Header().Get("Retry-After") != "" {
+		t.Error("allowed request carries Retry-After")
+	}
+
+	sendRateLimited(router, http.MethodPost, "/api/v1/people")
+	denied := sendRateLimited(router, http.MethodPost, "/api/v1/people")
+	if denied.Code != http.StatusTooManyRequests {
+		t.Fatalf("status past burst = %d, want 429", denied.Code)
+	}
+	// Токен появляется раз в 10 секунд, ведро наполнится через 20
+	if got := denied.Header().Get("Retry-After"); got != "10" {
+		t.Errorf("Retry-After = %q, want 10", got)
+	}
+	if got := denied.Header().Get("X-RateLimit-Remaining"); got != "0" {
+		t.Errorf("X-RateLimit-Remaining = %q, want 0", got)
+	}
+	if got := denied.Header().Get("X-RateLimit-Reset"); got != "20" {
+		t.Errorf("X-RateLimit-Reset = %q, want 20", got)
+	}
+}
+
+// Класс выбирается по шаблону маршрута: запросы к разным :id делят одно ведро назначенного класса
+func TestRateLimiterClassByRouteTemplate(t *testing.T) {
+	router := rateLimitRouter(t, map[string]ratelimit.Limit{
+		ratelimit.ClassEnrich:  ratelimit.PerMinute(60, 1),
+		ratelimit.ClassWrite:   ratelimit.PerMinute(60, 5),
+		ratelimit.ClassDefault: ratelimit.PerMinute(60, 5),
+	}, "PUT /api/v1/people/:id")
+
+	if w := sendRateLimited(router, http.MethodPut, "/api/v1/people/1"); w.Code != http.StatusOK {
+		t.Fatalf("first PUT status = %d", w.Code)
+	}
+	if w := sendRateLimited(router, http.MethodPut, "/api/v1/people/2"); w.Code != http.StatusTooManyRequests {
+		t.Errorf("PUT to another id: status = %d, want 429 from the shared enrich bucket", w.Code)
+	}
+
+	// Остальные маршруты берут свои классы и не задеты исчерпанным ведром
+	if w := sendRateLimited(router, http.MethodPost, "/api/v1/people"); w.Code != http.StatusOK || w.Header().Get("X-RateLimit-Limit") != "5" {
+		t.Errorf("POST: status %d, limit %q, want the write class", w.Code, w.Header().Get("X-RateLimit-Limit"))
+	}
+	if w := sendRateLimited(router, http.MethodGet, "/api/v1/people/1"); w.Code != http.StatusOK || w.Header().Get("X-RateLimit-Remaining") != "4" {
+		t.Errorf("GET: status %d, remaining %q, want the default class", w.Code, w.Header().Get("X-RateLimit-Remaining"))
+	}
+}
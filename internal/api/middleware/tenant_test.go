@@ -0,0 +1,84 @@
+package middleware
+
+import (
+	"PeopleCRUD/internal/auth"
+	"PeopleCRUD/internal/reqctx"
+	"PeopleCRUD/pkg/errors"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+type staticKeys map[string]*auth.Principal
+
+func (k staticKeys) VerifyAPIKey(_ context.Context, key string) (*auth.Principal, error) {
+	if principal, ok := k[key]; ok {
+		return principal, nil
+	}
+	return nil, errors.NewUnauthorizedError("Invalid API key")
+}
+
+func tenantRouter(authenticator *auth.Authenticator) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	router := gin.New()
+	router.GET("/tenant", Authenticate(authenticator, logger), Tenant(), func(c *gin.Context) {
+		c.String(http.StatusOK, reqctx.Tenant(c.Request.Context()))
+	})
+	return router
+}
+
+func TestTenant(t *testing.T) {
+	authenticator := auth.NewAuthenticator(staticKeys{
+		"team-a":    {Subject: "api_key:1", Roles: []string{auth.RoleAdmin}, TenantID: "team-a"},
+		"unbound":   {Subject: "api_key:2", Roles: []string{auth.RoleAdmin}},
+		"bootstrap": {Subject: "api_key:bootstrap", Roles: []string{auth.RoleAdmin}, CrossTenant: true},
+	}, nil, false)
+
+	tests := []struct {
+		name       string
+		authn      *auth.Authenticator
+		key        string
+		header     string
+		wantStatus int
+		wantTenant string
+	}{
+		{"bound key uses its tenant", authenticator, "team-a", "", http.StatusOK, "team-a"},
+		{"bound key with its own header", authenticator, "team-a", "team-a", http.StatusOK, "team-a"},
+		{"bound key with another tenant", authenticator, "team-a", "team-b", http.StatusForbidden, ""},
+		{"unbound key is pinned to default", authenticator, "unbound", "", http.StatusOK, reqctx.DefaultTenant},
+		{"unbound key with another tenant", authenticator, "unbound", "team-b", http.StatusForbidden, ""},
+		{"cross-tenant key picks a tenant", authenticator, "bootstrap", "team-b", http.StatusOK, "team-b"},
+		{"cross-tenant key without header", authenticator, "bootstrap", "", http.StatusOK, reqctx.DefaultTenant},
+		{"cross-tenant key with invalid tenant", authenticator, "bootstrap", "team:b", http.StatusBadRequest, ""},
+		{"disabled auth uses header", nil, "", "team-b", http.StatusOK, "team-b"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/tenant", nil)
+			if tt.key != "" {
+				req.Header.Set("X-API-Key", tt.key)
+			}
+			if tt.header != "" {
+				req.Header.Set("X-Tenant-ID", tt.header)
+			}
+			w := httptest.NewRecorder()
+			tenantRouter(tt.authn).ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (%s)", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if tt.wantTenant != "" && w.Body.String() != tt.wantTenant {
+				t.Errorf("tenant = %q, want %q", w.Body.String(), tt.wantTenant)
+			}
+		})
+	}
+}
@@ -3,39 +3,137 @@ package routes
 import (
 	"PeopleCRUD/internal/api/handlers"
 	"PeopleCRUD/internal/api/middleware"
+	"PeopleCRUD/internal/auth"
+	"PeopleCRUD/internal/ratelimit"
 	"PeopleCRUD/internal/service"
+	"net/http"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
 )
 
-func SetupRoutes(router *gin.Engine, personService service.PersonService, logger *logrus.Logger) {
+// Дорогие записи - создание людей по одному, пачками и импортом - ограничиваются строже остальных
+var enrichRoutes = []string{
+	"POST /api/v1/people",
+	"POST /api/v1/people/bulk",
+	"POST /api/v1/people/import",
+}
+
+// SetupRoutes регистрирует маршруты. authenticator == nil - API открыт без аутентификации,
+// limiter == nil - без ограничения частоты запросов, idempotency == nil - заголовок Idempotency-Key игнорируется,
+// metricsHandler == nil - без /metrics и учета запросов, tracingService == "" - без трассировки запросов
+func SetupRoutes(router *gin.Engine, personService service.PersonService, auditService service.AuditService,
+	authService service.AuthService, authenticator *auth.Authenticator, limiter *ratelimit.Limiter,
+	idempotency middleware.IdempotencyStore, idempotencyOpts middleware.IdempotencyOptions,
+	metricsHandler http.Handler, tracingService string, logger *logrus.Logger) {
+	if metricsHandler != nil {
+		router.Use(middleware.Metrics())
+		// Метрики снимает Prometheus без ключа. Маршрут объявлен до остальных middleware, чтобы частый сбор не засорял лог
+		router.GET("/metrics", gin.WrapH(metricsHandler))
+	}
+	if tracingService != "" {
+		// Span запроса продолжает трассу из входящего traceparent. Проверки здоровья не трассируются
+		router.Use(otelgin.Middleware(tracingService, otelgin.WithFilter(func(r *http.Request) bool {
+			return r.URL.Path != "/api/v1/health"
+		})))
+	}
 	router.Use(middleware.Logger(logger))
 	router.Use(middleware.Recovery(logger))
+	router.Use(middleware.RequestContext(logger))
 	router.Use(middleware.CORS())
-	router.Use(middleware.Timeout(30 * time.Second))
 
 	peopleHandler := handlers.NewPeopleHandler(personService, logger)
+	auditHandler := handlers.NewAuditHandler(auditService, logger)
+	authHandler := handlers.NewAuthHandler(authService, logger)
 
 	api := router.Group("/api")
 	{
-		v1 := api.Group("/v1")
+		// Проверка здоровья доступна без аутентификации
+		api.GET("/v1/health", peopleHandler.HealthCheck)
+
+		if limiter != nil {
+			limiter.Assign(ratelimit.ClassEnrich, enrichRoutes...)
+		}
+		// Лимит по адресу стоит до аутентификации, чтобы перебор ключей тоже ограничивался
+		secured := api.Group("", middleware.IPRateLimiter(limiter, logger), middleware.Authenticate(authenticator, logger),
+			middleware.Tenant(), middleware.RateLimiter(limiter, logger))
+
+		// Разрешения маршрутов: reader читает, editor создает и меняет, admin удаляет и управляет ключами.
+		// self - маршруты, где связанный с человеком :id вызывающий может менять свою запись сам
+		read := middleware.Require(authenticator, auth.PermPeopleRead)
+		write := middleware.Require(authenticator, auth.PermPeopleWrite)
+		self := middleware.RequireOrSelf(authenticator, auth.PermPeopleWrite, "id")
+		remove := middleware.Require(authenticator, auth.PermPeopleDelete)
+		audit := middleware.Require(authenticator, auth.PermAuditRead)
+		keys := middleware.Require(authenticator, auth.PermKeysManage)
+
+		// Потоковые импорт и выгрузка живут без общего таймаута: миллионы строк не уложатся в 30 секунд
+		streaming := secured.Group("/v1")
 		{
-			v1.GET("/health", peopleHandler.HealthCheck)
+			streaming.POST("/people/import", write, peopleHandler.ImportPeople)
+			streaming.GET("/people/export", read, peopleHandler.ExportPeople)
+			streaming.GET("/graph/export", read, peopleHandler.ExportGraph)
+		}
 
-			v1.POST("/people", peopleHandler.CreatePerson)
-			v1.GET("/people", peopleHandler.GetAllPeople)
-			v1.GET("/people/:id", peopleHandler.GetPerson)
-			v1.GET("/people/lastname/:lastname", peopleHandler.GetPeopleByLastName)
-			v1.PUT("/people/:id", peopleHandler.UpdatePerson)
-			v1.DELETE("/people/:id", peopleHandler.DeletePerson)
+		// Повтор POST с тем же Idempotency-Key получает сохраненный ответ. Потоковый импорт сюда не входит:
+		// тело пришлось бы целиком держать в памяти ради отпечатка
+		v1 := secured.Group("/v1", middleware.Timeout(30*time.Second), middleware.Idempotency(idempotency, idempotencyOpts, logger))
+		{
+			v1.POST("/people", write, peopleHandler.CreatePerson)
+			v1.GET("/people", read, peopleHandler.GetAllPeople)
+			v1.GET("/people/trash", remove, peopleHandler.GetTrash)
+			v1.GET("/people/duplicates", read, peopleHandler.GetDuplicates)
+			v1.POST("/people/bulk", write, peopleHandler.BulkCreatePeople)
+			v1.PUT("/people/bulk", write, peopleHandler.BulkUpdatePeople)
+			v1.DELETE("/people/bulk", remove, peopleHandler.BulkDeletePeople)
+			v1.GET("/people/import/reports/:reportId", write, peopleHandler.GetImportReport)
+			v1.GET("/people/:id", read, peopleHandler.GetPerson)
+			v1.GET("/people/lastname/:lastname", read, peopleHandler.GetPeopleByLastName)
+			v1.PUT("/people/:id", self, peopleHandler.UpdatePerson)
+			v1.DELETE("/people/:id", remove, peopleHandler.DeletePerson)
+			v1.POST("/people/:id/restore", remove, peopleHandler.RestorePerson)
+			v1.GET("/people/:id/versions", read, peopleHandler.GetPersonVersions)
+			v1.POST("/people/:id/revert", self, peopleHandler.RevertPerson)
+			v1.POST("/people/:id/merge/:otherId", remove, peopleHandler.MergePeople)
+
+			v1.GET("/people/:id/friends", read, peopleHandler.GetFriends)
+			// Старый маршрут добавления друга отправляет заявку: дружба без согласия второй стороны не создается
+			v1.POST("/people/:id/friends/:friendId", self, peopleHandler.SendFriendRequest)
+			v1.DELETE("/people/:id/friends/:friendId", self, peopleHandler.RemoveFriend)
+			v1.GET("/people/:id/friends/mutual/:otherId", read, peopleHandler.GetMutualFriends)
+			v1.GET("/people/:id/path/:otherId", read, peopleHandler.GetFriendPath)
+			v1.GET("/people/:id/suggestions", read, peopleHandler.GetFriendSuggestions)
+
+			v1.GET("/people/:id/friend-requests/incoming", read, peopleHandler.GetIncomingFriendRequests)
+			v1.GET("/people/:id/friend-requests/outgoing", read, peopleHandler.GetOutgoingFriendRequests)
+			v1.POST("/people/:id/friend-requests/:friendId", self, peopleHandler.SendFriendRequest)
+			v1.DELETE("/people/:id/friend-requests/:friendId", self, peopleHandler.CancelFriendRequest)
+			v1.POST("/people/:id/friend-requests/:friendId/accept", self, peopleHandler.AcceptFriendRequest)
+			v1.POST("/people/:id/friend-requests/:friendId/reject", self, peopleHandler.RejectFriendRequest)
+
+			v1.GET("/people/:id/relationships", read, peopleHandler.GetRelationships)
+			v1.POST("/people/:id/relationships", self, peopleHandler.CreateRelationship)
+			v1.PUT("/people/:id/relationships/:relatedId/:type", self, peopleHandler.UpdateRelationship)
+			v1.DELETE("/people/:id/relationships/:relatedId/:type", self, peopleHandler.DeleteRelationship)
 
-			v1.GET("/people/:id/friends", peopleHandler.GetFriends)
-			v1.POST("/people/:id/friends/:friendId", peopleHandler.AddFriend)
-			v1.DELETE("/people/:id/friends/:friendId", peopleHandler.RemoveFriend)
+			v1.POST("/people/:id/emails", self, peopleHandler.AddEmail)
 
-			v1.POST("/people/:id/emails", peopleHandler.AddEmail)
+			v1.GET("/people/:id/history", audit, auditHandler.GetPersonHistory)
+			v1.GET("/audit", audit, auditHandler.GetEvents)
+
+			v1.GET("/graph/analytics", read, peopleHandler.GetGraphAnalytics)
+			v1.GET("/stats", read, peopleHandler.GetStats)
+		}
+
+		// Ответ на создание ключа содержит сам ключ, поэтому эти маршруты не сохраняют ответы по Idempotency-Key:
+		// в базе хранятся только хэши ключей
+		admin := secured.Group("/v1/admin", middleware.Timeout(30*time.Second))
+		{
+			admin.GET("/api-keys", keys, authHandler.GetAPIKeys)
+			admin.POST("/api-keys", keys, authHandler.CreateAPIKey)
+			admin.DELETE("/api-keys/:keyId", keys, authHandler.RevokeAPIKey)
 		}
 	}
 }
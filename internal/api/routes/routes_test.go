@@ -0,0 +1,75 @@
+package routes
+
+import (
+	"PeopleCRUD/internal/api/middleware"
+	"PeopleCRUD/internal/models"
+	"PeopleCRUD/internal/service"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// issuingKeys выдает один и тот же ключ. Остальные методы не вызываются
+type issuingKeys struct {
+	service.AuthService
+}
+
+func (issuingKeys) CreateAPIKey(_ context.Context, req *models.CreateAPIKeyRequest) (*models.CreatedAPIKey, error) {
+	return &models.CreatedAPIKey{APIKey: models.APIKey{ID: 1, Name: req.Name}, Key: "pk_secret"}, nil
+}
+
+// countingIdempotency считает обращения к хранилищу ответов и ничего не хранит
+type countingIdempotency struct {
+	calls int
+}
+
+func (s *countingIdempotency) Begin(context.Context, string, string, string, time.Duration) (bool, error) {
+	s.calls++
+	return true, nil
+}
+
+func (s *countingIdempotency) Get(context.Context, string, string) (*models.IdempotencyRecord, error) {
+	s.calls++
+	return nil, nil
+}
+
+func (s *countingIdempotency) Complete(context.Context, string, string, *models.IdempotencyRecord, time.Duration) error {
+	s.calls++
+	return nil
+}
+
+func (s *countingIdempotency) Release(context.Context, string, string) error {
+	s.calls++
+	return nil
+}
+
+func TestCreatedAPIKeyIsNotStoredForIdempotency(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	store := &countingIdempotency{}
+	router := gin.New()
+	SetupRoutes(router, nil, nil, issuingKeys{}, nil, nil, store,
+		middleware.IdempotencyOptions{TTL: time.Hour, LockTimeout: time.Minute, Wait: time.Second}, nil, "", logger)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/admin/api-keys", strings.NewReader(`{"name":"ci"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Idempotency-Key", "create-ci-key")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusCreated || !strings.Contains(w.Body.String(), "pk_secret") {
+		t.Fatalf("status = %d, body = %s, want the created key", w.Code, w.Body.String())
+	}
+	if store.calls != 0 {
+		t.Errorf("idempotency store was used %d times, the plaintext key must not be saved", store.calls)
+	}
+}
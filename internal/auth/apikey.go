@@ -0,0 +1,58 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+)
+
+// Ключ имеет вид pk_<префикс>_<секрет>. Префикс хранится открыто и нужен для поиска ключа в базе,
+// секрет нигде не хранится
+const (
+	apiKeyScheme      = "pk_"
+	apiKeyPrefixBytes = 6
+	apiKeySecretBytes = 32
+)
+
+// GenerateAPIKey создает новый ключ. Возвращает сам ключ (показывается один раз),
+// его префикс и хеш для хранения
+func GenerateAPIKey() (key, prefix, hash string, err error) {
+	prefixBytes := make([]byte, apiKeyPrefixBytes)
+	if _, err := rand.Read(prefixBytes); err != nil {
+		return "", "", "", err
+	}
+	secretBytes := make([]byte, apiKeySecretBytes)
+	if _, err := rand.Read(secretBytes); err != nil {
+		return "", "", "", err
+	}
+
+	prefix = hex.EncodeToString(prefixBytes)
+	key = apiKeyScheme + prefix + "_" + base64.RawURLEncoding.EncodeToString(secretBytes)
+	return key, prefix, HashAPIKey(key), nil
+}
+
+// HashAPIKey - SHA-256 ключа. Медленный хеш не нужен: ключ случайный и длинный, перебор бесполезен
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// IsAPIKey отличает ключ от JWT в заголовке Authorization
+func IsAPIKey(token string) bool {
+	return strings.HasPrefix(token, apiKeyScheme)
+}
+
+// ParseAPIKey достает префикс из ключа
+func ParseAPIKey(key string) (prefix string, ok bool) {
+	rest, found := strings.CutPrefix(key, apiKeyScheme)
+	if !found {
+		return "", false
+	}
+	prefix, secret, found := strings.Cut(rest, "_")
+	if !found || len(prefix) != hex.EncodedLen(apiKeyPrefixBytes) || secret == "" {
+		return "", false
+	}
+	return prefix, true
+}
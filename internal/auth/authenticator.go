@@ -0,0 +1,57 @@
+package auth
+
+import (
+	"PeopleCRUD/pkg/errors"
+	"context"
+	"net/http"
+	"strings"
+)
+
+// APIKeyVerifier находит действующий ключ. Возвращает *errors.AppError: 401 для неизвестного
+// или отозванного ключа, 500 при сбое хранилища
+type APIKeyVerifier interface {
+	VerifyAPIKey(ctx context.Context, key string) (*Principal, error)
+}
+
+// Authenticator определяет вызывающего по заголовкам X-API-Key или Authorization: Bearer.
+// В Bearer принимается как API-ключ, так и JWT
+type Authenticator struct {
+	keys APIKeyVerifier
+	jwt  *JWTVerifier
+	// Разрешает вызывающему, связанному с человеком, менять его запись без роли editor
+	selfService bool
+}
+
+// NewAuthenticator создает проверку. jwt == nil - токены JWT не принимаются
+func NewAuthenticator(keys APIKeyVerifier, jwt *JWTVerifier, selfService bool) *Authenticator {
+	return &Authenticator{keys: keys, jwt: jwt, selfService: selfService}
+}
+
+func (a *Authenticator) Authenticate(ctx context.Context, r *http.Request) (*Principal, error) {
+	if key := r.Header.Get("X-API-Key"); key != "" {
+		return a.keys.VerifyAPIKey(ctx, key)
+	}
+
+	header := r.Header.Get("Authorization")
+	if header == "" {
+		return nil, errors.NewUnauthorizedError("Authentication required")
+	}
+	scheme, token, found := strings.Cut(header, " ")
+	token = strings.TrimSpace(token)
+	if !found || !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return nil, errors.NewUnauthorizedError("Authorization header must be 'Bearer <token>'")
+	}
+
+	if IsAPIKey(token) {
+		return a.keys.VerifyAPIKey(ctx, token)
+	}
+	if a.jwt == nil {
+		return nil, errors.NewUnauthorizedError("Invalid API key")
+	}
+
+	principal, err := a.jwt.Verify(token)
+	if err != nil {
+		return nil, errors.NewAppError(http.StatusUnauthorized, "Invalid token", err.Error())
+	}
+	return principal, nil
+}
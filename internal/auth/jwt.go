@@ -0,0 +1,256 @@
+package auth
+
+import (
+	"PeopleCRUD/internal/reqctx"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// JWTOptions задает, какие токены принимаются. Нужен либо Secret (HS256/384/512),
+// либо JWKSFile с открытыми ключами RSA/EC (RS*, PS*, ES*). Issuer и Audience проверяются, если заданы
+type JWTOptions struct {
+	Secret   string
+	JWKSFile string
+	Issuer   string
+	Audience string
+	Leeway   time.Duration
+}
+
+// JWTVerifier проверяет подпись и стандартные claims bearer-токенов
+type JWTVerifier struct {
+	parser  *jwt.Parser
+	keyFunc jwt.Keyfunc
+}
+
+func NewJWTVerifier(opts JWTOptions) (*JWTVerifier, error) {
+	parserOpts := []jwt.ParserOption{jwt.WithExpirationRequired(), jwt.WithLeeway(opts.Leeway)}
+	if opts.Issuer != "" {
+		parserOpts = append(parserOpts, jwt.WithIssuer(opts.Issuer))
+	}
+	if opts.Audience != "" {
+		parserOpts = append(parserOpts, jwt.WithAudience(opts.Audience))
+	}
+
+	verifier := &JWTVerifier{}
+	switch {
+	case opts.Secret != "" && opts.JWKSFile != "":
+		return nil, fmt.Errorf("JWT secret and JWKS file are mutually exclusive")
+	case opts.Secret != "":
+		secret := []byte(opts.Secret)
+		verifier.keyFunc = func(*jwt.Token) (interface{}, error) { return secret, nil }
+		parserOpts = append(parserOpts, jwt.WithValidMethods([]string{"HS256", "HS384", "HS512"}))
+	case opts.JWKSFile != "":
+		keys, err := loadJWKS(opts.JWKSFile)
+		if err != nil {
+			return nil, err
+		}
+		verifier.keyFunc = keys.keyFunc
+		parserOpts = append(parserOpts, jwt.WithValidMethods([]string{
+			"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512",
+		}))
+	default:
+		return nil, fmt.Errorf("either JWT secret or JWKS file is required")
+	}
+
+	verifier.parser = jwt.NewParser(parserOpts...)
+	return verifier, nil
+}
+
+// Verify возвращает вызывающего из токена. Subject берется из claim sub, имя - из name или preferred_username,
+// роли - из roles (массив или строка через пробел) или role, связанный человек - из person_id,
+// тенант - из tenant_id. Неизвестные роли отбрасываются
+func (v *JWTVerifier) Verify(tokenString string) (*Principal, error) {
+	claims := jwt.MapClaims{}
+	if _, err := v.parser.ParseWithClaims(tokenString, claims, v.keyFunc); err != nil {
+		return nil, err
+	}
+
+	subject, err := claims.GetSubject()
+	if err != nil || subject == "" {
+		return nil, fmt.Errorf("token has no subject")
+	}
+
+	tenant, _ := claims["tenant_id"].(string)
+	if tenant != "" && !reqctx.ValidTenant(tenant) {
+		return nil, fmt.Errorf("token has invalid tenant_id")
+	}
+
+	name, _ := claims["name"].(string)
+	if name == "" {
+		name, _ = claims["preferred_username"].(string)
+	}
+
+	return &Principal{
+		Subject:  subject,
+		Name:     name,
+		Method:   MethodJWT,
+		Roles:    claimRoles(claims),
+		PersonID: claimPersonID(claims),
+		TenantID: tenant,
+		Claims:   claims,
+	}, nil
+}
+
+func claimRoles(claims jwt.MapClaims) []string {
+	var candidates []string
+	switch value := claims["roles"].(type) {
+	case []interface{}:
+		for _, item := range value {
+			if role, ok := item.(string); ok {
+				candidates = append(candidates, role)
+			}
+		}
+	case string:
+		candidates = strings.Fields(value)
+	}
+	if role, ok := claims["role"].(string); ok {
+		candidates = append(candidates, role)
+	}
+
+	roles := []string{}
+	for _, role := range candidates {
+		if ValidRole(role) && !slices.Contains(roles, role) {
+			roles = append(roles, role)
+		}
+	}
+	return roles
+}
+
+func claimPersonID(claims jwt.MapClaims) *int {
+	var personID int
+	switch value := claims["person_id"].(type) {
+	case float64:
+		personID = int(value)
+		if float64(personID) != value {
+			return nil
+		}
+	case string:
+		var err error
+		if personID, err = strconv.Atoi(value); err != nil {
+			return nil
+		}
+	default:
+		return nil
+	}
+	if personID <= 0 {
+		return nil
+	}
+	return &personID
+}
+
+// jwks - открытые ключи по kid
+type jwks map[string]interface{}
+
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func loadJWKS(path string) (jwks, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read JWKS file: %w", err)
+	}
+
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("failed to parse JWKS file: %w", err)
+	}
+
+	keys := make(jwks, len(set.Keys))
+	for _, key := range set.Keys {
+		if key.Use != "" && key.Use != "sig" {
+			continue
+		}
+		publicKey, err := key.publicKey()
+		if err != nil {
+			return nil, fmt.Errorf("JWKS key %q: %w", key.Kid, err)
+		}
+		keys[key.Kid] = publicKey
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("JWKS file has no signing keys")
+	}
+	return keys, nil
+}
+
+// keyFunc выбирает ключ по kid из заголовка. Без kid подходит только единственный ключ набора
+func (k jwks) keyFunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" && len(k) == 1 {
+		for _, key := range k {
+			return key, nil
+		}
+	}
+	key, exists := k[kid]
+	if !exists {
+		return nil, fmt.Errorf("unknown key id %q", kid)
+	}
+	return key, nil
+}
+
+func (k jsonWebKey) publicKey() (interface{}, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+}
+
+func decodeBigInt(value string) (*big.Int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil || len(data) == 0 {
+		return nil, fmt.Errorf("invalid base64url integer")
+	}
+	return new(big.Int).SetBytes(data), nil
+}
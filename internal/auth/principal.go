@@ -0,0 +1,42 @@
+package auth
+
+import "context"
+
+// Способы, которыми вызывающий подтвердил личность
+const (
+	MethodAPIKey = "api_key"
+	MethodJWT    = "jwt"
+)
+
+// GinKey - ключ, под которым middleware кладет *Principal в gin.Context
+const GinKey = "principal"
+
+// Principal - аутентифицированный вызывающий
+type Principal struct {
+	// Subject - устойчивый идентификатор: "api_key:<id>" для ключей, claim sub для JWT
+	Subject string   `json:"subject"`
+	Name    string   `json:"name,omitempty"`
+	Method  string   `json:"method"`
+	Roles   []string `json:"roles"`
+	// PersonID - человек, с которым связан вызывающий, для правила самообслуживания
+	PersonID *int `json:"person_id,omitempty"`
+	// TenantID - тенант, к которому привязан вызывающий. Пусто - тенант по умолчанию
+	TenantID string `json:"tenant_id,omitempty"`
+	// CrossTenant - вызывающий не привязан к тенанту и выбирает его заголовком X-Tenant-ID.
+	// Так работает только ключ начальной настройки
+	CrossTenant bool `json:"cross_tenant,omitempty"`
+	// Claims - все claims JWT, для ключей пусто
+	Claims map[string]interface{} `json:"-"`
+}
+
+type contextKey struct{}
+
+func WithPrincipal(ctx context.Context, principal *Principal) context.Context {
+	return context.WithValue(ctx, contextKey{}, principal)
+}
+
+// FromContext возвращает вызывающего, если запрос прошел аутентификацию
+func FromContext(ctx context.Context) (*Principal, bool) {
+	principal, ok := ctx.Value(contextKey{}).(*Principal)
+	return principal, ok && principal != nil
+}
@@ -0,0 +1,61 @@
+package auth
+
+import (
+	"PeopleCRUD/pkg/errors"
+	"slices"
+)
+
+// Роли вызывающих. Каждая следующая включает права предыдущей
+const (
+	RoleReader = "reader"
+	RoleEditor = "editor"
+	RoleAdmin  = "admin"
+)
+
+type Permission string
+
+const (
+	PermPeopleRead   Permission = "people:read"
+	PermPeopleWrite  Permission = "people:write"
+	PermPeopleDelete Permission = "people:delete"
+	PermAuditRead    Permission = "audit:read"
+	PermKeysManage   Permission = "keys:manage"
+)
+
+var rolePermissions = map[string][]Permission{
+	RoleReader: {PermPeopleRead},
+	RoleEditor: {PermPeopleRead, PermPeopleWrite, PermAuditRead},
+	RoleAdmin:  {PermPeopleRead, PermPeopleWrite, PermAuditRead, PermPeopleDelete, PermKeysManage},
+}
+
+func ValidRole(role string) bool {
+	_, ok := rolePermissions[role]
+	return ok
+}
+
+// Can проверяет, дает ли хотя бы одна роль вызывающего разрешение
+func (p *Principal) Can(permission Permission) bool {
+	for _, role := range p.Roles {
+		if slices.Contains(rolePermissions[role], permission) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsPerson сообщает, связан ли вызывающий с человеком personID
+func (p *Principal) IsPerson(personID int) bool {
+	return p.PersonID != nil && *p.PersonID == personID
+}
+
+// Authorize возвращает 403, если вызывающему не хватает разрешения. selfPersonID - человек, которого
+// касается запрос: при включенном самообслуживании связанный с ним вызывающий проходит и без разрешения
+func (a *Authenticator) Authorize(principal *Principal, permission Permission, selfPersonID *int) error {
+	if principal.Can(permission) {
+		return nil
+	}
+	if a.selfService && selfPersonID != nil && principal.IsPerson(*selfPersonID) {
+		return nil
+	}
+	return errors.NewForbiddenError("Permission denied: " + string(permission))
+}
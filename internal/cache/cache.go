@@ -1,89 +1,68 @@
 package cache
 
 import (
-	"strings"
-	"sync"
+	"reflect"
 	"time"
 )
 
-type CacheItem struct {
-	Value      interface{}
-	Expiration time.Time
+// Cache - общий кэш сервисов. Значения должны переживать сериализацию в JSON:
+// реализация может хранить их вне процесса и отдавать копию.
+type Cache interface {
+	// Get записывает значение по ключу в dest (указатель на переменную того же типа, что передавался в Set)
+	Get(key string, dest interface{}) bool
+	// Set кладет значение на ttl и привязывает ключ к тегам для группового удаления
+	Set(key string, value interface{}, ttl time.Duration, tags ...string)
+	Delete(key string)
+	// InvalidateTag удаляет все ключи, положенные с этим тегом
+	InvalidateTag(tag string)
+	// Version возвращает текущую версию кэша. Delete и InvalidateTag увеличивают ее и запоминают
+	// новое значение как версию ключа или тега на versionTTL
+	Version() uint64
+	// SetIfUnchanged как Set, но ничего не кладет, если ключ или один из тегов сбрасывались после версии since.
+	// Так загрузка, начатая до инвалидации, не запишет прочитанные ею устаревшие данные
+	SetIfUnchanged(key string, value interface{}, ttl time.Duration, since uint64, tags ...string) bool
+	Stats() Stats
+	Stop()
 }
 
-type MemoryCache struct {
-	items map[string]CacheItem
-	mutex sync.RWMutex
-}
+// Сколько помнить версию сброшенного ключа или тега. Загрузка дольше этого может записать устаревшее значение
+const versionTTL = 10 * time.Minute
 
-func NewMemoryCache() *MemoryCache {
-	cache := &MemoryCache{
-		items: make(map[string]CacheItem),
-	}
-	go cache.cleanupExpired()
-	return cache
+func keyMark(key string) string {
+	return "key:" + key
 }
 
-func (c *MemoryCache) Set(key string, value interface{}, duration time.Duration) {
-	c.mutex.Lock()
-	defer c.mutex.Unlock()
-
-	c.items[key] = CacheItem{
-		Value:      value,
-		Expiration: time.Now().Add(duration),
-	}
+func tagMark(tag string) string {
+	return "tag:" + tag
 }
 
-func (c *MemoryCache) Get(key string) (interface{}, bool) {
-	c.mutex.RLock()
-	defer c.mutex.RUnlock()
-
-	item, exists := c.items[key]
-	if !exists {
-		return nil, false
-	}
-
-	if time.Now().After(item.Expiration) {
-		c.mutex.RUnlock()
-		c.mutex.Lock()
-		delete(c.items, key)
-		c.mutex.Unlock()
-		c.mutex.RLock()
-		return nil, false
-	}
-
-	return item.Value, true
+// TenantKey добавляет к ключу или тегу префикс тенанта, чтобы данные разных тенантов не пересекались
+func TenantKey(tenant, key string) string {
+	return "tenant:" + tenant + ":" + key
 }
 
-func (c *MemoryCache) Delete(key string) {
-	c.mutex.Lock()
-	defer c.mutex.Unlock()
-	delete(c.items, key)
+// Stats - накопленные счетчики и текущий размер кэша. Entries и Bytes известны не всем реализациям
+type Stats struct {
+	Hits      uint64 `json:"hits"`
+	Misses    uint64 `json:"misses"`
+	Evictions uint64 `json:"evictions"`
+	Expired   uint64 `json:"expired"`
+	Entries   int    `json:"entries"`
+	Bytes     int64  `json:"bytes"`
 }
 
-func (c *MemoryCache) DeleteByPrefix(prefix string) {
-	c.mutex.Lock()
-	defer c.mutex.Unlock()
-
-	for key := range c.items {
-		if strings.HasPrefix(key, prefix) {
-			delete(c.items, key)
-		}
+// assign копирует value в *dest, если типы совместимы
+func assign(dest, value interface{}) bool {
+	target := reflect.ValueOf(dest)
+	if target.Kind() != reflect.Pointer || target.IsNil() {
+		return false
 	}
-}
-
-func (c *MemoryCache) cleanupExpired() {
-	ticker := time.NewTicker(time.Minute)
-	defer ticker.Stop()
 
-	for range ticker.C {
-		c.mutex.Lock()
-		now := time.Now()
-		for key, item := range c.items {
-			if now.After(item.Expiration) {
-				delete(c.items, key)
-			}
-		}
-		c.mutex.Unlock()
+	source := reflect.ValueOf(value)
+	if !source.IsValid() || !source.Type().AssignableTo(target.Elem().Type()) {
+		return false
 	}
+
+	target.Elem().Set(source)
+	return true
 }
@@ -0,0 +1,172 @@
+package cache
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+// cacheFactory создает пустой кэш и функцию, которая сдвигает для него время
+type cacheFactory func(t *testing.T) (Cache, func(time.Duration))
+
+type contractValue struct {
+	Name  string   `json:"name"`
+	Items []string `json:"items"`
+}
+
+// runCacheContract проверяет поведение, на которое рассчитывают сервисы, одинаково для всех реализаций Cache
+func runCacheContract(t *testing.T, newCache cacheFactory) {
+	t.Run("set and get", func(t *testing.T) {
+		cache, _ := newCache(t)
+		want := contractValue{Name: "Ivan", Items: []string{"a", "b"}}
+		cache.Set("person:1", want, time.Minute)
+
+		var got contractValue
+		if !cache.Get("person:1", &got) {
+			t.Fatal("value is missing")
+		}
+		if got.Name != want.Name || len(got.Items) != 2 || got.Items[1] != "b" {
+			t.Errorf("got %+v, want %+v", got, want)
+		}
+	})
+
+	t.Run("miss", func(t *testing.T) {
+		cache, _ := newCache(t)
+		var got contractValue
+		if cache.Get("absent", &got) {
+			t.Error("absent key was found")
+		}
+		if stats := cache.Stats(); stats.Misses != 1 || stats.Hits != 0 {
+			t.Errorf("stats = %+v, want 1 miss", stats)
+		}
+	})
+
+	t.Run("overwrite", func(t *testing.T) {
+		cache, _ := newCache(t)
+		cache.Set("key", 1, time.Minute)
+		cache.Set("key", 2, time.Minute)
+
+		var got int
+		if !cache.Get("key", &got) || got != 2 {
+			t.Errorf("got %d, want 2", got)
+		}
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		cache, _ := newCache(t)
+		cache.Set("key", 1, time.Minute, "tag")
+		cache.Delete("key")
+
+		var got int
+		if cache.Get("key", &got) {
+			t.Error("deleted key was found")
+		}
+		cache.InvalidateTag("tag")
+	})
+
+	t.Run("ttl", func(t *testing.T) {
+		cache, advance := newCache(t)
+		cache.Set("short", 1, 30*time.Millisecond)
+		cache.Set("long", 2, time.Minute)
+		advance(60 * time.Millisecond)
+
+		var got int
+		if cache.Get("short", &got) {
+			t.Error("expired key was found")
+		}
+		if !cache.Get("long", &got) || got != 2 {
+			t.Error("live key is missing")
+		}
+	})
+
+	t.Run("invalidate tag", func(t *testing.T) {
+		cache, _ := newCache(t)
+		for i := 0; i < 10; i++ {
+			cache.Set("person:"+strconv.Itoa(i), i, time.Minute, "people", "person:"+strconv.Itoa(i))
+		}
+		cache.Set("stats", 1, time.Minute, "stats")
+
+		cache.InvalidateTag("person:3")
+		var got int
+		if cache.Get("person:3", &got) {
+			t.Error("person:3 survived invalidation of its own tag")
+		}
+		if !cache.Get("person:4", &got) {
+			t.Error("person:4 was invalidated by another person's tag")
+		}
+
+		cache.InvalidateTag("people")
+		for i := 0; i < 10; i++ {
+			if cache.Get("person:"+strconv.Itoa(i), &got) {
+				t.Errorf("person:%d survived invalidation of the shared tag", i)
+			}
+		}
+		if !cache.Get("stats", &got) {
+			t.Error("entry with an unrelated tag was invalidated")
+		}
+
+		cache.InvalidateTag("never-used")
+	})
+
+	t.Run("tag outlives shorter keys", func(t *testing.T) {
+		cache, advance := newCache(t)
+		cache.Set("long", 1, time.Minute, "tag")
+		cache.Set("short", 2, 30*time.Millisecond, "tag")
+		advance(60 * time.Millisecond)
+
+		cache.InvalidateTag("tag")
+		var got int
+		if cache.Get("long", &got) {
+			t.Error("long-lived key survived invalidation after a shorter key with the same tag expired")
+		}
+	})
+
+	t.Run("set if unchanged", func(t *testing.T) {
+		cache, _ := newCache(t)
+		since := cache.Version()
+
+		cache.InvalidateTag("other")
+		if !cache.SetIfUnchanged("person:1", 1, time.Minute, since, "person:1") {
+			t.Error("invalidation of an unrelated tag blocked the write")
+		}
+
+		cache.InvalidateTag("person:2")
+		if cache.SetIfUnchanged("person:2", 2, time.Minute, since, "people", "person:2") {
+			t.Error("write went through after its tag was invalidated")
+		}
+		cache.Delete("person:3")
+		if cache.SetIfUnchanged("person:3", 3, time.Minute, since) {
+			t.Error("write went through after its key was deleted")
+		}
+
+		var got int
+		if cache.Get("person:2", &got) || cache.Get("person:3", &got) {
+			t.Error("rejected write is visible")
+		}
+		if !cache.SetIfUnchanged("person:2", 2, time.Minute, cache.Version(), "people", "person:2") {
+			t.Error("write with the current version was rejected")
+		}
+		if version := cache.Version(); version <= since {
+			t.Errorf("version %d did not grow past %d after invalidations", version, since)
+		}
+	})
+
+	t.Run("stats", func(t *testing.T) {
+		cache, _ := newCache(t)
+		cache.Set("key", 1, time.Minute)
+
+		var got int
+		cache.Get("key", &got)
+		cache.Get("key", &got)
+		cache.Get("absent", &got)
+		if stats := cache.Stats(); stats.Hits != 2 || stats.Misses != 1 {
+			t.Errorf("stats = %+v, want 2 hits and 1 miss", stats)
+		}
+	})
+}
+
+func TestMemoryCacheContract(t *testing.T) {
+	runCacheContract(t, func(t *testing.T) (Cache, func(time.Duration)) {
+		return newTestMemoryCache(t, Options{}), time.Sleep
+	})
+}
@@ -0,0 +1,136 @@
+package cache
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"golang.org/x/sync/singleflight"
+)
+
+// Policy задает жизнь записей одного семейства ключей - части ключа до первого двоеточия.
+// TTL - сколько значение считается свежим. StaleTTL - сколько после этого старое значение еще отдается,
+// пока одна горутина обновляет его в фоне (0 - не отдается). EarlyRefresh - коэффициент beta
+// вероятностного раннего обновления: чем он больше и чем дольше загрузка, тем раньше до конца TTL
+// запись начинают обновлять в фоне (0 - выключено)
+type Policy struct {
+	TTL          time.Duration
+	StaleTTL     time.Duration
+	EarlyRefresh float64
+}
+
+var defaultPolicy = Policy{TTL: 5 * time.Minute}
+
+// Loader - кэш, через который значения загружаются с защитой от лавины промахов:
+// одновременные загрузки одного ключа объединяются в одну. Загрузка, во время которой ее ключ
+// или один из тегов сбросили (на любой реплике), могла прочитать устаревшие данные и в кэш не пишется
+type Loader struct {
+	Cache
+	policies map[string]Policy
+	logger   *logrus.Logger
+
+	group singleflight.Group
+}
+
+// envelope - то, что лежит в кэше под ключом: значение, момент, до которого оно свежее,
+// и сколько заняла его загрузка
+type envelope[T any] struct {
+	Value     T             `json:"value"`
+	FreshTill time.Time     `json:"fresh_till"`
+	Delta     time.Duration `json:"delta"`
+}
+
+func NewLoader(cache Cache, policies map[string]Policy, logger *logrus.Logger) *Loader {
+	return &Loader{
+		Cache:    cache,
+		policies: policies,
+		logger:   logger,
+	}
+}
+
+// policy выбирает политику по семейству - части ключа до первого двоеточия после префикса тенанта
+func (l *Loader) policy(key string) Policy {
+	if rest, found := strings.CutPrefix(key, "tenant:"); found {
+		_, key, _ = strings.Cut(rest, ":")
+	}
+	family, _, _ := strings.Cut(key, ":")
+	if policy, exists := l.policies[family]; exists {
+		return policy
+	}
+	return defaultPolicy
+}
+
+// Fetch отдает значение по ключу, а при промахе загружает его через load, который возвращает
+// значение и теги для него. Свежее значение отдается сразу; устаревшее в пределах StaleTTL тоже,
+// но одновременно запускается фоновое обновление. load выполняется без отмены ctx,
+// потому что его результат нужен всем объединенным запросам
+func Fetch[T any](ctx context.Context, l *Loader, key string, load func(ctx context.Context) (T, []string, error)) (T, error) {
+	policy := l.policy(key)
+
+	var cached envelope[T]
+	if l.Get(key, &cached) {
+		now := time.Now()
+		if now.After(cached.FreshTill) || policy.refreshEarly(cached.FreshTill, cached.Delta, now) {
+			refresh(ctx, l, key, policy, load)
+		}
+		return cached.Value, nil
+	}
+
+	version := l.Version()
+	value, err, _ := l.group.Do(flightKey(key, version), func() (interface{}, error) {
+		return fill(context.WithoutCancel(ctx), l, key, version, policy, load)
+	})
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	return value.(T), nil
+}
+
+// refresh обновляет запись в фоне. Если обновление этого ключа уже идет, второе не запускается
+func refresh[T any](ctx context.Context, l *Loader, key string, policy Policy, load func(ctx context.Context) (T, []string, error)) {
+	version := l.Version()
+	results := l.group.DoChan(flightKey(key, version), func() (interface{}, error) {
+		return fill(context.WithoutCancel(ctx), l, key, version, policy, load)
+	})
+
+	go func() {
+		if result := <-results; result.Err != nil {
+			l.logger.WithError(result.Err).WithField("key", key).Warn("Background cache refresh failed")
+		}
+	}()
+}
+
+// refreshEarly решает, пора ли обновлять еще свежую запись (алгоритм XFetch): вероятность растет
+// по мере приближения к концу TTL и тем быстрее, чем дольше шла загрузка
+func (p Policy) refreshEarly(freshTill time.Time, delta time.Duration, now time.Time) bool {
+	if p.EarlyRefresh <= 0 || delta <= 0 {
+		return false
+	}
+	gap := time.Duration(float64(delta) * p.EarlyRefresh * -math.Log(rand.Float64()))
+	return !now.Add(gap).Before(freshTill)
+}
+
+// fill загружает значение и кладет его, только если ключ и теги не сбрасывались с версии version,
+// взятой до начала загрузки
+func fill[T any](ctx context.Context, l *Loader, key string, version uint64, policy Policy, load func(ctx context.Context) (T, []string, error)) (interface{}, error) {
+	start := time.Now()
+	value, tags, err := load(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	entry := envelope[T]{Value: value, FreshTill: now.Add(policy.TTL), Delta: now.Sub(start)}
+	l.SetIfUnchanged(key, entry, policy.TTL+policy.StaleTTL, version, tags...)
+	return value, nil
+}
+
+// flightKey включает версию, чтобы запрос после инвалидации не присоединился к загрузке, начатой до нее
+func flightKey(key string, version uint64) string {
+	return key + "@" + strconv.FormatUint(version, 10)
+}
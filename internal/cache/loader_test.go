@@ -0,0 +1,133 @@
+package cache
+
+import (
+	"context"
+	"io"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func newTestLoader(cache Cache) *Loader {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	return NewLoader(cache, nil, logger)
+}
+
+// slowLoad возвращает загрузку, которая ждет release и отдает текущее значение *source
+func slowLoad(started chan<- struct{}, release <-chan struct{}, source *atomic.Int64, tags ...string) func(context.Context) (int64, []string, error) {
+	return func(context.Context) (int64, []string, error) {
+		value := source.Load()
+		if started != nil {
+			close(started)
+			<-release
+		}
+		return value, tags, nil
+	}
+}
+
+// fetchDuringInvalidation запускает загрузку key в owner, пока она идет, вызывает invalidate
+// (ее может выполнить другая реплика) и меняет исходные данные. Возвращает, что owner отдает после этого
+func fetchDuringInvalidation(t *testing.T, owner *Loader, key string, tags []string, invalidate func()) int64 {
+	t.Helper()
+	var source atomic.Int64
+	source.Store(1)
+	started, release := make(chan struct{}), make(chan struct{})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if _, err := Fetch(context.Background(), owner, key, slowLoad(started, release, &source, tags...)); err != nil {
+			t.Errorf("fetch: %v", err)
+		}
+	}()
+
+	<-started
+	source.Store(2)
+	invalidate()
+	close(release)
+	<-done
+
+	value, err := Fetch(context.Background(), owner, key, slowLoad(nil, nil, &source, tags...))
+	if err != nil {
+		t.Fatalf("fetch after invalidation: %v", err)
+	}
+	return value
+}
+
+func TestLoaderDropsLoadInvalidatedByTag(t *testing.T) {
+	loader := newTestLoader(newTestMemoryCache(t, Options{}))
+
+	got := fetchDuringInvalidation(t, loader, "person:1", []string{"person:1"}, func() {
+		loader.InvalidateTag("person:1")
+	})
+	if got != 2 {
+		t.Errorf("got %d: the value loaded before invalidation was cached", got)
+	}
+}
+
+func TestLoaderDropsLoadInvalidatedByKey(t *testing.T) {
+	loader := newTestLoader(newTestMemoryCache(t, Options{}))
+
+	got := fetchDuringInvalidation(t, loader, "person:1", nil, func() {
+		loader.Delete("person:1")
+	})
+	if got != 2 {
+		t.Errorf("got %d: the value loaded before deletion was cached", got)
+	}
+}
+
+func TestLoaderKeepsLoadOnUnrelatedInvalidation(t *testing.T) {
+	loader := newTestLoader(newTestMemoryCache(t, Options{}))
+
+	got := fetchDuringInvalidation(t, loader, "person:1", []string{"person:1"}, func() {
+		loader.InvalidateTag("person:2")
+		loader.Delete("stats")
+	})
+	if got != 1 {
+		t.Errorf("got %d: invalidation of other entries discarded an unaffected load", got)
+	}
+}
+
+// Версии хранятся в Redis, поэтому инвалидация на одной реплике отменяет запись загрузки на другой
+func TestLoaderDropsLoadInvalidatedOnAnotherReplica(t *testing.T) {
+	first, server := newTestRedisCache(t)
+	second := NewRedisCache(newRedisClient(server.Addr()), "test:", first.logger)
+	t.Cleanup(second.Stop)
+	replicaA, replicaB := newTestLoader(first), newTestLoader(second)
+
+	got := fetchDuringInvalidation(t, replicaA, "person:1", []string{"person:1"}, func() {
+		replicaB.InvalidateTag("person:1")
+	})
+	if got != 2 {
+		t.Errorf("got %d: replica A cached a value invalidated by replica B", got)
+	}
+}
+
+func TestLoaderServesStaleWhileRefreshing(t *testing.T) {
+	loader := newTestLoader(newTestMemoryCache(t, Options{}))
+	loader.policies = map[string]Policy{"person": {TTL: 10 * time.Millisecond, StaleTTL: time.Minute}}
+
+	var source atomic.Int64
+	source.Store(1)
+	if _, err := Fetch(context.Background(), loader, "person:1", slowLoad(nil, nil, &source)); err != nil {
+		t.Fatal(err)
+	}
+	source.Store(2)
+	time.Sleep(20 * time.Millisecond)
+
+	got, err := Fetch(context.Background(), loader, "person:1", slowLoad(nil, nil, &source))
+	if err != nil || got != 1 {
+		t.Fatalf("stale read = %d, %v, want the old value 1", got, err)
+	}
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		if got, _ = Fetch(context.Background(), loader, "person:1", slowLoad(nil, nil, &source)); got == 2 {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Errorf("background refresh did not replace the stale value, still %d", got)
+}
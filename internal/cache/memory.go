@@ -0,0 +1,318 @@
+package cache
+
+import (
+	"container/list"
+	"hash/fnv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Options ограничивает размер кэша. Лимиты делятся поровну между шардами,
+// нулевые значения заменяются значениями по умолчанию
+type Options struct {
+	MaxEntries      int
+	MaxBytes        int64
+	Shards          int
+	CleanupInterval time.Duration
+}
+
+const (
+	defaultMaxEntries      = 10000
+	defaultMaxBytes        = 256 << 20
+	defaultShards          = 16
+	defaultCleanupInterval = time.Minute
+)
+
+type CacheItem struct {
+	Key        string
+	Value      interface{}
+	Tags       []string
+	Expiration time.Time
+	Size       int64
+}
+
+// MemoryCache - LRU-кэш в памяти одного процесса с ограничением по числу записей и примерному объему.
+// Ключи распределены по шардам, у каждого свой мьютекс и свой список LRU.
+type MemoryCache struct {
+	shards []*shard
+
+	hits      atomic.Uint64
+	misses    atomic.Uint64
+	evictions atomic.Uint64
+	expired   atomic.Uint64
+
+	// Версии сброшенных ключей и тегов для SetIfUnchanged
+	version  atomic.Uint64
+	marksMu  sync.Mutex
+	marks    map[string]versionMark
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+type versionMark struct {
+	version    uint64
+	expiration time.Time
+}
+
+type shard struct {
+	mutex sync.Mutex
+	items map[string]*list.Element
+	lru   *list.List
+	// Индекс тег -> записи, чтобы InvalidateTag не перебирал весь шард
+	tags       map[string]map[*list.Element]struct{}
+	bytes      int64
+	maxEntries int
+	maxBytes   int64
+}
+
+func NewMemoryCache(opts Options) *MemoryCache {
+	if opts.MaxEntries <= 0 {
+		opts.MaxEntries = defaultMaxEntries
+	}
+	if opts.MaxBytes <= 0 {
+		opts.MaxBytes = defaultMaxBytes
+	}
+	if opts.Shards <= 0 {
+		opts.Shards = defaultShards
+	}
+	if opts.CleanupInterval <= 0 {
+		opts.CleanupInterval = defaultCleanupInterval
+	}
+
+	cache := &MemoryCache{
+		shards: make([]*shard, opts.Shards),
+		marks:  make(map[string]versionMark),
+		stop:   make(chan struct{}),
+	}
+	for i := range cache.shards {
+		cache.shards[i] = &shard{
+			items:      make(map[string]*list.Element),
+			lru:        list.New(),
+			tags:       make(map[string]map[*list.Element]struct{}),
+			maxEntries: max(1, opts.MaxEntries/opts.Shards),
+			maxBytes:   max(1, opts.MaxBytes/int64(opts.Shards)),
+		}
+	}
+
+	go cache.cleanupExpired(opts.CleanupInterval)
+	return cache
+}
+
+func (c *MemoryCache) shardFor(key string) *shard {
+	hash := fnv.New32a()
+	hash.Write([]byte(key))
+	return c.shards[hash.Sum32()%uint32(len(c.shards))]
+}
+
+func (c *MemoryCache) Set(key string, value interface{}, duration time.Duration, tags ...string) {
+	s := c.shardFor(key)
+	size := int64(len(key)) + estimateSize(value)
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	c.set(s, key, value, size, duration, tags)
+}
+
+// SetIfUnchanged проверяет версии под мьютексом шарда. Delete и InvalidateTag запоминают версию до того,
+// как берут мьютексы шардов, поэтому запись, прошедшая проверку до инвалидации, будет ею удалена
+func (c *MemoryCache) SetIfUnchanged(key string, value interface{}, duration time.Duration, since uint64, tags ...string) bool {
+	s := c.shardFor(key)
+	size := int64(len(key)) + estimateSize(value)
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if c.changedSince(since, key, tags) {
+		return false
+	}
+	c.set(s, key, value, size, duration, tags)
+	return true
+}
+
+// set кладет значение и вытесняет давно не использованные записи шарда, пока он не уложится в лимиты.
+// Значение больше всего объема шарда не кэшируется. Вызывается под мьютексом шарда
+func (c *MemoryCache) set(s *shard, key string, value interface{}, size int64, duration time.Duration, tags []string) {
+	if element, exists := s.items[key]; exists {
+		s.remove(element)
+	}
+	if size > s.maxBytes {
+		c.evictions.Add(1)
+		return
+	}
+
+	item := &CacheItem{
+		Key:        key,
+		Value:      value,
+		Tags:       tags,
+		Expiration: time.Now().Add(duration),
+		Size:       size,
+	}
+	element := s.lru.PushFront(item)
+	s.items[key] = element
+	s.bytes += size
+	for _, tag := range tags {
+		elements, exists := s.tags[tag]
+		if !exists {
+			elements = make(map[*list.Element]struct{})
+			s.tags[tag] = elements
+		}
+		elements[element] = struct{}{}
+	}
+
+	for len(s.items) > s.maxEntries || s.bytes > s.maxBytes {
+		s.remove(s.lru.Back())
+		c.evictions.Add(1)
+	}
+}
+
+// Get отдает сам сохраненный объект, а не копию: изменять его нельзя
+func (c *MemoryCache) Get(key string, dest interface{}) bool {
+	s := c.shardFor(key)
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	element, exists := s.items[key]
+	if !exists {
+		c.misses.Add(1)
+		return false
+	}
+
+	item := element.Value.(*CacheItem)
+	if time.Now().After(item.Expiration) {
+		s.remove(element)
+		c.expired.Add(1)
+		c.misses.Add(1)
+		return false
+	}
+
+	if !assign(dest, item.Value) {
+		c.misses.Add(1)
+		return false
+	}
+
+	s.lru.MoveToFront(element)
+	c.hits.Add(1)
+	return true
+}
+
+func (c *MemoryCache) Delete(key string) {
+	c.mark(keyMark(key))
+	s := c.shardFor(key)
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if element, exists := s.items[key]; exists {
+		s.remove(element)
+	}
+}
+
+func (c *MemoryCache) InvalidateTag(tag string) {
+	c.mark(tagMark(tag))
+	for _, s := range c.shards {
+		s.mutex.Lock()
+		for element := range s.tags[tag] {
+			s.remove(element)
+		}
+		s.mutex.Unlock()
+	}
+}
+
+func (c *MemoryCache) Stats() Stats {
+	stats := Stats{
+		Hits:      c.hits.Load(),
+		Misses:    c.misses.Load(),
+		Evictions: c.evictions.Load(),
+		Expired:   c.expired.Load(),
+	}
+	for _, s := range c.shards {
+		s.mutex.Lock()
+		stats.Entries += len(s.items)
+		stats.Bytes += s.bytes
+		s.mutex.Unlock()
+	}
+	return stats
+}
+
+func (c *MemoryCache) Version() uint64 {
+	return c.version.Load()
+}
+
+func (c *MemoryCache) mark(name string) {
+	c.marksMu.Lock()
+	defer c.marksMu.Unlock()
+	c.marks[name] = versionMark{version: c.version.Add(1), expiration: time.Now().Add(versionTTL)}
+}
+
+// changedSince сообщает, сбрасывались ли ключ или один из тегов после версии since
+func (c *MemoryCache) changedSince(since uint64, key string, tags []string) bool {
+	c.marksMu.Lock()
+	defer c.marksMu.Unlock()
+
+	if c.marks[keyMark(key)].version > since {
+		return true
+	}
+	for _, tag := range tags {
+		if c.marks[tagMark(tag)].version > since {
+			return true
+		}
+	}
+	return false
+}
+
+// Stop останавливает фоновую очистку. Кэш остается рабочим, просроченные записи удаляются при чтении
+func (c *MemoryCache) Stop() {
+	c.stopOnce.Do(func() { close(c.stop) })
+}
+
+// remove вызывается под мьютексом шарда
+func (s *shard) remove(element *list.Element) {
+	item := element.Value.(*CacheItem)
+	s.lru.Remove(element)
+	delete(s.items, item.Key)
+	s.bytes -= item.Size
+	for _, tag := range item.Tags {
+		elements := s.tags[tag]
+		delete(elements, element)
+		if len(elements) == 0 {
+			delete(s.tags, tag)
+		}
+	}
+}
+
+func (c *MemoryCache) cleanupExpired(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.stop:
+			return
+		case <-ticker.C:
+		}
+
+		for _, s := range c.shards {
+			s.mutex.Lock()
+			now := time.Now()
+			for _, element := range s.items {
+				if now.After(element.Value.(*CacheItem).Expiration) {
+					s.remove(element)
+					c.expired.Add(1)
+				}
+			}
+			s.mutex.Unlock()
+		}
+
+		c.marksMu.Lock()
+		now := time.Now()
+		for name, mark := range c.marks {
+			if now.After(mark.expiration) {
+				delete(c.marks, name)
+			}
+		}
+		c.marksMu.Unlock()
+	}
+}
@@ -0,0 +1,194 @@
+package cache
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newTestMemoryCache(t *testing.T, opts Options) *MemoryCache {
+	t.Helper()
+	cache := NewMemoryCache(opts)
+	t.Cleanup(cache.Stop)
+	return cache
+}
+
+func TestMemoryCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	cache := newTestMemoryCache(t, Options{MaxEntries: 2, Shards: 1})
+
+	cache.Set("a", "1", time.Minute)
+	cache.Set("b", "2", time.Minute)
+	var value string
+	if !cache.Get("a", &value) {
+		t.Fatal("a is missing before eviction")
+	}
+	// a только что прочитан, поэтому вытесняется b
+	cache.Set("c", "3", time.Minute)
+
+	if cache.Get("b", &value) {
+		t.Error("b should have been evicted")
+	}
+	for _, key := range []string{"a", "c"} {
+		if !cache.Get(key, &value) {
+			t.Errorf("%s should have survived eviction", key)
+		}
+	}
+	if stats := cache.Stats(); stats.Evictions != 1 || stats.Entries != 2 {
+		t.Errorf("stats = %+v, want 1 eviction and 2 entries", stats)
+	}
+}
+
+func TestMemoryCacheByteBound(t *testing.T) {
+	cache := newTestMemoryCache(t, Options{MaxBytes: 200, Shards: 1})
+	// Запись ключа из одного символа со строкой из 60 байт весит 1 + 16 + 60 = 77 байт
+	payload := strings.Repeat("x", 60)
+
+	for _, key := range []string{"a", "b", "c"} {
+		cache.Set(key, payload, time.Minute)
+		if stats := cache.Stats(); stats.Bytes > 200 {
+			t.Fatalf("after setting %s cache holds %d bytes, limit is 200", key, stats.Bytes)
+		}
+	}
+
+	var value string
+	if cache.Get("a", &value) {
+		t.Error("a should have been evicted to fit the byte limit")
+	}
+	if stats := cache.Stats(); stats.Entries != 2 || stats.Bytes != 154 {
+		t.Errorf("stats = %+v, want 2 entries of 154 bytes", stats)
+	}
+
+	// Значение больше всего объема не кэшируется и не вытесняет остальные
+	cache.Set("huge", strings.Repeat("x", 500), time.Minute)
+	if cache.Get("huge", &value) {
+		t.Error("value larger than the cache should not be stored")
+	}
+	if !cache.Get("c", &value) {
+		t.Error("oversized value evicted existing entries")
+	}
+}
+
+func TestMemoryCacheInvalidateTag(t *testing.T) {
+	cache := newTestMemoryCache(t, Options{Shards: 4})
+
+	for i := 0; i < 20; i++ {
+		cache.Set("person:"+strconv.Itoa(i), i, time.Minute, "people", "person:"+strconv.Itoa(i))
+	}
+	cache.Set("stats", 1, time.Minute, "stats")
+	// Повторный Set без тега отвязывает ключ от него
+	cache.Set("person:0", 0, time.Minute)
+
+	cache.InvalidateTag("people")
+
+	var value int
+	for i := 1; i < 20; i++ {
+		if cache.Get("person:"+strconv.Itoa(i), &value) {
+			t.Errorf("person:%d survived invalidation of its tag", i)
+		}
+	}
+	if !cache.Get("person:0", &value) {
+		t.Error("person:0 was re-set without the tag and should survive")
+	}
+	if !cache.Get("stats", &value) {
+		t.Error("entry with another tag was invalidated")
+	}
+
+	// Удаленные записи не остаются в индексе тегов
+	for _, s := range cache.shards {
+		s.mutex.Lock()
+		for tag := range s.tags {
+			if tag != "stats" {
+				t.Errorf("tag index still holds %q", tag)
+			}
+		}
+		s.mutex.Unlock()
+	}
+}
+
+func TestMemoryCacheExpiration(t *testing.T) {
+	cache := newTestMemoryCache(t, Options{Shards: 1, CleanupInterval: time.Hour})
+
+	cache.Set("short", "value", 10*time.Millisecond)
+	cache.Set("long", "value", time.Minute)
+	time.Sleep(20 * time.Millisecond)
+
+	var value string
+	if cache.Get("short", &value) {
+		t.Error("expired entry was returned")
+	}
+	if !cache.Get("long", &value) {
+		t.Error("live entry is missing")
+	}
+	if stats := cache.Stats(); stats.Expired != 1 || stats.Entries != 1 {
+		t.Errorf("stats = %+v, want 1 expired and 1 entry", stats)
+	}
+}
+
+func TestMemoryCacheGetTypeMismatch(t *testing.T) {
+	cache := newTestMemoryCache(t, Options{})
+
+	cache.Set("key", "string", time.Minute)
+	var number int
+	if cache.Get("key", &number) {
+		t.Error("value of another type was assigned")
+	}
+}
+
+// Запускать с -race: одновременные Set, Get, Delete и InvalidateTag при постоянном вытеснении
+// не должны гоняться за данными и ломать учет записей, байтов и тегов
+func TestMemoryCacheConcurrentAccess(t *testing.T) {
+	cache := newTestMemoryCache(t, Options{MaxEntries: 64, MaxBytes: 8 << 10, Shards: 4, CleanupInterval: time.Millisecond})
+
+	var wg sync.WaitGroup
+	for worker := 0; worker < 8; worker++ {
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+			var value string
+			for i := 0; i < 2000; i++ {
+				key := "key:" + strconv.Itoa((worker*31+i)%200)
+				tag := "tag:" + strconv.Itoa(i%5)
+				switch i % 10 {
+				case 0:
+					cache.InvalidateTag(tag)
+				case 1:
+					cache.Delete(key)
+				case 2, 3, 4:
+					cache.Set(key, strings.Repeat("v", i%50), time.Duration(i%3+1)*time.Millisecond, tag)
+				default:
+					cache.Get(key, &value)
+				}
+			}
+		}(worker)
+	}
+	wg.Wait()
+
+	stats := cache.Stats()
+	if stats.Entries > 64 || stats.Bytes > 8<<10 {
+		t.Errorf("limits exceeded: %+v", stats)
+	}
+	for i, s := range cache.shards {
+		s.mutex.Lock()
+		var bytes int64
+		for element := s.lru.Front(); element != nil; element = element.Next() {
+			item := element.Value.(*CacheItem)
+			if s.items[item.Key] != element {
+				t.Errorf("shard %d: LRU entry %q is not indexed", i, item.Key)
+			}
+			bytes += item.Size
+		}
+		if len(s.items) != s.lru.Len() || bytes != s.bytes {
+			t.Errorf("shard %d: %d items, %d in LRU, %d bytes counted, %d accounted", i, len(s.items), s.lru.Len(), bytes, s.bytes)
+		}
+		for tag, elements := range s.tags {
+			for element := range elements {
+				if s.items[element.Value.(*CacheItem).Key] != element {
+					t.Errorf("shard %d: tag %q points to a removed entry", i, tag)
+				}
+			}
+		}
+		s.mutex.Unlock()
+	}
+}
@@ -0,0 +1,201 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"sync/atomic"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/sirupsen/logrus"
+)
+
+// Сколько ждать Redis, прежде чем считать обращение к кэшу промахом
+const redisTimeout = 500 * time.Millisecond
+
+// setScript кладет значение и добавляет ключ в множества тегов. Множество тега живет не меньше
+// самого долгого своего ключа, чтобы InvalidateTag не пропустил еще живые записи.
+// KEYS: ключ, ARGV[3] множеств тегов, затем версии ключа и тегов. Если одна из версий больше ARGV[4],
+// ничего не кладется. У Set версий нет
+var setScript = redis.NewScript(`
+local tags = tonumber(ARGV[3])
+for i = tags + 2, #KEYS do
+	local version = redis.call('GET', KEYS[i])
+	if version and tonumber(version) > tonumber(ARGV[4]) then
+		return 0
+	end
+end
+redis.call('SET', KEYS[1], ARGV[1], 'PX', ARGV[2])
+local ttl = tonumber(ARGV[2])
+for i = 2, tags + 1 do
+	redis.call('SADD', KEYS[i], KEYS[1])
+	local current = redis.call('PTTL', KEYS[i])
+	if current < ttl then
+		redis.call('PEXPIRE', KEYS[i], ttl)
+	end
+end
+return 1
+`)
+
+// deleteScript удаляет ключ и запоминает новую версию кэша как версию ключа
+var deleteScript = redis.NewScript(`
+local version = redis.call('INCR', KEYS[3])
+redis.call('SET', KEYS[2], version, 'PX', ARGV[1])
+return redis.call('DEL', KEYS[1])
+`)
+
+// invalidateScript атомарно удаляет ключи тега и само множество и запоминает новую версию кэша как версию тега
+var invalidateScript = redis.NewScript(`
+local version = redis.call('INCR', KEYS[3])
+redis.call('SET', KEYS[2], version, 'PX', ARGV[1])
+local keys = redis.call('SMEMBERS', KEYS[1])
+for i = 1, #keys, 500 do
+	redis.call('DEL', unpack(keys, i, math.min(i + 499, #keys)))
+end
+redis.call('DEL', KEYS[1])
+return #keys
+`)
+
+// RedisCache хранит значения в Redis в виде JSON, поэтому общий для всех реплик.
+// Ошибки Redis не возвращаются: кэш лишь ускоряет чтение, поэтому они логируются и считаются промахом
+type RedisCache struct {
+	client *redis.Client
+	prefix string
+	logger *logrus.Logger
+
+	hits   atomic.Uint64
+	misses atomic.Uint64
+}
+
+func NewRedisCache(client *redis.Client, prefix string, logger *logrus.Logger) *RedisCache {
+	return &RedisCache{
+		client: client,
+		prefix: prefix,
+		logger: logger,
+	}
+}
+
+func (c *RedisCache) key(key string) string {
+	return c.prefix + key
+}
+
+func (c *RedisCache) tagKey(tag string) string {
+	return c.prefix + "tag:" + tag
+}
+
+// markKey - ключ версии сброшенного ключа или тега, versionKey - счетчик версий кэша
+func (c *RedisCache) markKey(mark string) string {
+	return c.prefix + "version:" + mark
+}
+
+func (c *RedisCache) versionKey() string {
+	return c.prefix + "version"
+}
+
+func (c *RedisCache) Get(key string, dest interface{}) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+
+	data, err := c.client.Get(ctx, c.key(key)).Bytes()
+	if err != nil {
+		if err != redis.Nil {
+			c.logger.WithError(err).WithField("key", key).Warn("Redis cache read failed")
+		}
+		c.misses.Add(1)
+		return false
+	}
+
+	if err := json.Unmarshal(data, dest); err != nil {
+		c.logger.WithError(err).WithField("key", key).Warn("Failed to decode cached value")
+		c.misses.Add(1)
+		return false
+	}
+
+	c.hits.Add(1)
+	return true
+}
+
+func (c *RedisCache) Set(key string, value interface{}, ttl time.Duration, tags ...string) {
+	c.set(key, value, ttl, 0, false, tags)
+}
+
+// SetIfUnchanged проверяет версии и кладет значение одним скриптом, поэтому инвалидация с любой реплики
+// не проскочит между проверкой и записью
+func (c *RedisCache) SetIfUnchanged(key string, value interface{}, ttl time.Duration, since uint64, tags ...string) bool {
+	return c.set(key, value, ttl, since, true, tags)
+}
+
+func (c *RedisCache) set(key string, value interface{}, ttl time.Duration, since uint64, checkVersions bool, tags []string) bool {
+	data, err := json.Marshal(value)
+	if err != nil {
+		c.logger.WithError(err).WithField("key", key).Warn("Failed to encode value for cache")
+		return false
+	}
+
+	keys := make([]string, 0, 2*len(tags)+2)
+	keys = append(keys, c.key(key))
+	for _, tag := range tags {
+		keys = append(keys, c.tagKey(tag))
+	}
+	if checkVersions {
+		keys = append(keys, c.markKey(keyMark(key)))
+		for _, tag := range tags {
+			keys = append(keys, c.markKey(tagMark(tag)))
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+
+	stored, err := setScript.Run(ctx, c.client, keys, data, ttl.Milliseconds(), len(tags), since).Int()
+	if err != nil {
+		c.logger.WithError(err).WithField("key", key).Warn("Redis cache write failed")
+		return false
+	}
+	return stored == 1
+}
+
+func (c *RedisCache) Delete(key string) {
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+
+	keys := []string{c.key(key), c.markKey(keyMark(key)), c.versionKey()}
+	if err := deleteScript.Run(ctx, c.client, keys, versionTTL.Milliseconds()).Err(); err != nil {
+		c.logger.WithError(err).WithField("key", key).Warn("Redis cache delete failed")
+	}
+}
+
+func (c *RedisCache) InvalidateTag(tag string) {
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+
+	keys := []string{c.tagKey(tag), c.markKey(tagMark(tag)), c.versionKey()}
+	if err := invalidateScript.Run(ctx, c.client, keys, versionTTL.Milliseconds()).Err(); err != nil {
+		c.logger.WithError(err).WithField("tag", tag).Warn("Redis cache invalidation failed")
+	}
+}
+
+// Version при недоступном Redis возвращает 0: тогда SetIfUnchanged не положит ничего, что сбрасывалось
+func (c *RedisCache) Version() uint64 {
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+
+	version, err := c.client.Get(ctx, c.versionKey()).Uint64()
+	if err != nil && err != redis.Nil {
+		c.logger.WithError(err).Warn("Redis cache version read failed")
+	}
+	return version
+}
+
+func (c *RedisCache) Stats() Stats {
+	return Stats{
+		Hits:   c.hits.Load(),
+		Misses: c.misses.Load(),
+	}
+}
+
+func (c *RedisCache) Stop() {
+	if err := c.client.Close(); err != nil {
+		c.logger.WithError(err).Warn("Failed to close Redis client")
+	}
+}
@@ -0,0 +1,101 @@
+package cache
+
+import (
+	"io"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/sirupsen/logrus"
+)
+
+func newTestRedisCache(t *testing.T) (*RedisCache, *miniredis.Miniredis) {
+	t.Helper()
+	server := miniredis.RunT(t)
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	cache := NewRedisCache(newRedisClient(server.Addr()), "test:", logger)
+	t.Cleanup(cache.Stop)
+	return cache, server
+}
+
+func newRedisClient(addr string) *redis.Client {
+	return redis.NewClient(&redis.Options{Addr: addr})
+}
+
+func TestRedisCacheContract(t *testing.T) {
+	runCacheContract(t, func(t *testing.T) (Cache, func(time.Duration)) {
+		cache, server := newTestRedisCache(t)
+		return cache, server.FastForward
+	})
+}
+
+func TestRedisCacheSetScriptTagTTL(t *testing.T) {
+	cache, server := newTestRedisCache(t)
+
+	cache.Set("a", 1, time.Minute, "people")
+	if ttl := server.TTL("test:a"); ttl != time.Minute {
+		t.Errorf("key TTL = %v, want 1m", ttl)
+	}
+	if ttl := server.TTL("test:tag:people"); ttl != time.Minute {
+		t.Errorf("tag TTL = %v, want 1m", ttl)
+	}
+
+	// Более короткий ключ не сокращает жизнь множества тега, более долгий - продлевает
+	cache.Set("b", 2, 10*time.Second, "people")
+	if ttl := server.TTL("test:tag:people"); ttl != time.Minute {
+		t.Errorf("tag TTL after a shorter key = %v, want 1m", ttl)
+	}
+	cache.Set("c", 3, 2*time.Minute, "people")
+	if ttl := server.TTL("test:tag:people"); ttl != 2*time.Minute {
+		t.Errorf("tag TTL after a longer key = %v, want 2m", ttl)
+	}
+
+	members, err := server.Members("test:tag:people")
+	if err != nil || len(members) != 3 {
+		t.Errorf("tag members = %v, %v, want 3 keys", members, err)
+	}
+}
+
+func TestRedisCacheInvalidateScript(t *testing.T) {
+	cache, server := newTestRedisCache(t)
+
+	// Больше одной порции DEL по 500 ключей
+	for i := 0; i < 1200; i++ {
+		cache.Set("person:"+strconv.Itoa(i), i, time.Minute, "people")
+	}
+	cache.Set("stats", 1, time.Minute, "stats")
+
+	cache.InvalidateTag("people")
+
+	if server.Exists("test:tag:people") {
+		t.Error("tag set survived invalidation")
+	}
+	for i := 0; i < 1200; i++ {
+		if server.Exists("test:person:" + strconv.Itoa(i)) {
+			t.Fatalf("person:%d survived invalidation", i)
+		}
+	}
+	if !server.Exists("test:stats") || !server.Exists("test:tag:stats") {
+		t.Error("unrelated tag was invalidated")
+	}
+}
+
+func TestRedisCacheFailuresAreMisses(t *testing.T) {
+	cache, server := newTestRedisCache(t)
+	cache.Set("key", 1, time.Minute)
+	server.Close()
+
+	var got int
+	if cache.Get("key", &got) {
+		t.Error("value was returned while Redis is down")
+	}
+	cache.Set("key", 2, time.Minute)
+	cache.InvalidateTag("tag")
+	if stats := cache.Stats(); stats.Misses != 1 {
+		t.Errorf("stats = %+v, want 1 miss", stats)
+	}
+}
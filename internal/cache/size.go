@@ -0,0 +1,60 @@
+package cache
+
+import "reflect"
+
+// Глубже этого уровня вложенности значения не обходятся, чтобы не зациклиться на циклических ссылках
+const maxSizeDepth = 8
+
+// estimateSize примерно оценивает, сколько байт занимает значение вместе со всем, на что оно ссылается
+func estimateSize(value interface{}) int64 {
+	if value == nil {
+		return 0
+	}
+	return sizeOf(reflect.ValueOf(value), 0)
+}
+
+func sizeOf(v reflect.Value, depth int) int64 {
+	if depth > maxSizeDepth {
+		return 0
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			return 8
+		}
+		return 8 + sizeOf(v.Elem(), depth+1)
+	case reflect.String:
+		return int64(v.Type().Size()) + int64(v.Len())
+	case reflect.Slice:
+		if v.IsNil() {
+			return int64(v.Type().Size())
+		}
+		size := int64(v.Type().Size())
+		for i := 0; i < v.Len(); i++ {
+			size += sizeOf(v.Index(i), depth+1)
+		}
+		return size
+	case reflect.Array:
+		var size int64
+		for i := 0; i < v.Len(); i++ {
+			size += sizeOf(v.Index(i), depth+1)
+		}
+		return size
+	case reflect.Struct:
+		var size int64
+		for i := 0; i < v.NumField(); i++ {
+			size += sizeOf(v.Field(i), depth+1)
+		}
+		return size
+	case reflect.Map:
+		size := int64(v.Type().Size()) + 48
+		iter := v.MapRange()
+		for iter.Next() {
+			size += sizeOf(iter.Key(), depth+1) + sizeOf(iter.Value(), depth+1)
+		}
+		return size
+	default:
+		return int64(v.Type().Size())
+	}
+}
@@ -5,11 +5,22 @@ import (
 	"log"
 	"os"
 	"strconv"
+	"strings"
+	"time"
 )
 
 type Config struct {
 	Database    DatabaseConfig
 	Server      ServerConfig
+	Purge       PurgeConfig
+	Analytics   AnalyticsConfig
+	Import      ImportConfig
+	Cache       CacheConfig
+	Auth        AuthConfig
+	RateLimit   RateLimitConfig
+	Idempotency IdempotencyConfig
+	Metrics     MetricsConfig
+	Tracing     TracingConfig
 	Environment string
 }
 
@@ -23,8 +34,104 @@ type DatabaseConfig struct {
 	URL      string // Добавляем поддержку DATABASE_URL
 }
 
+// ServerConfig: TrustedProxies - адреса или подсети прокси, которым доверяется X-Forwarded-For.
+// Пустой список - адрес клиента берется из соединения, заголовок игнорируется
 type ServerConfig struct {
-	Port string
+	Port           string
+	TrustedProxies []string
+}
+
+// PurgeConfig задает, сколько хранить мягко удаленных людей и как часто их чистить
+type PurgeConfig struct {
+	Retention time.Duration
+	Interval  time.Duration
+}
+
+// AnalyticsConfig задает, как часто пересчитывать метрики графа дружбы
+type AnalyticsConfig struct {
+	Interval time.Duration
+}
+
+// ImportConfig задает, сколько хранить отчеты об отклоненных строках импорта и как часто удалять старые
+type ImportConfig struct {
+	ReportRetention time.Duration
+	CleanupInterval time.Duration
+}
+
+// CacheConfig выбирает реализацию кэша: memory - в памяти процесса, redis - общий для всех реплик
+type CacheConfig struct {
+	Backend     string
+	MaxEntries  int
+	MaxBytes    int64
+	Shards      int
+	RedisURL    string
+	RedisPrefix string
+	// Политики по семействам ключей (person, people, stats)
+	Policies map[string]CachePolicyConfig
+}
+
+// CachePolicyConfig - время жизни записей семейства ключей кэша, см. cache.Policy
+type CachePolicyConfig struct {
+	TTL          time.Duration
+	StaleTTL     time.Duration
+	EarlyRefresh float64
+}
+
+// AuthConfig - проверка вызывающих. JWT принимаются, если задан секрет HMAC или файл JWKS.
+// BootstrapKey - ключ из окружения для создания первых ключей в пустой базе.
+// SelfService разрешает вызывающему, связанному с человеком, менять его запись без роли editor
+type AuthConfig struct {
+	Enabled      bool
+	SelfService  bool
+	BootstrapKey string
+	JWTSecret    string
+	JWKSFile     string
+	JWTIssuer    string
+	JWTAudience  string
+	JWTLeeway    time.Duration
+}
+
+// RateLimitConfig - лимиты запросов на вызывающего по классам маршрутов: default - чтение,
+// write - изменения, enrich - дорогие записи: создание людей, пачки и импорт.
+// IP - общий лимит на адрес до аутентификации, он должен быть не меньше остальных
+type RateLimitConfig struct {
+	Enabled bool
+	Default RateLimitRule
+	Write   RateLimitRule
+	Enrich  RateLimitRule
+	IP      RateLimitRule
+}
+
+// RateLimitRule - запросов в минуту и сколько можно сделать подряд сверх равномерного темпа
+type RateLimitRule struct {
+	PerMinute int
+	Burst     int
+}
+
+// IdempotencyConfig - сколько хранить ответы на запросы с Idempotency-Key, через сколько снимать блокировку
+// запроса, который так и не ответил, сколько повтору ждать выполняющийся запрос и как часто чистить истекшие ключи
+type IdempotencyConfig struct {
+	TTL             time.Duration
+	LockTimeout     time.Duration
+	Wait            time.Duration
+	CleanupInterval time.Duration
+}
+
+// MetricsConfig включает /metrics в формате Prometheus
+type MetricsConfig struct {
+	Enabled bool
+}
+
+// TracingConfig - трассировка OpenTelemetry. Exporter: otlp - на OTLP/HTTP коллектор Endpoint,
+// stdout - в стандартный вывод или File для локальной отладки. SampleRatio - доля записываемых трасс от 0 до 1
+type TracingConfig struct {
+	Enabled     bool
+	ServiceName string
+	Exporter    string
+	Endpoint    string
+	Insecure    bool
+	File        string
+	SampleRatio float64
 }
 
 func Load() *Config {
@@ -62,7 +169,67 @@ func Load() *Config {
 	return &Config{
 		Database: dbConfig,
 		Server: ServerConfig{
-			Port: getEnv("PORT", getEnv("SERVER_PORT", "8080")),
+			Port:           getEnv("PORT", getEnv("SERVER_PORT", "8080")),
+			TrustedProxies: getList("TRUSTED_PROXIES"),
+		},
+		Purge: PurgeConfig{
+			Retention: getDuration("SOFT_DELETE_RETENTION", 30*24*time.Hour),
+			Interval:  getDuration("PURGE_INTERVAL", time.Hour),
+		},
+		Analytics: AnalyticsConfig{
+			Interval: getDuration("GRAPH_ANALYTICS_INTERVAL", 15*time.Minute),
+		},
+		Import: ImportConfig{
+			ReportRetention: getDuration("IMPORT_REPORT_RETENTION", 7*24*time.Hour),
+			CleanupInterval: getDuration("IMPORT_REPORT_CLEANUP_INTERVAL", time.Hour),
+		},
+		Cache: CacheConfig{
+			Backend:     getEnv("CACHE_BACKEND", "memory"),
+			MaxEntries:  getInt("CACHE_MAX_ENTRIES", 10000),
+			MaxBytes:    int64(getInt("CACHE_MAX_BYTES", 256<<20)),
+			Shards:      getInt("CACHE_SHARDS", 16),
+			RedisURL:    getEnv("REDIS_URL", "redis://localhost:6379/0"),
+			RedisPrefix: getEnv("REDIS_PREFIX", "peoplecrud:"),
+			Policies: map[string]CachePolicyConfig{
+				"person": getCachePolicy("PERSON", 5*time.Minute, time.Minute),
+				"people": getCachePolicy("PEOPLE", time.Minute, 30*time.Second),
+				"stats":  getCachePolicy("STATS", 5*time.Minute, 5*time.Minute),
+			},
+		},
+		Auth: AuthConfig{
+			Enabled:      getBool("AUTH_ENABLED", true),
+			SelfService:  getBool("AUTH_SELF_SERVICE", false),
+			BootstrapKey: os.Getenv("AUTH_BOOTSTRAP_KEY"),
+			JWTSecret:    os.Getenv("JWT_SECRET"),
+			JWKSFile:     os.Getenv("JWT_JWKS_FILE"),
+			JWTIssuer:    os.Getenv("JWT_ISSUER"),
+			JWTAudience:  os.Getenv("JWT_AUDIENCE"),
+			JWTLeeway:    getDuration("JWT_LEEWAY", 30*time.Second),
+		},
+		RateLimit: RateLimitConfig{
+			Enabled: getBool("RATE_LIMIT_ENABLED", true),
+			Default: getRateLimitRule("DEFAULT", 600, 100),
+			Write:   getRateLimitRule("WRITE", 120, 20),
+			Enrich:  getRateLimitRule("ENRICH", 30, 5),
+			IP:      getRateLimitRule("IP", 1200, 200),
+		},
+		Idempotency: IdempotencyConfig{
+			TTL:             getDuration("IDEMPOTENCY_TTL", 24*time.Hour),
+			LockTimeout:     getDuration("IDEMPOTENCY_LOCK_TIMEOUT", time.Minute),
+			Wait:            getDuration("IDEMPOTENCY_WAIT", 5*time.Second),
+			CleanupInterval: getDuration("IDEMPOTENCY_CLEANUP_INTERVAL", time.Hour),
+		},
+		Metrics: MetricsConfig{
+			Enabled: getBool("METRICS_ENABLED", true),
+		},
+		Tracing: TracingConfig{
+			Enabled:     getBool("TRACING_ENABLED", false),
+			ServiceName: getEnv("TRACING_SERVICE_NAME", "people-api"),
+			Exporter:    getEnv("TRACING_EXPORTER", "otlp"),
+			Endpoint:    os.Getenv("TRACING_OTLP_ENDPOINT"),
+			Insecure:    getBool("TRACING_OTLP_INSECURE", true),
+			File:        os.Getenv("TRACING_FILE"),
+			SampleRatio: getFloat("TRACING_SAMPLE_RATIO", 1),
 		},
 		Environment: getEnv("ENVIRONMENT", "development"),
 	}
@@ -90,3 +257,85 @@ func getEnv(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+func getDuration(key string, defaultValue time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		log.Printf("Invalid %s, using default %s. Error: %v", key, defaultValue, err)
+		return defaultValue
+	}
+	return duration
+}
+
+func getInt(key string, defaultValue int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	number, err := strconv.Atoi(value)
+	if err != nil {
+		log.Printf("Invalid %s, using default %d. Error: %v", key, defaultValue, err)
+		return defaultValue
+	}
+	return number
+}
+
+func getBool(key string, defaultValue bool) bool {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	flag, err := strconv.ParseBool(value)
+	if err != nil {
+		log.Printf("Invalid %s, using default %t. Error: %v", key, defaultValue, err)
+		return defaultValue
+	}
+	return flag
+}
+
+func getFloat(key string, defaultValue float64) float64 {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	number, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		log.Printf("Invalid %s, using default %g. Error: %v", key, defaultValue, err)
+		return defaultValue
+	}
+	return number
+}
+
+// getList читает список через запятую, пустые элементы отбрасываются
+func getList(key string) []string {
+	var items []string
+	for _, item := range strings.Split(os.Getenv(key), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// getRateLimitRule читает RATE_LIMIT_<CLASS>_PER_MINUTE и RATE_LIMIT_<CLASS>_BURST
+func getRateLimitRule(class string, perMinute, burst int) RateLimitRule {
+	prefix := "RATE_LIMIT_" + class + "_"
+	return RateLimitRule{
+		PerMinute: getInt(prefix+"PER_MINUTE", perMinute),
+		Burst:     getInt(prefix+"BURST", burst),
+	}
+}
+
+// getCachePolicy читает CACHE_<FAMILY>_TTL, CACHE_<FAMILY>_STALE_TTL и CACHE_<FAMILY>_EARLY_REFRESH
+func getCachePolicy(family string, ttl, staleTTL time.Duration) CachePolicyConfig {
+	prefix := "CACHE_" + family + "_"
+	return CachePolicyConfig{
+		TTL:          getDuration(prefix+"TTL", ttl),
+		StaleTTL:     getDuration(prefix+"STALE_TTL", staleTTL),
+		EarlyRefresh: getFloat(prefix+"EARLY_REFRESH", 1),
+	}
+}
@@ -0,0 +1,136 @@
+package exporter
+
+import (
+	"PeopleCRUD/internal/models"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	FormatCSV    = "csv"
+	FormatNDJSON = "ndjson"
+	FormatXLSX   = "xlsx"
+
+	// Разделитель списков совпадает с форматом импорта
+	listSeparator = ";"
+)
+
+// Writer пишет строки выгрузки по одной. Close дописывает хвост формата, но не закрывает исходный io.Writer.
+type Writer interface {
+	Write(row *models.PersonExport) error
+	Close() error
+}
+
+var columns = []string{
+	"id", "first_name", "last_name", "middle_name", "age", "gender", "nationality",
+	"emails", "friend_ids", "created_at", "updated_at",
+}
+
+func NewWriter(format string, w io.Writer) (Writer, error) {
+	switch format {
+	case FormatCSV:
+		return newCSVWriter(w)
+	case FormatNDJSON:
+		return &ndjsonWriter{encoder: json.NewEncoder(w)}, nil
+	case FormatXLSX:
+		return newXLSXWriter(w)
+	default:
+		return nil, fmt.Errorf("unsupported export format: %s", format)
+	}
+}
+
+func Supported(format string) bool {
+	return format == FormatCSV || format == FormatNDJSON || format == FormatXLSX
+}
+
+func ContentType(format string) string {
+	switch format {
+	case FormatCSV:
+		return "text/csv; charset=utf-8"
+	case FormatNDJSON:
+		return "application/x-ndjson"
+	case FormatXLSX:
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	}
+	return "application/octet-stream"
+}
+
+type csvWriter struct {
+	writer *csv.Writer
+}
+
+func newCSVWriter(w io.Writer) (*csvWriter, error) {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(columns); err != nil {
+		return nil, err
+	}
+	return &csvWriter{writer: writer}, nil
+}
+
+func (w *csvWriter) Write(row *models.PersonExport) error {
+	return w.writer.Write(rowValues(row))
+}
+
+func (w *csvWriter) Close() error {
+	w.writer.Flush()
+	return w.writer.Error()
+}
+
+type ndjsonWriter struct {
+	encoder *json.Encoder
+}
+
+func (w *ndjsonWriter) Write(row *models.PersonExport) error {
+	if row.Emails == nil {
+		row.Emails = []string{}
+	}
+	if row.FriendIDs == nil {
+		row.FriendIDs = []int64{}
+	}
+	return w.encoder.Encode(row)
+}
+
+func (w *ndjsonWriter) Close() error {
+	return nil
+}
+
+// rowValues раскладывает строку по колонкам columns в текстовом виде
+func rowValues(row *models.PersonExport) []string {
+	friendIDs := make([]string, len(row.FriendIDs))
+	for i, id := range row.FriendIDs {
+		friendIDs[i] = strconv.FormatInt(id, 10)
+	}
+
+	return []string{
+		strconv.Itoa(row.ID),
+		row.FirstName,
+		row.LastName,
+		stringValue(row.MiddleName),
+		intValue(row.Age),
+		stringValue(row.Gender),
+		stringValue(row.Nationality),
+		strings.Join(row.Emails, listSeparator),
+		strings.Join(friendIDs, listSeparator),
+		row.CreatedAt.Format(time.RFC3339),
+		row.UpdatedAt.Format(time.RFC3339),
+	}
+}
+
+func stringValue(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return *value
+}
+
+func intValue(value *int) string {
+	if value == nil {
+		return ""
+	}
+	return strconv.Itoa(*value)
+}
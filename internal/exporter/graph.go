@@ -0,0 +1,251 @@
+package exporter
+
+import (
+	"PeopleCRUD/internal/models"
+	"bufio"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+const (
+	GraphFormatGraphML   = "graphml"
+	GraphFormatDOT       = "dot"
+	GraphFormatJSONGraph = "jsongraph"
+)
+
+// GraphWriter пишет граф потоково: сначала все вершины, затем все ребра.
+// Close дописывает хвост формата, но не закрывает исходный io.Writer.
+type GraphWriter interface {
+	WriteNode(person *models.Person) error
+	WriteEdge(edge *models.GraphEdge) error
+	Close() error
+}
+
+func NewGraphWriter(format string, w io.Writer) (GraphWriter, error) {
+	buf := bufio.NewWriter(w)
+	switch format {
+	case GraphFormatGraphML:
+		return newGraphMLWriter(buf)
+	case GraphFormatDOT:
+		return newDOTWriter(buf)
+	case GraphFormatJSONGraph:
+		return newJSONGraphWriter(buf)
+	default:
+		return nil, fmt.Errorf("unsupported graph format: %s", format)
+	}
+}
+
+func GraphSupported(format string) bool {
+	return format == GraphFormatGraphML || format == GraphFormatDOT || format == GraphFormatJSONGraph
+}
+
+func GraphContentType(format string) string {
+	switch format {
+	case GraphFormatGraphML:
+		return "application/graphml+xml; charset=utf-8"
+	case GraphFormatDOT:
+		return "text/vnd.graphviz; charset=utf-8"
+	case GraphFormatJSONGraph:
+		return "application/vnd.jgf+json"
+	}
+	return "application/octet-stream"
+}
+
+// GraphFileExtension - расширение файла, под которым формат открывают Gephi и Graphviz
+func GraphFileExtension(format string) string {
+	if format == GraphFormatJSONGraph {
+		return "json"
+	}
+	return format
+}
+
+func personLabel(person *models.Person) string {
+	return strings.TrimSpace(person.FirstName + " " + person.LastName)
+}
+
+// personAttributes - демографические атрибуты вершины, пустые значения пропускаются
+func personAttributes(person *models.Person) [][2]string {
+	attrs := [][2]string{
+		{"first_name", person.FirstName},
+		{"last_name", person.LastName},
+	}
+	if person.MiddleName != nil {
+		attrs = append(attrs, [2]string{"middle_name", *person.MiddleName})
+	}
+	if person.Age != nil {
+		attrs = append(attrs, [2]string{"age", strconv.Itoa(*person.Age)})
+	}
+	if person.Gender != nil {
+		attrs = append(attrs, [2]string{"gender", *person.Gender})
+	}
+	if person.Nationality != nil {
+		attrs = append(attrs, [2]string{"nationality", *person.Nationality})
+	}
+	return attrs
+}
+
+type graphMLWriter struct {
+	buf *bufio.Writer
+}
+
+func newGraphMLWriter(buf *bufio.Writer) (*graphMLWriter, error) {
+	_, err := buf.WriteString(xml.Header + `<graphml xmlns="http://graphml.graphdrawing.org/xmlns">
+  <key id="label" for="node" attr.name="label" attr.type="string"/>
+  <key id="first_name" for="node" attr.name="first_name" attr.type="string"/>
+  <key id="last_name" for="node" attr.name="last_name" attr.type="string"/>
+  <key id="middle_name" for="node" attr.name="middle_name" attr.type="string"/>
+  <key id="age" for="node" attr.name="age" attr.type="int"/>
+  <key id="gender" for="node" attr.name="gender" attr.type="string"/>
+  <key id="nationality" for="node" attr.name="nationality" attr.type="string"/>
+  <key id="weight" for="edge" attr.name="weight" attr.type="double"/>
+  <graph id="people" edgedefault="undirected">
+`)
+	if err != nil {
+		return nil, err
+	}
+	return &graphMLWriter{buf: buf}, nil
+}
+
+func (w *graphMLWriter) WriteNode(person *models.Person) error {
+	fmt.Fprintf(w.buf, `    <node id="n%d">`+"\n", person.ID)
+	w.writeData("label", personLabel(person))
+	for _, attr := range personAttributes(person) {
+		w.writeData(attr[0], attr[1])
+	}
+	_, err := w.buf.WriteString("    </node>\n")
+	return err
+}
+
+func (w *graphMLWriter) writeData(key, value string) {
+	fmt.Fprintf(w.buf, `      <data key="%s">`, key)
+	xml.EscapeText(w.buf, []byte(value))
+	w.buf.WriteString("</data>\n")
+}
+
+func (w *graphMLWriter) WriteEdge(edge *models.GraphEdge) error {
+	_, err := fmt.Fprintf(w.buf, `    <edge source="n%d" target="n%d"><data key="weight">%s</data></edge>`+"\n",
+		edge.Source, edge.Target, strconv.FormatFloat(edge.Weight, 'g', -1, 64))
+	return err
+}
+
+func (w *graphMLWriter) Close() error {
+	if _, err := w.buf.WriteString("  </graph>\n</graphml>\n"); err != nil {
+		return err
+	}
+	return w.buf.Flush()
+}
+
+var dotEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+type dotWriter struct {
+	buf *bufio.Writer
+}
+
+func newDOTWriter(buf *bufio.Writer) (*dotWriter, error) {
+	if _, err := buf.WriteString("graph people {\n"); err != nil {
+		return nil, err
+	}
+	return &dotWriter{buf: buf}, nil
+}
+
+func (w *dotWriter) WriteNode(person *models.Person) error {
+	fmt.Fprintf(w.buf, `  %d [label="%s"`, person.ID, dotEscaper.Replace(personLabel(person)))
+	for _, attr := range personAttributes(person) {
+		fmt.Fprintf(w.buf, `, %s="%s"`, attr[0], dotEscaper.Replace(attr[1]))
+	}
+	_, err := w.buf.WriteString("];\n")
+	return err
+}
+
+func (w *dotWriter) WriteEdge(edge *models.GraphEdge) error {
+	_, err := fmt.Fprintf(w.buf, "  %d -- %d [weight=%s];\n",
+		edge.Source, edge.Target, strconv.FormatFloat(edge.Weight, 'g', -1, 64))
+	return err
+}
+
+func (w *dotWriter) Close() error {
+	if _, err := w.buf.WriteString("}\n"); err != nil {
+		return err
+	}
+	return w.buf.Flush()
+}
+
+// jsonGraphWriter пишет JSON Graph Format v2: вершины - объект по id, ребра - массив
+type jsonGraphWriter struct {
+	buf       *bufio.Writer
+	nodes     int
+	edges     int
+	nodesDone bool
+}
+
+func newJSONGraphWriter(buf *bufio.Writer) (*jsonGraphWriter, error) {
+	if _, err := buf.WriteString(`{"graph":{"directed":false,"nodes":{`); err != nil {
+		return nil, err
+	}
+	return &jsonGraphWriter{buf: buf}, nil
+}
+
+func (w *jsonGraphWriter) WriteNode(person *models.Person) error {
+	metadata := map[string]interface{}{
+		"first_name": person.FirstName,
+		"last_name":  person.LastName,
+	}
+	if person.MiddleName != nil {
+		metadata["middle_name"] = *person.MiddleName
+	}
+	if person.Age != nil {
+		metadata["age"] = *person.Age
+	}
+	if person.Gender != nil {
+		metadata["gender"] = *person.Gender
+	}
+	if person.Nationality != nil {
+		metadata["nationality"] = *person.Nationality
+	}
+
+	node, err := json.Marshal(map[string]interface{}{
+		"label":    personLabel(person),
+		"metadata": metadata,
+	})
+	if err != nil {
+		return err
+	}
+
+	if w.nodes > 0 {
+		w.buf.WriteByte(',')
+	}
+	w.nodes++
+	fmt.Fprintf(w.buf, `"%d":`, person.ID)
+	_, err = w.buf.Write(node)
+	return err
+}
+
+func (w *jsonGraphWriter) finishNodes() {
+	if !w.nodesDone {
+		w.buf.WriteString(`},"edges":[`)
+		w.nodesDone = true
+	}
+}
+
+func (w *jsonGraphWriter) WriteEdge(edge *models.GraphEdge) error {
+	w.finishNodes()
+	if w.edges > 0 {
+		w.buf.WriteByte(',')
+	}
+	w.edges++
+	_, err := fmt.Fprintf(w.buf, `{"source":"%d","target":"%d","metadata":{"weight":%s}}`,
+		edge.Source, edge.Target, strconv.FormatFloat(edge.Weight, 'g', -1, 64))
+	return err
+}
+
+func (w *jsonGraphWriter) Close() error {
+	w.finishNodes()
+	if _, err := w.buf.WriteString("]}}\n"); err != nil {
+		return err
+	}
+	return w.buf.Flush()
+}
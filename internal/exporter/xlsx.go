@@ -0,0 +1,140 @@
+package exporter
+
+import (
+	"PeopleCRUD/internal/models"
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Лимит строк на лист в Excel, включая заголовок. При переполнении начинается новый лист.
+const xlsxMaxRows = 1048576
+
+// xlsxWriter пишет минимальный SpreadsheetML-пакет потоково: листы идут в zip по мере записи,
+// а workbook.xml и [Content_Types].xml, которым нужно знать число листов, дописываются в Close.
+type xlsxWriter struct {
+	zip    *zip.Writer
+	sheet  io.Writer
+	sheets int
+	rows   int
+}
+
+func newXLSXWriter(w io.Writer) (*xlsxWriter, error) {
+	writer := &xlsxWriter{zip: zip.NewWriter(w)}
+	if err := writer.startSheet(); err != nil {
+		return nil, err
+	}
+	return writer, nil
+}
+
+func (w *xlsxWriter) Write(row *models.PersonExport) error {
+	if w.rows >= xlsxMaxRows {
+		if err := w.endSheet(); err != nil {
+			return err
+		}
+		if err := w.startSheet(); err != nil {
+			return err
+		}
+	}
+
+	values := rowValues(row)
+	numeric := map[int]bool{0: true, 4: values[4] != ""}
+	return w.writeRow(values, numeric)
+}
+
+func (w *xlsxWriter) Close() error {
+	if err := w.endSheet(); err != nil {
+		return err
+	}
+
+	var sheets, rels, overrides strings.Builder
+	for i := 1; i <= w.sheets; i++ {
+		fmt.Fprintf(&sheets, `<sheet name="people%s" sheetId="%d" r:id="rId%d"/>`, sheetSuffix(i), i, i)
+		fmt.Fprintf(&rels, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet%d.xml"/>`, i, i)
+		fmt.Fprintf(&overrides, `<Override PartName="/xl/worksheets/sheet%d.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>`, i)
+	}
+
+	files := []struct{ name, body string }{
+		{"xl/workbook.xml", xml.Header + `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets>` + sheets.String() + `</sheets></workbook>`},
+		{"xl/_rels/workbook.xml.rels", xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` + rels.String() + `</Relationships>`},
+		{"_rels/.rels", xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/></Relationships>`},
+		{"[Content_Types].xml", xml.Header + `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"><Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/><Default Extension="xml" ContentType="application/xml"/><Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` + overrides.String() + `</Types>`},
+	}
+	for _, file := range files {
+		f, err := w.zip.Create(file.name)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(f, file.body); err != nil {
+			return err
+		}
+	}
+
+	return w.zip.Close()
+}
+
+func (w *xlsxWriter) startSheet() error {
+	w.sheets++
+	sheet, err := w.zip.Create(fmt.Sprintf("xl/worksheets/sheet%d.xml", w.sheets))
+	if err != nil {
+		return err
+	}
+	w.sheet = sheet
+	w.rows = 0
+
+	if _, err := io.WriteString(w.sheet, xml.Header+`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`); err != nil {
+		return err
+	}
+	return w.writeRow(columns, nil)
+}
+
+func (w *xlsxWriter) endSheet() error {
+	_, err := io.WriteString(w.sheet, `</sheetData></worksheet>`)
+	return err
+}
+
+func (w *xlsxWriter) writeRow(values []string, numeric map[int]bool) error {
+	w.rows++
+	if _, err := fmt.Fprintf(w.sheet, `<row r="%d">`, w.rows); err != nil {
+		return err
+	}
+	for i, value := range values {
+		var err error
+		switch {
+		case value == "":
+			continue
+		case numeric[i]:
+			_, err = fmt.Fprintf(w.sheet, `<c r="%s%d"><v>%s</v></c>`, columnName(i), w.rows, value)
+		default:
+			if _, err = fmt.Fprintf(w.sheet, `<c r="%s%d" t="inlineStr"><is><t>`, columnName(i), w.rows); err == nil {
+				if err = xml.EscapeText(w.sheet, []byte(value)); err == nil {
+					_, err = io.WriteString(w.sheet, `</t></is></c>`)
+				}
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+	_, err := io.WriteString(w.sheet, `</row>`)
+	return err
+}
+
+// columnName переводит индекс колонки в буквенное обозначение Excel: 0 -> A, 26 -> AA
+func columnName(index int) string {
+	name := ""
+	for index >= 0 {
+		name = string(rune('A'+index%26)) + name
+		index = index/26 - 1
+	}
+	return name
+}
+
+func sheetSuffix(i int) string {
+	if i == 1 {
+		return ""
+	}
+	return fmt.Sprintf("_%d", i)
+}
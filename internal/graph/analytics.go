@@ -0,0 +1,266 @@
+package graph
+
+import (
+	"PeopleCRUD/internal/models"
+	"sort"
+	"time"
+)
+
+// MaxPivots - сколько вершин-источников использовать для betweenness. На больших графах точный
+// алгоритм Брандеса (O(V*E)) слишком дорог, поэтому берутся равномерно разнесенные источники,
+// а результат масштабируется на V/pivots
+const MaxPivots = 500
+
+// Graph - неориентированный граф с вершинами, пронумерованными по порядку добавления
+type Graph struct {
+	people []models.Person
+	index  map[int]int
+	adj    [][]int
+	edges  int
+}
+
+func New() *Graph {
+	return &Graph{index: map[int]int{}}
+}
+
+func (g *Graph) AddNode(person *models.Person) {
+	if _, ok := g.index[person.ID]; ok {
+		return
+	}
+	g.index[person.ID] = len(g.people)
+	g.people = append(g.people, *person)
+	g.adj = append(g.adj, nil)
+}
+
+// AddEdge добавляет ребро между уже добавленными вершинами, остальные ребра пропускаются
+func (g *Graph) AddEdge(edge *models.GraphEdge) {
+	a, okA := g.index[edge.Source]
+	b, okB := g.index[edge.Target]
+	if !okA || !okB || a == b {
+		return
+	}
+	g.adj[a] = append(g.adj[a], b)
+	g.adj[b] = append(g.adj[b], a)
+	g.edges++
+}
+
+// Analyze считает метрики графа, списки самых связанных и самых "посреднических" людей обрезаются до top
+func (g *Graph) Analyze(top int) *models.GraphAnalytics {
+	n := len(g.people)
+	result := &models.GraphAnalytics{
+		ComputedAt:         time.Now(),
+		Nodes:              n,
+		Edges:              g.edges,
+		ComponentSizes:     []models.SizeCount{},
+		DegreeDistribution: []models.SizeCount{},
+		MostConnected:      []models.CentralPerson{},
+		MostBetween:        []models.CentralPerson{},
+	}
+	if n == 0 {
+		return result
+	}
+
+	result.ComponentSizes, result.Components, result.LargestComponent = g.components()
+	result.DegreeDistribution = g.degreeDistribution()
+	result.AverageDegree = float64(2*g.edges) / float64(n)
+
+	clustering, average, transitivity := g.clustering()
+	result.AverageClustering = average
+	result.Transitivity = transitivity
+
+	betweenness, pivots := g.betweenness()
+	result.BetweennessPivots = pivots
+
+	central := func(v int) models.CentralPerson {
+		return models.CentralPerson{
+			Person:      g.people[v],
+			Degree:      len(g.adj[v]),
+			Clustering:  clustering[v],
+			Betweenness: betweenness[v],
+		}
+	}
+
+	for _, v := range g.topBy(top, func(v int) float64 { return float64(len(g.adj[v])) }) {
+		result.MostConnected = append(result.MostConnected, central(v))
+	}
+	for _, v := range g.topBy(top, func(v int) float64 { return betweenness[v] }) {
+		result.MostBetween = append(result.MostBetween, central(v))
+	}
+
+	return result
+}
+
+// components возвращает распределение размеров компонент связности (от больших к малым), их число и размер наибольшей
+func (g *Graph) components() ([]models.SizeCount, int, int) {
+	n := len(g.people)
+	seen := make([]bool, n)
+	counts := map[int]int{}
+	total, largest := 0, 0
+	queue := make([]int, 0, n)
+
+	for start := 0; start < n; start++ {
+		if seen[start] {
+			continue
+		}
+		seen[start] = true
+		queue = append(queue[:0], start)
+		for i := 0; i < len(queue); i++ {
+			for _, u := range g.adj[queue[i]] {
+				if !seen[u] {
+					seen[u] = true
+					queue = append(queue, u)
+				}
+			}
+		}
+		counts[len(queue)]++
+		total++
+		largest = max(largest, len(queue))
+	}
+
+	sizes := sortedCounts(counts)
+	sort.Slice(sizes, func(i, j int) bool { return sizes[i].Size > sizes[j].Size })
+	return sizes, total, largest
+}
+
+func (g *Graph) degreeDistribution() []models.SizeCount {
+	counts := map[int]int{}
+	for _, neighbors := range g.adj {
+		counts[len(neighbors)]++
+	}
+	return sortedCounts(counts)
+}
+
+// clustering возвращает локальный коэффициент кластеризации каждой вершины, средний по всем вершинам
+// (вершины степени меньше 2 считаются с нулем) и глобальную транзитивность
+func (g *Graph) clustering() ([]float64, float64, float64) {
+	n := len(g.people)
+	local := make([]float64, n)
+	mark := make([]int, n)
+	for i := range mark {
+		mark[i] = -1
+	}
+
+	var sum, closed, triples float64
+	for v := 0; v < n; v++ {
+		k := len(g.adj[v])
+		if k < 2 {
+			continue
+		}
+		for _, u := range g.adj[v] {
+			mark[u] = v
+		}
+
+		// Каждое ребро между соседями v встречается дважды
+		links := 0
+		for _, u := range g.adj[v] {
+			for _, w := range g.adj[u] {
+				if mark[w] == v {
+					links++
+				}
+			}
+		}
+		links /= 2
+
+		possible := float64(k*(k-1)) / 2
+		local[v] = float64(links) / possible
+		sum += local[v]
+		closed += float64(links)
+		triples += possible
+	}
+
+	transitivity := 0.0
+	if triples > 0 {
+		transitivity = closed / triples
+	}
+	return local, sum / float64(n), transitivity
+}
+
+// betweenness считает центральность по посредничеству алгоритмом Брандеса и возвращает число использованных источников
+func (g *Graph) betweenness() ([]float64, int) {
+	n := len(g.people)
+	result := make([]float64, n)
+
+	step := 1
+	if n > MaxPivots {
+		step = (n + MaxPivots - 1) / MaxPivots
+	}
+
+	sigma := make([]float64, n)
+	dist := make([]int, n)
+	delta := make([]float64, n)
+	preds := make([][]int, n)
+	stack := make([]int, 0, n)
+	queue := make([]int, 0, n)
+
+	pivots := 0
+	for s := 0; s < n; s += step {
+		pivots++
+		for i := 0; i < n; i++ {
+			sigma[i], dist[i], delta[i] = 0, -1, 0
+			preds[i] = preds[i][:0]
+		}
+		sigma[s], dist[s] = 1, 0
+		stack = stack[:0]
+		queue = append(queue[:0], s)
+
+		for i := 0; i < len(queue); i++ {
+			v := queue[i]
+			stack = append(stack, v)
+			for _, w := range g.adj[v] {
+				if dist[w] < 0 {
+					dist[w] = dist[v] + 1
+					queue = append(queue, w)
+				}
+				if dist[w] == dist[v]+1 {
+					sigma[w] += sigma[v]
+					preds[w] = append(preds[w], v)
+				}
+			}
+		}
+
+		for i := len(stack) - 1; i >= 0; i-- {
+			w := stack[i]
+			for _, v := range preds[w] {
+				delta[v] += sigma[v] / sigma[w] * (1 + delta[w])
+			}
+			if w != s {
+				result[w] += delta[w]
+			}
+		}
+	}
+
+	// Граф неориентированный: каждая пара учтена с обеих сторон
+	scale := float64(n) / float64(pivots) / 2
+	for i := range result {
+		result[i] *= scale
+	}
+	return result, pivots
+}
+
+// topBy возвращает до top вершин с наибольшим score, при равенстве - с меньшим id
+func (g *Graph) topBy(top int, score func(v int) float64) []int {
+	order := make([]int, len(g.people))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(i, j int) bool {
+		a, b := score(order[i]), score(order[j])
+		if a != b {
+			return a > b
+		}
+		return g.people[order[i]].ID < g.people[order[j]].ID
+	})
+	if len(order) > top {
+		order = order[:top]
+	}
+	return order
+}
+
+func sortedCounts(counts map[int]int) []models.SizeCount {
+	result := make([]models.SizeCount, 0, len(counts))
+	for size, count := range counts {
+		result = append(result, models.SizeCount{Size: size, Count: count})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Size < result[j].Size })
+	return result
+}
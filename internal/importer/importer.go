@@ -0,0 +1,229 @@
+package importer
+
+import (
+	"PeopleCRUD/internal/models"
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const (
+	FormatCSV    = "csv"
+	FormatNDJSON = "ndjson"
+
+	emailSeparator = ";"
+	maxLineBytes   = 1 << 20
+)
+
+// Record - одна распознанная строка входного файла. Если Err != nil, Request не заполнен.
+type Record struct {
+	Line    int
+	Request models.CreatePersonRequest
+	Raw     string
+	Err     error
+}
+
+// Reader читает записи по одной, не загружая файл целиком. По окончании данных возвращает io.EOF.
+type Reader interface {
+	Next() (*Record, error)
+}
+
+// headerAliases сопоставляет нормализованные названия колонок с полями запроса
+var headerAliases = map[string]string{
+	"first_name":  "first_name",
+	"firstname":   "first_name",
+	"first":       "first_name",
+	"имя":         "first_name",
+	"last_name":   "last_name",
+	"lastname":    "last_name",
+	"last":        "last_name",
+	"surname":     "last_name",
+	"фамилия":     "last_name",
+	"middle_name": "middle_name",
+	"middlename":  "middle_name",
+	"patronymic":  "middle_name",
+	"отчество":    "middle_name",
+	"emails":      "emails",
+	"email":       "emails",
+	"e-mail":      "emails",
+	"почта":       "emails",
+}
+
+// NewReader создает читателя нужного формата. mapping переопределяет сопоставление:
+// ключ - поле запроса (first_name, last_name, middle_name, emails), значение - имя колонки в файле.
+func NewReader(format string, r io.Reader, mapping map[string]string) (Reader, error) {
+	columns := make(map[string]string, len(headerAliases)+len(mapping))
+	for alias, field := range headerAliases {
+		columns[alias] = field
+	}
+	for field, column := range mapping {
+		if !isKnownField(field) {
+			return nil, fmt.Errorf("unknown field in mapping: %s", field)
+		}
+		columns[normalizeHeader(column)] = field
+	}
+
+	switch format {
+	case FormatCSV:
+		return newCSVReader(r, columns)
+	case FormatNDJSON:
+		return newNDJSONReader(r, columns), nil
+	default:
+		return nil, fmt.Errorf("unsupported import format: %s", format)
+	}
+}
+
+type csvReader struct {
+	reader *csv.Reader
+	fields []string
+}
+
+func newCSVReader(r io.Reader, columns map[string]string) (*csvReader, error) {
+	reader := csv.NewReader(bufio.NewReader(r))
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, fmt.Errorf("file is empty")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read header: %w", err)
+	}
+
+	fields := make([]string, len(header))
+	found := map[string]bool{}
+	for i, column := range header {
+		if i == 0 {
+			column = strings.TrimPrefix(column, "\uFEFF")
+		}
+		fields[i] = columns[normalizeHeader(column)]
+		found[fields[i]] = true
+	}
+	if !found["first_name"] || !found["last_name"] {
+		return nil, fmt.Errorf("header must contain first_name and last_name columns")
+	}
+
+	return &csvReader{reader: reader, fields: fields}, nil
+}
+
+func (r *csvReader) Next() (*Record, error) {
+	values, err := r.reader.Read()
+	if err == io.EOF {
+		return nil, io.EOF
+	}
+	if err != nil {
+		if parseErr, ok := err.(*csv.ParseError); ok {
+			return &Record{Line: parseErr.Line, Err: parseErr.Err}, nil
+		}
+		return nil, err
+	}
+
+	line, _ := r.reader.FieldPos(0)
+	record := &Record{Line: line, Raw: strings.Join(values, ",")}
+
+	for i, value := range values {
+		if i < len(r.fields) {
+			setField(&record.Request, r.fields[i], value)
+		}
+	}
+	return record, nil
+}
+
+type ndjsonReader struct {
+	scanner *bufio.Scanner
+	columns map[string]string
+	line    int
+}
+
+func newNDJSONReader(r io.Reader, columns map[string]string) *ndjsonReader {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxLineBytes)
+	return &ndjsonReader{scanner: scanner, columns: columns}
+}
+
+func (r *ndjsonReader) Next() (*Record, error) {
+	for r.scanner.Scan() {
+		r.line++
+		raw := strings.TrimSpace(r.scanner.Text())
+		if raw == "" {
+			continue
+		}
+
+		record := &Record{Line: r.line, Raw: raw}
+		var object map[string]interface{}
+		if err := json.Unmarshal([]byte(raw), &object); err != nil {
+			record.Err = fmt.Errorf("invalid JSON: %w", err)
+			return record, nil
+		}
+
+		for key, value := range object {
+			field := r.columns[normalizeHeader(key)]
+			switch v := value.(type) {
+			case string:
+				setField(&record.Request, field, v)
+			case []interface{}:
+				if field != "emails" {
+					record.Err = fmt.Errorf("field %s must be a string", key)
+					return record, nil
+				}
+				for _, item := range v {
+					email, ok := item.(string)
+					if !ok {
+						record.Err = fmt.Errorf("emails must contain strings")
+						return record, nil
+					}
+					record.Request.Emails = append(record.Request.Emails, strings.TrimSpace(email))
+				}
+			case nil:
+			default:
+				if field != "" {
+					record.Err = fmt.Errorf("field %s must be a string", key)
+					return record, nil
+				}
+			}
+		}
+		return record, nil
+	}
+
+	if err := r.scanner.Err(); err != nil {
+		return nil, err
+	}
+	return nil, io.EOF
+}
+
+func setField(req *models.CreatePersonRequest, field, value string) {
+	value = strings.TrimSpace(value)
+	switch field {
+	case "first_name":
+		req.FirstName = value
+	case "last_name":
+		req.LastName = value
+	case "middle_name":
+		if value != "" {
+			req.MiddleName = &value
+		}
+	case "emails":
+		for _, email := range strings.Split(value, emailSeparator) {
+			if email = strings.TrimSpace(email); email != "" {
+				req.Emails = append(req.Emails, email)
+			}
+		}
+	}
+}
+
+func normalizeHeader(header string) string {
+	header = strings.ToLower(strings.TrimSpace(header))
+	return strings.ReplaceAll(header, " ", "_")
+}
+
+func isKnownField(field string) bool {
+	switch field {
+	case "first_name", "last_name", "middle_name", "emails":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,31 @@
+package jobs
+
+import (
+	"PeopleCRUD/internal/service"
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// StartGraphAnalytics считает метрики графа дружбы каждого тенанта сразу при старте и затем раз в interval.
+// Останавливается при отмене ctx.
+func StartGraphAnalytics(ctx context.Context, personService service.PersonService, interval time.Duration, logger *logrus.Logger) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			forEachTenant(ctx, personService, logger, func(ctx context.Context) error {
+				_, err := personService.RefreshGraphAnalytics(ctx)
+				return err
+			}, "Graph analytics job failed")
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
@@ -0,0 +1,33 @@
+package jobs
+
+import (
+	"PeopleCRUD/internal/repository"
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// StartIdempotencyCleanup периодически удаляет истекшие ключи идемпотентности. Останавливается при отмене ctx.
+func StartIdempotencyCleanup(ctx context.Context, repo repository.IdempotencyRepository, interval time.Duration, logger *logrus.Logger) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				deleted, err := repo.DeleteExpired(ctx)
+				if err != nil {
+					logger.WithError(err).Error("Idempotency cleanup job failed")
+					continue
+				}
+				if deleted > 0 {
+					logger.WithField("count", deleted).Info("Deleted expired idempotency keys")
+				}
+			}
+		}
+	}()
+}
@@ -0,0 +1,34 @@
+package jobs
+
+import (
+	"PeopleCRUD/internal/repository"
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// StartImportReportCleanup периодически удаляет отчеты импорта всех тенантов старше retention. Останавливается при отмене ctx.
+func StartImportReportCleanup(ctx context.Context, repo repository.PersonRepository, retention, interval time.Duration, logger *logrus.Logger) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				// created_at - TIMESTAMP без зоны в UTC, поэтому граница тоже в UTC
+				deleted, err := repo.DeleteImportReports(ctx, time.Now().UTC().Add(-retention))
+				if err != nil {
+					logger.WithError(err).Error("Import report cleanup job failed")
+					continue
+				}
+				if deleted > 0 {
+					logger.WithField("count", deleted).Info("Deleted old import reports")
+				}
+			}
+		}
+	}()
+}
@@ -0,0 +1,30 @@
+package jobs
+
+import (
+	"PeopleCRUD/internal/service"
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// StartPurge периодически окончательно удаляет людей всех тенантов, которые пролежали в корзине дольше retention.
+// Останавливается при отмене ctx.
+func StartPurge(ctx context.Context, personService service.PersonService, retention, interval time.Duration, logger *logrus.Logger) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				forEachTenant(ctx, personService, logger, func(ctx context.Context) error {
+					_, err := personService.PurgeDeletedPeople(ctx, retention)
+					return err
+				}, "Purge job failed")
+			}
+		}
+	}()
+}
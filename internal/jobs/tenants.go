@@ -0,0 +1,29 @@
+package jobs
+
+import (
+	"PeopleCRUD/internal/reqctx"
+	"PeopleCRUD/internal/service"
+	"context"
+
+	"github.com/sirupsen/logrus"
+)
+
+// forEachTenant выполняет fn отдельно для каждого тенанта, логи сервисов внутри fn получают поле tenant. Сбой одного тенанта не мешает остальным
+func forEachTenant(ctx context.Context, personService service.PersonService, logger *logrus.Logger, fn func(ctx context.Context) error, failure string) {
+	tenants, err := personService.GetTenants(ctx)
+	if err != nil {
+		logger.WithError(err).Error(failure)
+		return
+	}
+
+	for _, tenant := range tenants {
+		if ctx.Err() != nil {
+			return
+		}
+		entry := logger.WithField("tenant", tenant)
+		tenantCtx := reqctx.WithLogger(reqctx.WithTenant(ctx, tenant), entry)
+		if err := fn(tenantCtx); err != nil {
+			entry.WithError(err).Error(failure)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package metrics
+
+import (
+	"PeopleCRUD/internal/cache"
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+)
+
+// Сколько сбор метрик ждет подсчет людей, чтобы медленная база не подвесила /metrics
+const peopleCountTimeout = 5 * time.Second
+
+// RegisterDB добавляет статистику пула соединений из sql.DB.Stats()
+func RegisterDB(db *sql.DB, name string) {
+	Registry.MustRegister(collectors.NewDBStatsCollector(db, name))
+}
+
+// RegisterCache добавляет счетчики кэша из cache.Stats(), снятые в момент сбора
+func RegisterCache(c cache.Cache) {
+	Registry.MustRegister(&cacheCollector{cache: c})
+}
+
+// RegisterPeopleCount добавляет общее число живых людей. count вызывается при каждом сборе.
+// Разбивки по тенантам нет: /metrics открыт без ключа и не должен раскрывать список тенантов
+func RegisterPeopleCount(count func(ctx context.Context) (int, error)) {
+	Registry.MustRegister(&peopleCollector{count: count})
+}
+
+var (
+	cacheHitsDesc      = prometheus.NewDesc("cache_hits_total", "Cache lookups that found a value.", nil, nil)
+	cacheMissesDesc    = prometheus.NewDesc("cache_misses_total", "Cache lookups that found nothing.", nil, nil)
+	cacheEvictionsDesc = prometheus.NewDesc("cache_evictions_total", "Entries evicted to stay within cache limits.", nil, nil)
+	cacheExpiredDesc   = prometheus.NewDesc("cache_expired_total", "Entries removed after their TTL.", nil, nil)
+	cacheEntriesDesc   = prometheus.NewDesc("cache_entries", "Entries currently in the cache.", nil, nil)
+	cacheBytesDesc     = prometheus.NewDesc("cache_bytes", "Estimated size of cached values in bytes.", nil, nil)
+
+	peopleDesc = prometheus.NewDesc("people_total", "People that are not deleted, across all tenants.", nil, nil)
+)
+
+type cacheCollector struct {
+	cache cache.Cache
+}
+
+func (c *cacheCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- cacheHitsDesc
+	ch <- cacheMissesDesc
+	ch <- cacheEvictionsDesc
+	ch <- cacheExpiredDesc
+	ch <- cacheEntriesDesc
+	ch <- cacheBytesDesc
+}
+
+func (c *cacheCollector) Collect(ch chan<- prometheus.Metric) {
+	stats := c.cache.Stats()
+	ch <- prometheus.MustNewConstMetric(cacheHitsDesc, prometheus.CounterValue, float64(stats.Hits))
+	ch <- prometheus.MustNewConstMetric(cacheMissesDesc, prometheus.CounterValue, float64(stats.Misses))
+	ch <- prometheus.MustNewConstMetric(cacheEvictionsDesc, prometheus.CounterValue, float64(stats.Evictions))
+	ch <- prometheus.MustNewConstMetric(cacheExpiredDesc, prometheus.CounterValue, float64(stats.Expired))
+	ch <- prometheus.MustNewConstMetric(cacheEntriesDesc, prometheus.GaugeValue, float64(stats.Entries))
+	ch <- prometheus.MustNewConstMetric(cacheBytesDesc, prometheus.GaugeValue, float64(stats.Bytes))
+}
+
+type peopleCollector struct {
+	count func(ctx context.Context) (int, error)
+}
+
+func (c *peopleCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- peopleDesc
+}
+
+func (c *peopleCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), peopleCountTimeout)
+	defer cancel()
+
+	count, err := c.count(ctx)
+	if err != nil {
+		ch <- prometheus.NewInvalidMetric(peopleDesc, err)
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(peopleDesc, prometheus.GaugeValue, float64(count))
+}
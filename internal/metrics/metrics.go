@@ -0,0 +1,100 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Registry - реестр метрик сервиса. Свой, а не глобальный prometheus, чтобы /metrics отдавал только то,
+// что зарегистрировано здесь
+var Registry = prometheus.NewRegistry()
+
+var (
+	httpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_requests_total",
+		Help: "HTTP requests by method, route template and status.",
+	}, []string{"method", "route", "status"})
+
+	httpDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_duration_seconds",
+		Help:    "HTTP request latency by method, route template and status.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	externalRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "external_requests_total",
+		Help: "Outbound enrichment requests by provider and status (\"error\" - no response).",
+	}, []string{"provider", "status"})
+
+	externalErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "external_request_errors_total",
+		Help: "Outbound enrichment requests that failed or got a non-2xx response.",
+	}, []string{"provider"})
+
+	externalDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "external_request_duration_seconds",
+		Help:    "Outbound enrichment request latency by provider.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"provider"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		httpRequests, httpDuration,
+		externalRequests, externalErrors, externalDuration,
+	)
+}
+
+// Handler отдает метрики в формате Prometheus. Сбой одного сборщика (например, база недоступна
+// для подсчета людей) не мешает отдать остальные
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{
+		Registry:      Registry,
+		ErrorHandling: promhttp.ContinueOnError,
+	})
+}
+
+// ObserveHTTP учитывает обработанный запрос. route - шаблон маршрута (/api/v1/people/:id), а не путь,
+// иначе каждый id станет отдельным рядом
+func ObserveHTTP(method, route string, status int, duration time.Duration) {
+	code := strconv.Itoa(status)
+	httpRequests.WithLabelValues(method, route, code).Inc()
+	httpDuration.WithLabelValues(method, route, code).Observe(duration.Seconds())
+}
+
+// Transport оборачивает транспорт http-клиента внешнего сервиса provider и учитывает каждый его запрос.
+// next == nil - http.DefaultTransport
+func Transport(provider string, next http.RoundTripper) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &instrumentedTransport{provider: provider, next: next}
+}
+
+type instrumentedTransport struct {
+	provider string
+	next     http.RoundTripper
+}
+
+func (t *instrumentedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+	externalDuration.WithLabelValues(t.provider).Observe(time.Since(start).Seconds())
+
+	status := "error"
+	if err == nil {
+		status = strconv.Itoa(resp.StatusCode)
+	}
+	externalRequests.WithLabelValues(t.provider, status).Inc()
+	if err != nil || resp.StatusCode < 200 || resp.StatusCode > 299 {
+		externalErrors.WithLabelValues(t.provider).Inc()
+	}
+	return resp, err
+}
@@ -1,8 +1,10 @@
 package models
 
 import (
+	"PeopleCRUD/internal/reqctx"
 	"PeopleCRUD/pkg/errors"
 	"regexp"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -10,15 +12,16 @@ import (
 var emailRegex = regexp.MustCompile(`^[a-zA-Z0-9._%+-]+@[a-zA-Z0-9.-]+\.[a-zA-Z]{2,}$`)
 
 type Person struct {
-	ID          int       `json:"id"`
-	FirstName   string    `json:"first_name"`
-	LastName    string    `json:"last_name"`
-	MiddleName  *string   `json:"middle_name,omitempty"`
-	Age         *int      `json:"age,omitempty"`
-	Gender      *string   `json:"gender,omitempty"`
-	Nationality *string   `json:"nationality,omitempty"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	ID          int        `json:"id"`
+	FirstName   string     `json:"first_name"`
+	LastName    string     `json:"last_name"`
+	MiddleName  *string    `json:"middle_name,omitempty"`
+	Age         *int       `json:"age,omitempty"`
+	Gender      *string    `json:"gender,omitempty"`
+	Nationality *string    `json:"nationality,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	DeletedAt   *time.Time `json:"deleted_at,omitempty"`
 }
 
 type PersonWithDetails struct {
@@ -76,7 +79,423 @@ func (r *UpdatePersonRequest) Validate() error {
 	return nil
 }
 
+// PeopleFilter - фильтры списка людей, общие для листинга и выгрузки
+type PeopleFilter struct {
+	LastName    string
+	Gender      string
+	Nationality string
+	MinAge      *int
+	MaxAge      *int
+}
+
+// CacheKey однозначно описывает фильтр для ключей кэша
+func (f PeopleFilter) CacheKey() string {
+	key := "last_name=" + f.LastName + "&gender=" + f.Gender + "&nationality=" + f.Nationality
+	if f.MinAge != nil {
+		key += "&min_age=" + strconv.Itoa(*f.MinAge)
+	}
+	if f.MaxAge != nil {
+		key += "&max_age=" + strconv.Itoa(*f.MaxAge)
+	}
+	return key
+}
+
 type AddEmailRequest struct {
 	Email     string `json:"email" binding:"required"`
 	IsPrimary bool   `json:"is_primary"`
 }
+
+const (
+	AuditEntityPerson       = "person"
+	AuditEntityEmail        = "email"
+	AuditEntityFriendship   = "friendship"
+	AuditEntityRelationship = "relationship"
+
+	AuditActionCreate  = "create"
+	AuditActionUpdate  = "update"
+	AuditActionDelete  = "delete"
+	AuditActionRestore = "restore"
+	AuditActionPurge   = "purge"
+	AuditActionMerge   = "merge"
+)
+
+type FieldChange struct {
+	Old interface{} `json:"old"`
+	New interface{} `json:"new"`
+}
+
+type AuditEvent struct {
+	ID         int64                  `json:"id"`
+	EntityType string                 `json:"entity_type"`
+	EntityID   int                    `json:"entity_id"`
+	PersonID   int                    `json:"person_id"`
+	Action     string                 `json:"action"`
+	Actor      string                 `json:"actor"`
+	RequestID  string                 `json:"request_id,omitempty"`
+	Changes    map[string]FieldChange `json:"changes,omitempty"`
+	CreatedAt  time.Time              `json:"created_at"`
+}
+
+type AuditFilter struct {
+	PersonID   *int
+	EntityType string
+	Action     string
+	Actor      string
+	RequestID  string
+	From       *time.Time
+	To         *time.Time
+	Limit      int
+	Offset     int
+}
+
+// PersonVersion - состояние человека, действовавшее в интервале [ValidFrom, ValidTo)
+type PersonVersion struct {
+	Version   int        `json:"version"`
+	Person    Person     `json:"person"`
+	ValidFrom time.Time  `json:"valid_from"`
+	ValidTo   *time.Time `json:"valid_to,omitempty"`
+}
+
+const (
+	BulkModeAtomic     = "all_or_nothing"
+	BulkModeBestEffort = "best_effort"
+
+	MaxBulkItems = 1000
+)
+
+type BulkUpdateItem struct {
+	ID int `json:"id" binding:"required"`
+	UpdatePersonRequest
+}
+
+type BulkDeleteRequest struct {
+	IDs []int `json:"ids" binding:"required"`
+}
+
+// BulkItemResult - результат обработки одного элемента пакета, Index - позиция во входном массиве
+type BulkItemResult struct {
+	Index int              `json:"index"`
+	ID    int              `json:"id,omitempty"`
+	Error *errors.AppError `json:"error,omitempty"`
+}
+
+type BulkResponse struct {
+	Mode      string           `json:"mode"`
+	Succeeded int              `json:"succeeded"`
+	Failed    int              `json:"failed"`
+	Results   []BulkItemResult `json:"results"`
+}
+
+type ImportRejection struct {
+	Line   int    `json:"line"`
+	Reason string `json:"reason"`
+	Raw    string `json:"raw,omitempty"`
+}
+
+type ImportResult struct {
+	DryRun   bool   `json:"dry_run"`
+	Total    int    `json:"total"`
+	Accepted int    `json:"accepted"`
+	Rejected int    `json:"rejected"`
+	ReportID string `json:"report_id,omitempty"`
+}
+
+// PersonExport - строка выгрузки: человек, его email'ы и id друзей
+type PersonExport struct {
+	Person
+	Emails    []string `json:"emails"`
+	FriendIDs []int64  `json:"friend_ids"`
+}
+
+const (
+	OnDuplicateWarn   = "warn"
+	OnDuplicateReject = "reject"
+	OnDuplicateIgnore = "ignore"
+
+	DuplicateReasonName  = "name"
+	DuplicateReasonEmail = "email"
+)
+
+// DuplicateCandidate - существующий человек, похожий на создаваемого или на другого человека
+type DuplicateCandidate struct {
+	Person       Person   `json:"person"`
+	Score        float64  `json:"score"`
+	Reasons      []string `json:"reasons"`
+	SharedEmails []string `json:"shared_emails,omitempty"`
+}
+
+type CreatePersonResult struct {
+	*PersonWithDetails
+	PossibleDuplicates []DuplicateCandidate `json:"possible_duplicates,omitempty"`
+}
+
+// DuplicatePair - пара людей, похожих по имени (Score - триграммное сходство) или по email
+type DuplicatePair struct {
+	PersonID int
+	OtherID  int
+	Score    float64
+	Reason   string
+}
+
+type DuplicateCluster struct {
+	People []Person `json:"people"`
+	Score  float64  `json:"score"`
+}
+
+const (
+	FriendshipPending  = "pending"
+	FriendshipAccepted = "accepted"
+	FriendshipRejected = "rejected"
+)
+
+// FriendRequest - заявка в друзья от FromID к ToID. Person - вторая сторона относительно запрашивающего.
+type FriendRequest struct {
+	FromID      int        `json:"from_id"`
+	ToID        int        `json:"to_id"`
+	Status      string     `json:"status"`
+	CreatedAt   time.Time  `json:"created_at"`
+	RespondedAt *time.Time `json:"responded_at,omitempty"`
+	Person      Person     `json:"person"`
+}
+
+const (
+	RelationshipFriend    = "friend"
+	RelationshipSibling   = "sibling"
+	RelationshipSpouse    = "spouse"
+	RelationshipColleague = "colleague"
+	RelationshipParent    = "parent"
+	RelationshipChild     = "child"
+	RelationshipManager   = "manager"
+	RelationshipReport    = "report"
+
+	relationshipDateLayout = "2006-01-02"
+)
+
+// RelationshipInverse - тип обратной стороны связи. Для симметричных типов совпадает с самим типом
+var RelationshipInverse = map[string]string{
+	RelationshipFriend:    RelationshipFriend,
+	RelationshipSibling:   RelationshipSibling,
+	RelationshipSpouse:    RelationshipSpouse,
+	RelationshipColleague: RelationshipColleague,
+	RelationshipParent:    RelationshipChild,
+	RelationshipChild:     RelationshipParent,
+	RelationshipManager:   RelationshipReport,
+	RelationshipReport:    RelationshipManager,
+}
+
+// Relationship - связь PersonID с RelatedID. Type описывает роль PersonID: "parent" - PersonID родитель RelatedID
+type Relationship struct {
+	PersonID  int       `json:"person_id"`
+	RelatedID int       `json:"related_id"`
+	Type      string    `json:"type"`
+	Directed  bool      `json:"directed"`
+	Weight    float64   `json:"weight"`
+	StartedOn *string   `json:"started_on,omitempty"`
+	EndedOn   *string   `json:"ended_on,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	Person    *Person   `json:"person,omitempty"`
+}
+
+type CreateRelationshipRequest struct {
+	RelatedID int      `json:"related_id" binding:"required"`
+	Type      string   `json:"type" binding:"required"`
+	Weight    *float64 `json:"weight,omitempty"`
+	StartedOn *string  `json:"started_on,omitempty"`
+	EndedOn   *string  `json:"ended_on,omitempty"`
+}
+
+func (r *CreateRelationshipRequest) Validate() error {
+	if _, ok := RelationshipInverse[r.Type]; !ok {
+		return errors.NewValidationError("Invalid relationship type: " + r.Type)
+	}
+	// Дружба создается только через заявку, которую принимает вторая сторона
+	if r.Type == RelationshipFriend {
+		return errors.NewValidationError("Friendship requires consent, use POST /api/v1/people/:id/friend-requests/:friendId")
+	}
+	return validateRelationshipAttrs(r.Weight, r.StartedOn, r.EndedOn)
+}
+
+type UpdateRelationshipRequest struct {
+	Weight    *float64 `json:"weight,omitempty"`
+	StartedOn *string  `json:"started_on,omitempty"`
+	EndedOn   *string  `json:"ended_on,omitempty"`
+}
+
+func (r *UpdateRelationshipRequest) Validate() error {
+	return validateRelationshipAttrs(r.Weight, r.StartedOn, r.EndedOn)
+}
+
+func validateRelationshipAttrs(weight *float64, startedOn, endedOn *string) error {
+	if weight != nil && (*weight < 0 || *weight > 1) {
+		return errors.NewValidationError("Weight must be between 0 and 1")
+	}
+
+	var started, ended time.Time
+	var err error
+	if startedOn != nil {
+		if started, err = time.Parse(relationshipDateLayout, *startedOn); err != nil {
+			return errors.NewValidationError("Invalid started_on, expected YYYY-MM-DD")
+		}
+	}
+	if endedOn != nil {
+		if ended, err = time.Parse(relationshipDateLayout, *endedOn); err != nil {
+			return errors.NewValidationError("Invalid ended_on, expected YYYY-MM-DD")
+		}
+	}
+	if startedOn != nil && endedOn != nil && ended.Before(started) {
+		return errors.NewValidationError("ended_on cannot be before started_on")
+	}
+	return nil
+}
+
+// IsDirectedRelationship сообщает, различаются ли роли сторон связи
+func IsDirectedRelationship(relType string) bool {
+	return RelationshipInverse[relType] != relType
+}
+
+// FriendPath - кратчайшая цепочка дружбы от первого человека в People до последнего
+type FriendPath struct {
+	Degrees int      `json:"degrees"`
+	People  []Person `json:"people"`
+}
+
+type FriendSuggestion struct {
+	Person
+	MutualFriends int `json:"mutual_friends"`
+}
+
+// GraphEdge - ребро дружбы для выгрузки графа, каждая пара выдается один раз (Source < Target)
+type GraphEdge struct {
+	Source int     `json:"source"`
+	Target int     `json:"target"`
+	Weight float64 `json:"weight"`
+}
+
+// GraphAnalytics - метрики графа дружбы, посчитанные фоновым заданием
+type GraphAnalytics struct {
+	ComputedAt         time.Time       `json:"computed_at"`
+	Nodes              int             `json:"nodes"`
+	Edges              int             `json:"edges"`
+	Components         int             `json:"components"`
+	LargestComponent   int             `json:"largest_component"`
+	ComponentSizes     []SizeCount     `json:"component_sizes"`
+	DegreeDistribution []SizeCount     `json:"degree_distribution"`
+	AverageDegree      float64         `json:"average_degree"`
+	AverageClustering  float64         `json:"average_clustering"`
+	Transitivity       float64         `json:"transitivity"`
+	BetweennessPivots  int             `json:"betweenness_pivots"`
+	MostConnected      []CentralPerson `json:"most_connected"`
+	MostBetween        []CentralPerson `json:"most_between"`
+}
+
+// SizeCount - сколько раз (Count) встречается значение Size: размер компоненты или степень вершины
+type SizeCount struct {
+	Size  int `json:"size"`
+	Count int `json:"count"`
+}
+
+type CentralPerson struct {
+	Person
+	Degree      int     `json:"degree"`
+	Clustering  float64 `json:"clustering"`
+	Betweenness float64 `json:"betweenness"`
+}
+
+const (
+	StatsPeriodDay  = "day"
+	StatsPeriodWeek = "week"
+)
+
+// DefaultAgeBuckets - границы возрастных интервалов по умолчанию, последний интервал открыт сверху
+var DefaultAgeBuckets = []int{0, 18, 25, 35, 45, 55, 65}
+
+// PeopleStats - агрегаты по людям, подходящим под фильтр листинга
+type PeopleStats struct {
+	Total                   int              `json:"total"`
+	ByGender                []GroupCount     `json:"by_gender"`
+	ByNationality           []GroupCount     `json:"by_nationality"`
+	AgeHistogram            []AgeBucket      `json:"age_histogram"`
+	UnknownAge              int              `json:"unknown_age"`
+	AverageAgeByNationality []NationalityAge `json:"average_age_by_nationality"`
+	Period                  string           `json:"period"`
+	CreatedPerPeriod        []PeriodCount    `json:"created_per_period"`
+	EmailCoverage           EmailCoverage    `json:"email_coverage"`
+}
+
+// GroupCount - число людей со значением Value, пустое значение обозначается "unknown"
+type GroupCount struct {
+	Value string `json:"value"`
+	Count int    `json:"count"`
+}
+
+// AgeBucket - люди с возрастом From <= age < To, To == nil - без верхней границы
+type AgeBucket struct {
+	From  int  `json:"from"`
+	To    *int `json:"to"`
+	Count int  `json:"count"`
+}
+
+type NationalityAge struct {
+	Nationality string  `json:"nationality"`
+	AverageAge  float64 `json:"average_age"`
+	Count       int     `json:"count"`
+}
+
+type PeriodCount struct {
+	Start time.Time `json:"start"`
+	Count int       `json:"count"`
+}
+
+type EmailCoverage struct {
+	WithEmail    int     `json:"with_email"`
+	WithoutEmail int     `json:"without_email"`
+	Ratio        float64 `json:"ratio"`
+}
+
+// APIKey - ключ доступа к API. Сам ключ не хранится, только его хеш и открытый префикс
+type APIKey struct {
+	ID         int        `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Role       string     `json:"role"`
+	TenantID   string     `json:"tenant_id"`
+	PersonID   *int       `json:"person_id,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
+
+// CreateAPIKeyRequest - Role по умолчанию reader, PersonID связывает ключ с человеком для самообслуживания.
+// TenantID - тенант ключа, по умолчанию тенант запроса. Выбрать чужой тенант может только ключ начальной настройки
+type CreateAPIKeyRequest struct {
+	Name     string  `json:"name" binding:"required"`
+	Role     string  `json:"role"`
+	TenantID *string `json:"tenant_id,omitempty"`
+	PersonID *int    `json:"person_id,omitempty"`
+}
+
+func (r *CreateAPIKeyRequest) Validate() error {
+	r.Name = strings.TrimSpace(r.Name)
+	if r.Name == "" || len(r.Name) > 100 {
+		return errors.NewValidationError("Name is required and must be at most 100 characters")
+	}
+	if r.TenantID != nil && !reqctx.ValidTenant(*r.TenantID) {
+		return errors.NewValidationError("Tenant ID must be 1-64 letters, digits, '_' or '-'")
+	}
+	return nil
+}
+
+// CreatedAPIKey отдается один раз при создании: позже узнать ключ нельзя
+type CreatedAPIKey struct {
+	APIKey
+	Key string `json:"key"`
+}
+
+// IdempotencyRecord - запрос с ключом идемпотентности и сохраненный ответ на него.
+// StatusCode == 0 - первый запрос еще выполняется
+type IdempotencyRecord struct {
+	Fingerprint string
+	StatusCode  int
+	ContentType string
+	Body        []byte
+}
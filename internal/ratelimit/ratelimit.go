@@ -0,0 +1,115 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"time"
+)
+
+// Классы ограничений. Чтение и запись ограничиваются по методу, enrich - назначенные дорогие записи
+// (создание людей, пачки, импорт), ip - все запросы с адреса до аутентификации
+const (
+	ClassDefault = "default"
+	ClassWrite   = "write"
+	ClassEnrich  = "enrich"
+	ClassIP      = "ip"
+)
+
+// Limit - ведро токенов: пополняется на Rate токенов в секунду и вмещает не больше Burst
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// PerMinute задает лимит в запросах в минуту с запасом burst на всплески
+func PerMinute(requests, burst int) Limit {
+	return Limit{Rate: float64(requests) / 60, Burst: burst}
+}
+
+// Result - состояние ведра после попытки взять токен
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// Reset - через сколько ведро наполнится полностью
+	Reset time.Duration
+	// RetryAfter - через сколько появится следующий токен, если запрос отклонен
+	RetryAfter time.Duration
+}
+
+// Store хранит ведра. Общее хранилище (например, Redis) позволяет нескольким репликам делить лимиты
+type Store interface {
+	// Take берет токен из ведра key и возвращает его состояние
+	Take(ctx context.Context, key string, limit Limit) (Result, error)
+}
+
+// Limiter выбирает класс ограничения для маршрута и берет токен из ведра вызывающего
+type Limiter struct {
+	store   Store
+	limits  map[string]Limit
+	classes map[string]string
+}
+
+// NewLimiter создает ограничитель. limits - лимиты по классам; класс без лимита не ограничивается
+func NewLimiter(store Store, limits map[string]Limit) *Limiter {
+	return &Limiter{store: store, limits: limits, classes: make(map[string]string)}
+}
+
+// Assign назначает класс маршрутам вида "POST /api/v1/people". Вызывается до начала обработки запросов
+func (l *Limiter) Assign(class string, routes ...string) {
+	for _, route := range routes {
+		l.classes[route] = class
+	}
+}
+
+// Class возвращает класс маршрута: назначенный явно, иначе write для изменяющих методов и default для остальных
+func (l *Limiter) Class(method, route string) string {
+	if class, exists := l.classes[method+" "+route]; exists {
+		return class
+	}
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return ClassDefault
+	default:
+		return ClassWrite
+	}
+}
+
+// Take берет токен класса для клиента. ok == false - класс не ограничен
+func (l *Limiter) Take(ctx context.Context, class, client string) (result Result, ok bool, err error) {
+	limit, exists := l.limits[class]
+	if !exists || limit.Rate <= 0 || limit.Burst <= 0 {
+		return Result{}, false, nil
+	}
+	result, err = l.store.Take(ctx, class+":"+client, limit)
+	return result, true, err
+}
+
+// bucket - ведро токенов на момент updated
+type bucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+// take пополняет ведро за прошедшее время и пытается взять токен
+func (b *bucket) take(now time.Time, limit Limit) Result {
+	capacity := float64(limit.Burst)
+	b.tokens = math.Min(capacity, b.tokens+now.Sub(b.updated).Seconds()*limit.Rate)
+	b.updated = now
+
+	result := Result{Limit: limit.Burst}
+	if b.tokens >= 1 {
+		b.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = seconds((1 - b.tokens) / limit.Rate)
+	}
+	result.Remaining = int(b.tokens)
+	result.Reset = seconds((capacity - b.tokens) / limit.Rate)
+	return result
+}
+
+func seconds(value float64) time.Duration {
+	return time.Duration(value * float64(time.Second))
+}
@@ -0,0 +1,100 @@
+package ratelimit
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestBucketTake(t *testing.T) {
+	limit := PerMinute(60, 3) // токен в секунду, до трех подряд
+	start := time.Now()
+	b := &bucket{tokens: float64(limit.Burst), updated: start}
+
+	for i := 0; i < 3; i++ {
+		if result := b.take(start, limit); !result.Allowed || result.Remaining != 2-i {
+			t.Fatalf("take %d: %+v, want allowed with %d remaining", i+1, result, 2-i)
+		}
+	}
+
+	denied := b.take(start, limit)
+	if denied.Allowed || denied.Remaining != 0 || denied.Limit != 3 {
+		t.Fatalf("take past burst: %+v, want denied", denied)
+	}
+	if denied.RetryAfter != time.Second || denied.Reset != 3*time.Second {
+		t.Errorf("RetryAfter = %v, Reset = %v, want 1s and 3s", denied.RetryAfter, denied.Reset)
+	}
+
+	// Через полсекунды токена еще нет, до него осталось полсекунды
+	half := b.take(start.Add(500*time.Millisecond), limit)
+	if half.Allowed || half.RetryAfter != 500*time.Millisecond {
+		t.Errorf("after 0.5s: %+v, want denied with 0.5s to wait", half)
+	}
+
+	refilled := b.take(start.Add(time.Second), limit)
+	if !refilled.Allowed || refilled.Remaining != 0 || refilled.RetryAfter != 0 {
+		t.Errorf("after 1s: %+v, want one refilled token", refilled)
+	}
+	if refilled.Reset != 3*time.Second {
+		t.Errorf("Reset after refill = %v, want 3s", refilled.Reset)
+	}
+
+	// Долгий простой не накапливает больше burst
+	idle := b.take(start.Add(time.Hour), limit)
+	if !idle.Allowed || idle.Remaining != 2 || idle.Reset != time.Second {
+		t.Errorf("after an hour: %+v, want a full bucket of 3 minus one", idle)
+	}
+}
+
+func TestLimiterClass(t *testing.T) {
+	limiter := NewLimiter(nil, nil)
+	limiter.Assign(ClassEnrich, "POST /api/v1/people", "POST /api/v1/people/import")
+
+	tests := []struct {
+		method, route, want string
+	}{
+		{http.MethodPost, "/api/v1/people", ClassEnrich},
+		{http.MethodPost, "/api/v1/people/import", ClassEnrich},
+		{http.MethodGet, "/api/v1/people", ClassDefault},
+		{http.MethodHead, "/api/v1/people/:id", ClassDefault},
+		{http.MethodOptions, "/api/v1/people", ClassDefault},
+		{http.MethodPut, "/api/v1/people/:id", ClassWrite},
+		{http.MethodDelete, "/api/v1/people/:id", ClassWrite},
+		{http.MethodPost, "/api/v1/people/:id/restore", ClassWrite},
+		{http.MethodPost, "", ClassWrite},
+	}
+	for _, tt := range tests {
+		if got := limiter.Class(tt.method, tt.route); got != tt.want {
+			t.Errorf("Class(%s %s) = %s, want %s", tt.method, tt.route, got, tt.want)
+		}
+	}
+}
+
+func TestLimiterTake(t *testing.T) {
+	store := NewMemoryStore(time.Minute)
+	t.Cleanup(store.Stop)
+	limiter := NewLimiter(store, map[string]Limit{
+		ClassWrite:   PerMinute(60, 1),
+		ClassDefault: {Rate: 0, Burst: 10},
+	})
+	ctx := context.Background()
+
+	if _, limited, _ := limiter.Take(ctx, ClassEnrich, "ip:1"); limited {
+		t.Error("class without a limit was limited")
+	}
+	if _, limited, _ := limiter.Take(ctx, ClassDefault, "ip:1"); limited {
+		t.Error("class with a zero rate was limited")
+	}
+
+	if result, limited, err := limiter.Take(ctx, ClassWrite, "ip:1"); err != nil || !limited || !result.Allowed {
+		t.Fatalf("first write: %+v, %v, %v", result, limited, err)
+	}
+	if result, _, _ := limiter.Take(ctx, ClassWrite, "ip:1"); result.Allowed {
+		t.Error("second write passed a burst of 1")
+	}
+	// Ведра раздельны по клиентам
+	if result, _, _ := limiter.Take(ctx, ClassWrite, "ip:2"); !result.Allowed {
+		t.Error("another client shares the bucket")
+	}
+}
@@ -0,0 +1,119 @@
+package repository
+
+import (
+	"PeopleCRUD/internal/models"
+	"PeopleCRUD/internal/reqctx"
+	"PeopleCRUD/pkg/errors"
+	"context"
+	"database/sql"
+
+	"github.com/lib/pq"
+)
+
+// APIKeyRepository хранит ключи API. Сами ключи не сохраняются, только их хеши
+type APIKeyRepository interface {
+	Create(ctx context.Context, key *models.APIKey, hash string) error
+	// GetByPrefix возвращает ключ и его хеш, в том числе отозванный
+	GetByPrefix(ctx context.Context, prefix string) (*models.APIKey, string, error)
+	// List и Revoke видят только ключи тенанта запроса
+	List(ctx context.Context) ([]models.APIKey, error)
+	Revoke(ctx context.Context, id int) error
+	TouchLastUsed(ctx context.Context, id int) error
+}
+
+type apiKeyRepository struct {
+	db *sql.DB
+}
+
+func NewAPIKeyRepository(db *sql.DB) APIKeyRepository {
+	return &apiKeyRepository{db: db}
+}
+
+func (r *apiKeyRepository) Create(ctx context.Context, key *models.APIKey, hash string) error {
+	query := `
+		INSERT INTO api_keys (name, prefix, key_hash, role, tenant_id, person_id) VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at`
+
+	err := r.db.QueryRowContext(ctx, query, key.Name, key.Prefix, hash, key.Role, key.TenantID, key.PersonID).Scan(&key.ID, &key.CreatedAt)
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23503" {
+			return errors.NewValidationError("Person not found")
+		}
+		return errors.NewInternalServerError("Failed to create API key")
+	}
+	return nil
+}
+
+func (r *apiKeyRepository) GetByPrefix(ctx context.Context, prefix string) (*models.APIKey, string, error) {
+	query := `
+		SELECT id, name, prefix, key_hash, role, tenant_id, person_id, created_at, last_used_at, revoked_at
+		FROM api_keys WHERE prefix = $1`
+
+	var key models.APIKey
+	var hash string
+	err := r.db.QueryRowContext(ctx, query, prefix).Scan(&key.ID, &key.Name, &key.Prefix, &hash, &key.Role, &key.TenantID, &key.PersonID,
+		&key.CreatedAt, &key.LastUsedAt, &key.RevokedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, "", errors.NewNotFoundError("API key not found")
+		}
+		return nil, "", errors.NewInternalServerError("Failed to get API key")
+	}
+	return &key, hash, nil
+}
+
+func (r *apiKeyRepository) List(ctx context.Context) ([]models.APIKey, error) {
+	query := `
+		SELECT id, name, prefix, role, tenant_id, person_id, created_at, last_used_at, revoked_at
+		FROM api_keys WHERE tenant_id = $1 ORDER BY id`
+
+	rows, err := r.db.QueryContext(ctx, query, reqctx.Tenant(ctx))
+	if err != nil {
+		return nil, errors.NewInternalServerError("Failed to get API keys")
+	}
+	defer rows.Close()
+
+	keys := []models.APIKey{}
+	for rows.Next() {
+		var key models.APIKey
+		if err := rows.Scan(&key.ID, &key.Name, &key.Prefix, &key.Role, &key.TenantID, &key.PersonID, &key.CreatedAt, &key.LastUsedAt, &key.RevokedAt); err != nil {
+			return nil, errors.NewInternalServerError("Failed to scan API key")
+		}
+		keys = append(keys, key)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.NewInternalServerError("Failed to get API keys")
+	}
+	return keys, nil
+}
+
+// Revoke отзывает ключ. Повторный отзыв не меняет время первого
+func (r *apiKeyRepository) Revoke(ctx context.Context, id int) error {
+	query := `UPDATE api_keys SET revoked_at = COALESCE(revoked_at, CURRENT_TIMESTAMP) WHERE id = $1 AND tenant_id = $2`
+
+	result, err := r.db.ExecContext(ctx, query, id, reqctx.Tenant(ctx))
+	if err != nil {
+		return errors.NewInternalServerError("Failed to revoke API key")
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return errors.NewInternalServerError("Failed to get rows affected")
+	}
+	if rowsAffected == 0 {
+		return errors.NewNotFoundError("API key not found")
+	}
+	return nil
+}
+
+// TouchLastUsed обновляет время использования не чаще раза в минуту, чтобы не писать в базу на каждый запрос
+func (r *apiKeyRepository) TouchLastUsed(ctx context.Context, id int) error {
+	query := `
+		UPDATE api_keys SET last_used_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < CURRENT_TIMESTAMP - INTERVAL '1 minute')`
+
+	if _, err := r.db.ExecContext(ctx, query, id); err != nil {
+		return errors.NewInternalServerError("Failed to update API key usage")
+	}
+	return nil
+}
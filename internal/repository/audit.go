@@ -0,0 +1,120 @@
+package repository
+
+import (
+	"PeopleCRUD/internal/models"
+	"PeopleCRUD/internal/reqctx"
+	"PeopleCRUD/pkg/errors"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// AuditRepository хранит журнал изменений. Записи только добавляются, чтение ограничено тенантом из контекста.
+type AuditRepository interface {
+	Create(ctx context.Context, event *models.AuditEvent) error
+	List(ctx context.Context, filter models.AuditFilter) ([]models.AuditEvent, int, error)
+}
+
+type auditRepository struct {
+	db *sql.DB
+}
+
+func NewAuditRepository(db *sql.DB) AuditRepository {
+	return &auditRepository{db: db}
+}
+
+func (r *auditRepository) Create(ctx context.Context, event *models.AuditEvent) error {
+	var changes []byte
+	if len(event.Changes) > 0 {
+		var err error
+		changes, err = json.Marshal(event.Changes)
+		if err != nil {
+			return fmt.Errorf("failed to marshal audit changes: %w", err)
+		}
+	}
+
+	query := `
+		INSERT INTO audit_events (entity_type, entity_id, person_id, action, actor, request_id, changes, tenant_id)
+		VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''), $7, $8)
+		RETURNING id, created_at`
+
+	err := r.db.QueryRowContext(ctx, query, event.EntityType, event.EntityID, event.PersonID, event.Action,
+		event.Actor, event.RequestID, changes, reqctx.Tenant(ctx)).Scan(&event.ID, &event.CreatedAt)
+	if err != nil {
+		return errors.NewInternalServerError("Failed to create audit event")
+	}
+	return nil
+}
+
+func (r *auditRepository) List(ctx context.Context, filter models.AuditFilter) ([]models.AuditEvent, int, error) {
+	conditions := []string{}
+	args := []interface{}{}
+
+	addCondition := func(format string, value interface{}) {
+		args = append(args, value)
+		conditions = append(conditions, fmt.Sprintf(format, len(args)))
+	}
+
+	addCondition("tenant_id = $%d", reqctx.Tenant(ctx))
+	if filter.PersonID != nil {
+		addCondition("person_id = $%d", *filter.PersonID)
+	}
+	if filter.EntityType != "" {
+		addCondition("entity_type = $%d", filter.EntityType)
+	}
+	if filter.Action != "" {
+		addCondition("action = $%d", filter.Action)
+	}
+	if filter.Actor != "" {
+		addCondition("actor = $%d", filter.Actor)
+	}
+	if filter.RequestID != "" {
+		addCondition("request_id = $%d", filter.RequestID)
+	}
+	if filter.From != nil {
+		addCondition("created_at >= $%d", *filter.From)
+	}
+	if filter.To != nil {
+		addCondition("created_at < $%d", *filter.To)
+	}
+
+	where := "WHERE " + strings.Join(conditions, " AND ")
+
+	var total int
+	if err := r.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM audit_events "+where, args...).Scan(&total); err != nil {
+		return nil, 0, errors.NewInternalServerError("Failed to count audit events")
+	}
+
+	query := fmt.Sprintf(`
+		SELECT id, entity_type, entity_id, person_id, action, actor, COALESCE(request_id, ''), changes, created_at
+		FROM audit_events %s
+		ORDER BY created_at DESC, id DESC
+		LIMIT $%d OFFSET $%d`, where, len(args)+1, len(args)+2)
+
+	rows, err := r.db.QueryContext(ctx, query, append(args, filter.Limit, filter.Offset)...)
+	if err != nil {
+		return nil, 0, errors.NewInternalServerError("Failed to get audit events")
+	}
+	defer rows.Close()
+
+	var events []models.AuditEvent
+	for rows.Next() {
+		var event models.AuditEvent
+		var changes []byte
+		err := rows.Scan(&event.ID, &event.EntityType, &event.EntityID, &event.PersonID, &event.Action,
+			&event.Actor, &event.RequestID, &changes, &event.CreatedAt)
+		if err != nil {
+			return nil, 0, errors.NewInternalServerError("Failed to scan audit event")
+		}
+		if len(changes) > 0 {
+			if err := json.Unmarshal(changes, &event.Changes); err != nil {
+				return nil, 0, errors.NewInternalServerError("Failed to decode audit changes")
+			}
+		}
+		events = append(events, event)
+	}
+
+	return events, total, nil
+}
@@ -0,0 +1,325 @@
+package repository
+
+import (
+	"PeopleCRUD/internal/models"
+	"PeopleCRUD/internal/reqctx"
+	"PeopleCRUD/pkg/errors"
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// Размер чанка для многострочных INSERT: держимся далеко от лимита в 65535 параметров
+const bulkChunkSize = 500
+
+// Email'ы вставляются отдельными чанками по числу строк, а не людей: у человека их может быть сколько угодно.
+// 3 параметра на строку, 15001 с тенантом
+const bulkEmailChunkSize = 5000
+
+var errRolledBack = errors.NewAppError(http.StatusConflict, "Rolled back", "Another item in the all-or-nothing batch failed")
+
+// BulkCreate создает людей многострочными INSERT. id выделяются заранее из последовательности,
+// поэтому соответствие строк и входных элементов не зависит от порядка RETURNING.
+func (r *personRepository) BulkCreate(ctx context.Context, people []*models.Person, emails [][]string, atomic bool) []error {
+	return r.runBulk(ctx, len(people), atomic,
+		func(tx *sql.Tx) ([]error, error) {
+			if err := allocatePersonIDs(ctx, tx, people); err != nil {
+				return nil, err
+			}
+			for start := 0; start < len(people); start += bulkChunkSize {
+				end := min(start+bulkChunkSize, len(people))
+				if err := insertPeopleChunk(ctx, tx, people[start:end], emails[start:end]); err != nil {
+					return nil, err
+				}
+			}
+			return make([]error, len(people)), nil
+		},
+		func(tx *sql.Tx, i int) error {
+			if err := allocatePersonIDs(ctx, tx, people[i:i+1]); err != nil {
+				return err
+			}
+			return insertPeopleChunk(ctx, tx, people[i:i+1], emails[i:i+1])
+		},
+	)
+}
+
+// BulkUpdate обновляет людей одним UPDATE ... FROM (VALUES ...). Пустые поля запроса не меняются.
+func (r *personRepository) BulkUpdate(ctx context.Context, ids []int, reqs []*models.UpdatePersonRequest, atomic bool) []error {
+	return r.runBulk(ctx, len(ids), atomic,
+		func(tx *sql.Tx) ([]error, error) {
+			itemErrs := make([]error, len(ids))
+			for start := 0; start < len(ids); start += bulkChunkSize {
+				end := min(start+bulkChunkSize, len(ids))
+				updated, err := updatePeopleChunk(ctx, tx, ids[start:end], reqs[start:end])
+				if err != nil {
+					return nil, err
+				}
+				for i := start; i < end; i++ {
+					if !updated[ids[i]] {
+						itemErrs[i] = errors.NewNotFoundError("Person not found")
+					}
+				}
+			}
+			return itemErrs, nil
+		},
+		func(tx *sql.Tx, i int) error {
+			updated, err := updatePeopleChunk(ctx, tx, ids[i:i+1], reqs[i:i+1])
+			if err != nil {
+				return err
+			}
+			if !updated[ids[i]] {
+				return errors.NewNotFoundError("Person not found")
+			}
+			return nil
+		},
+	)
+}
+
+// BulkDelete мягко удаляет людей по списку id
+func (r *personRepository) BulkDelete(ctx context.Context, ids []int, atomic bool) []error {
+	return r.runBulk(ctx, len(ids), atomic,
+		func(tx *sql.Tx) ([]error, error) {
+			query := `UPDATE people SET deleted_at = CURRENT_TIMESTAMP WHERE id = ANY($1) AND tenant_id = $2 AND deleted_at IS NULL RETURNING id`
+
+			rows, err := tx.QueryContext(ctx, query, pq.Array(ids), reqctx.Tenant(ctx))
+			if err != nil {
+				return nil, errors.NewInternalServerError("Failed to delete people")
+			}
+			defer rows.Close()
+
+			deleted := make(map[int]bool, len(ids))
+			for rows.Next() {
+				var id int
+				if err := rows.Scan(&id); err != nil {
+					return nil, errors.NewInternalServerError("Failed to scan deleted person id")
+				}
+				deleted[id] = true
+			}
+			if err := rows.Err(); err != nil {
+				return nil, errors.NewInternalServerError("Failed to delete people")
+			}
+
+			itemErrs := make([]error, len(ids))
+			for i, id := range ids {
+				if !deleted[id] {
+					itemErrs[i] = errors.NewNotFoundError("Person not found")
+				}
+			}
+			return itemErrs, nil
+		},
+		nil,
+	)
+}
+
+// runBulk выполняет пакет в одной транзакции. В режиме all-or-nothing любая ошибка откатывает
+// весь пакет. В режиме best-effort при ошибке пакетного запроса операции повторяются по одной,
+// каждая под своим savepoint, чтобы сбой одной строки не ронял остальные.
+func (r *personRepository) runBulk(ctx context.Context, n int, atomic bool, batch func(tx *sql.Tx) ([]error, error), single func(tx *sql.Tx, i int) error) []error {
+	errs := make([]error, n)
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fillErrors(errs, errors.NewInternalServerError("Failed to begin transaction"))
+	}
+
+	itemErrs, batchErr := batch(tx)
+	if batchErr == nil {
+		if atomic && hasErrors(itemErrs) {
+			tx.Rollback()
+			for i, e := range itemErrs {
+				if e == nil {
+					itemErrs[i] = errRolledBack
+				}
+			}
+			return itemErrs
+		}
+		if err := tx.Commit(); err != nil {
+			return fillErrors(errs, errors.NewInternalServerError("Failed to commit transaction"))
+		}
+		return itemErrs
+	}
+
+	tx.Rollback()
+	if atomic || single == nil {
+		return fillErrors(errs, batchErr)
+	}
+
+	tx, err = r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fillErrors(errs, errors.NewInternalServerError("Failed to begin transaction"))
+	}
+	defer tx.Rollback()
+
+	for i := 0; i < n; i++ {
+		if _, err := tx.ExecContext(ctx, "SAVEPOINT bulk_item"); err != nil {
+			return fillErrors(errs, errors.NewInternalServerError("Failed to create savepoint"))
+		}
+		if err := single(tx, i); err != nil {
+			errs[i] = err
+			if _, err := tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT bulk_item"); err != nil {
+				return fillErrors(errs, errors.NewInternalServerError("Failed to rollback savepoint"))
+			}
+			continue
+		}
+		if _, err := tx.ExecContext(ctx, "RELEASE SAVEPOINT bulk_item"); err != nil {
+			return fillErrors(errs, errors.NewInternalServerError("Failed to release savepoint"))
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fillErrors(errs, errors.NewInternalServerError("Failed to commit transaction"))
+	}
+	return errs
+}
+
+func allocatePersonIDs(ctx context.Context, tx *sql.Tx, people []*models.Person) error {
+	rows, err := tx.QueryContext(ctx, `SELECT nextval(pg_get_serial_sequence('people', 'id')) FROM generate_series(1, $1)`, len(people))
+	if err != nil {
+		return errors.NewInternalServerError("Failed to allocate person ids")
+	}
+	defer rows.Close()
+
+	i := 0
+	for rows.Next() {
+		if err := rows.Scan(&people[i].ID); err != nil {
+			return errors.NewInternalServerError("Failed to scan person id")
+		}
+		i++
+	}
+	return rows.Err()
+}
+
+func insertPeopleChunk(ctx context.Context, tx *sql.Tx, people []*models.Person, emails [][]string) error {
+	values := make([]string, 0, len(people))
+	tenant := reqctx.Tenant(ctx)
+	args := make([]interface{}, 0, len(people)*7+1)
+	args = append(args, tenant)
+	for _, p := range people {
+		n := len(args)
+		values = append(values, fmt.Sprintf("($%d, $%d, $%d, $%d, $%d, $%d, $%d, $1)", n+1, n+2, n+3, n+4, n+5, n+6, n+7))
+		args = append(args, p.ID, p.FirstName, p.LastName, p.MiddleName, p.Age, p.Gender, p.Nationality)
+	}
+
+	query := `INSERT INTO people (id, first_name, last_name, middle_name, age, gender, nationality, tenant_id) VALUES ` +
+		strings.Join(values, ", ") + ` RETURNING id, created_at, updated_at`
+
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return errors.NewInternalServerError("Failed to create people")
+	}
+	byID := make(map[int]*models.Person, len(people))
+	for _, p := range people {
+		byID[p.ID] = p
+	}
+	for rows.Next() {
+		var id int
+		var person models.Person
+		if err := rows.Scan(&id, &person.CreatedAt, &person.UpdatedAt); err != nil {
+			rows.Close()
+			return errors.NewInternalServerError("Failed to scan created person")
+		}
+		byID[id].CreatedAt, byID[id].UpdatedAt = person.CreatedAt, person.UpdatedAt
+	}
+	err = rows.Err()
+	rows.Close()
+	if err != nil {
+		return errors.NewInternalServerError("Failed to create people")
+	}
+
+	values = values[:0]
+	args = append(args[:0], tenant)
+	for i, p := range people {
+		for j, email := range emails[i] {
+			n := len(args)
+			values = append(values, fmt.Sprintf("($%d, $%d, $%d, $1)", n+1, n+2, n+3))
+			args = append(args, p.ID, email, j == 0)
+
+			if len(values) == bulkEmailChunkSize {
+				if err := insertEmailsChunk(ctx, tx, values, args); err != nil {
+					return err
+				}
+				values = values[:0]
+				args = append(args[:0], tenant)
+			}
+		}
+	}
+	if len(values) == 0 {
+		return nil
+	}
+	return insertEmailsChunk(ctx, tx, values, args)
+}
+
+func insertEmailsChunk(ctx context.Context, tx *sql.Tx, values []string, args []interface{}) error {
+	query := `INSERT INTO emails (person_id, email, is_primary, tenant_id) VALUES ` + strings.Join(values, ", ")
+	if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+			return errors.NewConflictError("Email already exists")
+		}
+		return errors.NewInternalServerError("Failed to add emails")
+	}
+	return nil
+}
+
+func updatePeopleChunk(ctx context.Context, tx *sql.Tx, ids []int, reqs []*models.UpdatePersonRequest) (map[int]bool, error) {
+	values := make([]string, 0, len(ids))
+	args := make([]interface{}, 0, len(ids)*7+1)
+	args = append(args, reqctx.Tenant(ctx))
+	for i, req := range reqs {
+		n := len(args)
+		values = append(values, fmt.Sprintf("($%d::int, $%d::varchar, $%d::varchar, $%d::varchar, $%d::int, $%d::varchar, $%d::varchar)",
+			n+1, n+2, n+3, n+4, n+5, n+6, n+7))
+		args = append(args, ids[i], req.FirstName, req.LastName, req.MiddleName, req.Age, req.Gender, req.Nationality)
+	}
+
+	query := `
+		UPDATE people p SET
+			first_name = COALESCE(v.first_name, p.first_name),
+			last_name = COALESCE(v.last_name, p.last_name),
+			middle_name = COALESCE(v.middle_name, p.middle_name),
+			age = COALESCE(v.age, p.age),
+			gender = COALESCE(v.gender, p.gender),
+			nationality = COALESCE(v.nationality, p.nationality),
+			updated_at = CURRENT_TIMESTAMP
+		FROM (VALUES ` + strings.Join(values, ", ") + `) AS v(id, first_name, last_name, middle_name, age, gender, nationality)
+		WHERE p.id = v.id AND p.tenant_id = $1 AND p.deleted_at IS NULL
+		RETURNING p.id`
+
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, errors.NewInternalServerError("Failed to update people")
+	}
+	defer rows.Close()
+
+	updated := make(map[int]bool, len(ids))
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, errors.NewInternalServerError("Failed to scan updated person id")
+		}
+		updated[id] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.NewInternalServerError("Failed to update people")
+	}
+	return updated, nil
+}
+
+func fillErrors(errs []error, err error) []error {
+	for i := range errs {
+		errs[i] = err
+	}
+	return errs
+}
+
+func hasErrors(errs []error) bool {
+	for _, err := range errs {
+		if err != nil {
+			return true
+		}
+	}
+	return false
+}
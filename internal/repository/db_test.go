@@ -0,0 +1,53 @@
+package repository
+
+import (
+	"PeopleCRUD/pkg/errors"
+	"database/sql"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// testDB подключается к базе из TEST_DATABASE_URL и очищает ее. База должна быть отдельной:
+// тесты удаляют все данные. Без переменной тесты пропускаются
+func testDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+
+	db, err := sql.Open("postgres", url)
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	schema, err := os.ReadFile(filepath.Join("..", "..", "db-init", "init.sql"))
+	if err != nil {
+		t.Fatalf("read schema: %v", err)
+	}
+	// Второй прогон - путь обновления существующей базы, он тоже должен проходить без ошибок
+	for i := 0; i < 2; i++ {
+		if _, err := db.Exec(string(schema)); err != nil {
+			t.Fatalf("apply schema (run %d): %v", i+1, err)
+		}
+	}
+
+	_, err = db.Exec(`TRUNCATE people, people_history, emails_history, relationships_history, audit_events, api_keys, import_reports
+		RESTART IDENTITY CASCADE`)
+	if err != nil {
+		t.Fatalf("truncate: %v", err)
+	}
+	return db
+}
+
+func requireNotFound(t *testing.T, what string, err error) {
+	t.Helper()
+	appErr, ok := err.(*errors.AppError)
+	if !ok || appErr.Code != http.StatusNotFound {
+		t.Errorf("%s: expected 404, got %v", what, err)
+	}
+}
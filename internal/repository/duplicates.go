@@ -0,0 +1,237 @@
+package repository
+
+import (
+	"PeopleCRUD/internal/models"
+	"PeopleCRUD/internal/reqctx"
+	"PeopleCRUD/pkg/errors"
+	"context"
+	"database/sql"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// FindDuplicateCandidates ищет живых людей, чье нормализованное имя похоже на normalizedName
+// не меньше чем на threshold, или у которых есть email из списка (без учета регистра)
+func (r *personRepository) FindDuplicateCandidates(ctx context.Context, normalizedName string, emails []string, threshold float64, limit int) ([]models.DuplicateCandidate, error) {
+	lowered := make([]string, len(emails))
+	for i, email := range emails {
+		lowered[i] = strings.ToLower(email)
+	}
+
+	query := `
+		WITH by_name AS (
+			SELECT id, similarity(normalized_name, $1) AS score
+			FROM people
+			WHERE tenant_id = $5 AND deleted_at IS NULL AND normalized_name % $1 AND similarity(normalized_name, $1) >= $2
+		), by_email AS (
+			SELECT e.person_id AS id, array_agg(e.email ORDER BY e.email) AS shared
+			FROM emails e
+			WHERE e.tenant_id = $5 AND lower(e.email) = ANY($3)
+			GROUP BY e.person_id
+		)
+		SELECT p.id, p.first_name, p.last_name, p.middle_name, p.age, p.gender, p.nationality, p.created_at, p.updated_at,
+			n.score, COALESCE(m.shared, '{}')
+		FROM people p
+		LEFT JOIN by_name n ON n.id = p.id
+		LEFT JOIN by_email m ON m.id = p.id
+		WHERE p.tenant_id = $5 AND p.deleted_at IS NULL AND (n.id IS NOT NULL OR m.id IS NOT NULL)
+		ORDER BY (m.id IS NOT NULL) DESC, n.score DESC NULLS LAST, p.id
+		LIMIT $4`
+
+	rows, err := r.db.QueryContext(ctx, query, normalizedName, threshold, pq.Array(lowered), limit, reqctx.Tenant(ctx))
+	if err != nil {
+		return nil, errors.NewInternalServerError("Failed to find duplicate candidates")
+	}
+	defer rows.Close()
+
+	var candidates []models.DuplicateCandidate
+	for rows.Next() {
+		var candidate models.DuplicateCandidate
+		var nameScore sql.NullFloat64
+		var shared pq.StringArray
+		person := &candidate.Person
+		err := rows.Scan(
+			&person.ID, &person.FirstName, &person.LastName, &person.MiddleName,
+			&person.Age, &person.Gender, &person.Nationality, &person.CreatedAt, &person.UpdatedAt,
+			&nameScore, &shared,
+		)
+		if err != nil {
+			return nil, errors.NewInternalServerError("Failed to scan duplicate candidate")
+		}
+
+		if nameScore.Valid {
+			candidate.Score = nameScore.Float64
+			candidate.Reasons = append(candidate.Reasons, models.DuplicateReasonName)
+		}
+		if len(shared) > 0 {
+			candidate.Score = 1
+			candidate.Reasons = append(candidate.Reasons, models.DuplicateReasonEmail)
+			candidate.SharedEmails = shared
+		}
+		candidates = append(candidates, candidate)
+	}
+
+	return candidates, nil
+}
+
+// FindDuplicatePairs возвращает пары живых людей с похожими именами или общим email (без учета регистра).
+// Самые вероятные пары идут первыми, поэтому limit отрезает одни и те же наименее похожие
+func (r *personRepository) FindDuplicatePairs(ctx context.Context, threshold float64, limit int) ([]models.DuplicatePair, error) {
+	query := `
+		SELECT a.id, b.id, similarity(a.normalized_name, b.normalized_name), 'name'
+		FROM people a
+		JOIN people b ON b.tenant_id = a.tenant_id AND a.id < b.id AND a.normalized_name % b.normalized_name
+		WHERE a.tenant_id = $3 AND a.deleted_at IS NULL AND b.deleted_at IS NULL
+			AND similarity(a.normalized_name, b.normalized_name) >= $1
+		UNION ALL
+		SELECT DISTINCT ea.person_id, eb.person_id, 1.0::real, 'email'
+		FROM emails ea
+		JOIN emails eb ON eb.tenant_id = ea.tenant_id AND lower(ea.email) = lower(eb.email) AND ea.person_id < eb.person_id
+		JOIN people pa ON pa.id = ea.person_id AND pa.deleted_at IS NULL
+		JOIN people pb ON pb.id = eb.person_id AND pb.deleted_at IS NULL
+		WHERE ea.tenant_id = $3
+		ORDER BY 3 DESC, 1, 2, 4
+		LIMIT $2`
+
+	rows, err := r.db.QueryContext(ctx, query, threshold, limit, reqctx.Tenant(ctx))
+	if err != nil {
+		return nil, errors.NewInternalServerError("Failed to find duplicate pairs")
+	}
+	defer rows.Close()
+
+	var pairs []models.DuplicatePair
+	for rows.Next() {
+		var pair models.DuplicatePair
+		if err := rows.Scan(&pair.PersonID, &pair.OtherID, &pair.Score, &pair.Reason); err != nil {
+			return nil, errors.NewInternalServerError("Failed to scan duplicate pair")
+		}
+		pairs = append(pairs, pair)
+	}
+
+	return pairs, nil
+}
+
+// GetByIDs возвращает живых людей по списку id. Отсутствующие id просто пропускаются.
+func (r *personRepository) GetByIDs(ctx context.Context, ids []int) (map[int]models.Person, error) {
+	query := `
+		SELECT id, first_name, last_name, middle_name, age, gender, nationality, created_at, updated_at
+		FROM people WHERE id = ANY($1) AND tenant_id = $2 AND deleted_at IS NULL`
+
+	rows, err := r.db.QueryContext(ctx, query, pq.Array(ids), reqctx.Tenant(ctx))
+	if err != nil {
+		return nil, errors.NewInternalServerError("Failed to get people")
+	}
+	defer rows.Close()
+
+	people := make(map[int]models.Person, len(ids))
+	for rows.Next() {
+		var person models.Person
+		err := rows.Scan(
+			&person.ID, &person.FirstName, &person.LastName, &person.MiddleName,
+			&person.Age, &person.Gender, &person.Nationality, &person.CreatedAt, &person.UpdatedAt,
+		)
+		if err != nil {
+			return nil, errors.NewInternalServerError("Failed to scan person")
+		}
+		people[person.ID] = person
+	}
+
+	return people, nil
+}
+
+// Merge переносит email'ы и связи otherID на keepID, дополняет пустые поля keepID
+// значениями otherID и мягко удаляет otherID. Все выполняется в одной транзакции.
+// Возвращает id людей, связи с которыми изменились.
+func (r *personRepository) Merge(ctx context.Context, keepID, otherID int) ([]int, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, errors.NewInternalServerError("Failed to begin transaction")
+	}
+	defer tx.Rollback()
+
+	// Дальше оба id уже проверены на принадлежность тенанту, а связи и email'ы не выходят за его пределы по внешним ключам
+	rows, err := tx.QueryContext(ctx, `
+		SELECT id FROM people WHERE id IN ($1, $2) AND tenant_id = $3 AND deleted_at IS NULL
+		ORDER BY id FOR UPDATE`, keepID, otherID, reqctx.Tenant(ctx))
+	if err != nil {
+		return nil, errors.NewInternalServerError("Failed to lock people")
+	}
+	locked := 0
+	for rows.Next() {
+		locked++
+	}
+	rows.Close()
+	if locked != 2 {
+		return nil, errors.NewNotFoundError("Person not found")
+	}
+
+	fillQuery := `
+		UPDATE people k SET
+			middle_name = COALESCE(k.middle_name, o.middle_name),
+			age = COALESCE(k.age, o.age),
+			gender = COALESCE(k.gender, o.gender),
+			nationality = COALESCE(k.nationality, o.nationality)
+		FROM people o
+		WHERE k.id = $1 AND o.id = $2`
+	if _, err := tx.ExecContext(ctx, fillQuery, keepID, otherID); err != nil {
+		return nil, errors.NewInternalServerError("Failed to merge person fields")
+	}
+
+	// Основным остается email сохраняемого человека
+	emailQuery := `
+		UPDATE emails SET person_id = $1,
+			is_primary = is_primary AND NOT EXISTS (SELECT 1 FROM emails WHERE person_id = $1 AND is_primary)
+		WHERE person_id = $2`
+	if _, err := tx.ExecContext(ctx, emailQuery, keepID, otherID); err != nil {
+		return nil, errors.NewInternalServerError("Failed to move emails")
+	}
+
+	relatedRows, err := tx.QueryContext(ctx, `
+		SELECT DISTINCT related_id FROM relationships
+		WHERE person_id = $1 AND related_id <> $2 AND status = 'accepted'`, otherID, keepID)
+	if err != nil {
+		return nil, errors.NewInternalServerError("Failed to get relationships")
+	}
+	var relatedIDs []int
+	for relatedRows.Next() {
+		var id int
+		if err := relatedRows.Scan(&id); err != nil {
+			relatedRows.Close()
+			return nil, errors.NewInternalServerError("Failed to scan relationship")
+		}
+		relatedIDs = append(relatedIDs, id)
+	}
+	relatedRows.Close()
+
+	// Переносим обе стороны каждой связи, при совпадении с уже существующей оставляем больший вес
+	linkQuery := `
+		INSERT INTO relationships (person_id, related_id, type, status, weight, started_on, ended_on, tenant_id)
+		SELECT CASE WHEN person_id = $2::int THEN $1::int ELSE person_id END,
+			CASE WHEN related_id = $2::int THEN $1::int ELSE related_id END,
+			type, 'accepted', weight, started_on, ended_on, tenant_id
+		FROM relationships
+		WHERE (person_id = $2::int OR related_id = $2::int)
+			AND person_id <> $1::int AND related_id <> $1::int AND status = 'accepted'
+		ON CONFLICT (person_id, related_id, type) DO UPDATE SET
+			status = 'accepted',
+			weight = GREATEST(relationships.weight, EXCLUDED.weight),
+			started_on = COALESCE(relationships.started_on, EXCLUDED.started_on),
+			responded_at = CURRENT_TIMESTAMP`
+	if _, err := tx.ExecContext(ctx, linkQuery, keepID, otherID); err != nil {
+		return nil, errors.NewInternalServerError("Failed to move relationships")
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM relationships WHERE person_id = $1 OR related_id = $1`, otherID); err != nil {
+		return nil, errors.NewInternalServerError("Failed to remove merged relationships")
+	}
+
+	if _, err := tx.ExecContext(ctx, `UPDATE people SET deleted_at = CURRENT_TIMESTAMP WHERE id = $1`, otherID); err != nil {
+		return nil, errors.NewInternalServerError("Failed to delete merged person")
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, errors.NewInternalServerError("Failed to commit merge")
+	}
+	return relatedIDs, nil
+}
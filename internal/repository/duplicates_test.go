@@ -0,0 +1,52 @@
+package repository
+
+import (
+	"PeopleCRUD/internal/models"
+	"PeopleCRUD/internal/reqctx"
+	"context"
+	"testing"
+)
+
+func TestFindDuplicatePairsOrderedByScore(t *testing.T) {
+	repo := NewPersonRepository(testDB(t))
+	ctx := reqctx.WithTenant(context.Background(), "team-a")
+
+	people := []struct {
+		person *models.Person
+		emails []string
+	}{
+		{&models.Person{FirstName: "Ivan", LastName: "Petrov"}, nil},
+		{&models.Person{FirstName: "Ivan", LastName: "Petrova"}, nil},
+		{&models.Person{FirstName: "Anna", LastName: "Smirnova"}, []string{"anna@example.com"}},
+		{&models.Person{FirstName: "Oleg", LastName: "Sidorov"}, []string{"ANNA@example.com"}},
+	}
+	for _, p := range people {
+		if err := repo.CreateWithTransaction(ctx, p.person, p.emails); err != nil {
+			t.Fatalf("create %s: %v", p.person.FirstName, err)
+		}
+	}
+
+	all, err := repo.FindDuplicatePairs(ctx, 0.3, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) < 2 {
+		t.Fatalf("pairs = %+v, want the name and the email pair", all)
+	}
+	for i := 1; i < len(all); i++ {
+		if all[i].Score > all[i-1].Score {
+			t.Errorf("pairs are not ordered by score: %+v", all)
+		}
+	}
+
+	// Лимит оставляет самую вероятную пару, сколько бы раз ни повторялся запрос
+	for i := 0; i < 5; i++ {
+		top, err := repo.FindDuplicatePairs(ctx, 0.3, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(top) != 1 || top[0].PersonID != all[0].PersonID || top[0].OtherID != all[0].OtherID || top[0].Reason != models.DuplicateReasonEmail {
+			t.Fatalf("top pair = %+v, want the shared email pair %+v", top, all[0])
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package repository
+
+import (
+	"PeopleCRUD/internal/models"
+	"PeopleCRUD/pkg/errors"
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/lib/pq"
+)
+
+// Сколько строк забирать с сервера за один FETCH
+const exportFetchSize = 1000
+
+// StreamPeople читает людей по фильтру через серверный курсор порциями по exportFetchSize
+// и передает каждую строку в fn. В памяти одновременно находится не больше одной порции.
+func (r *personRepository) StreamPeople(ctx context.Context, filter models.PeopleFilter, fn func(*models.PersonExport) error) error {
+	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return errors.NewInternalServerError("Failed to begin export transaction")
+	}
+	defer tx.Rollback()
+
+	where, args := peopleFilterClause(ctx, filter, "p.", nil)
+	query := fmt.Sprintf(`
+		DECLARE people_export NO SCROLL CURSOR FOR
+		SELECT p.id, p.first_name, p.last_name, p.middle_name, p.age, p.gender, p.nationality, p.created_at, p.updated_at,
+			ARRAY(SELECT e.email FROM emails e WHERE e.person_id = p.id ORDER BY e.is_primary DESC, e.id),
+			ARRAY(SELECT f.related_id FROM relationships f JOIN people fp ON fp.id = f.related_id
+				WHERE f.person_id = p.id AND f.type = 'friend' AND f.status = 'accepted' AND fp.deleted_at IS NULL
+				ORDER BY f.related_id)
+		FROM people p
+		WHERE %s
+		ORDER BY p.id`, where)
+
+	if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+		return errors.NewInternalServerError("Failed to open export cursor")
+	}
+
+	fetch := fmt.Sprintf("FETCH %d FROM people_export", exportFetchSize)
+	for {
+		fetched, err := r.fetchExportBatch(ctx, tx, fetch, fn)
+		if err != nil {
+			return err
+		}
+		if fetched < exportFetchSize {
+			return nil
+		}
+	}
+}
+
+func (r *personRepository) fetchExportBatch(ctx context.Context, tx *sql.Tx, fetch string, fn func(*models.PersonExport) error) (int, error) {
+	rows, err := tx.QueryContext(ctx, fetch)
+	if err != nil {
+		return 0, errors.NewInternalServerError("Failed to fetch export rows")
+	}
+	defer rows.Close()
+
+	fetched := 0
+	for rows.Next() {
+		var row models.PersonExport
+		var emails pq.StringArray
+		var friendIDs pq.Int64Array
+		err := rows.Scan(
+			&row.ID, &row.FirstName, &row.LastName, &row.MiddleName,
+			&row.Age, &row.Gender, &row.Nationality, &row.CreatedAt, &row.UpdatedAt,
+			&emails, &friendIDs,
+		)
+		if err != nil {
+			return fetched, errors.NewInternalServerError("Failed to scan export row")
+		}
+		row.Emails = emails
+		row.FriendIDs = friendIDs
+
+		if err := fn(&row); err != nil {
+			return fetched, err
+		}
+		fetched++
+	}
+
+	if err := rows.Err(); err != nil {
+		return fetched, errors.NewInternalServerError("Failed to read export rows")
+	}
+	return fetched, nil
+}
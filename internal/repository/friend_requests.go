@@ -0,0 +1,162 @@
+package repository
+
+import (
+	"PeopleCRUD/internal/models"
+	"PeopleCRUD/internal/reqctx"
+	"PeopleCRUD/pkg/errors"
+	"context"
+	"database/sql"
+)
+
+// GetFriendshipStatus возвращает статус связи person_id -> friend_id или "" если ее нет
+func (r *personRepository) GetFriendshipStatus(ctx context.Context, personID, friendID int) (string, error) {
+	query := `SELECT status FROM relationships WHERE person_id = $1 AND related_id = $2 AND type = 'friend' AND tenant_id = $3`
+
+	var status string
+	err := r.db.QueryRowContext(ctx, query, personID, friendID, reqctx.Tenant(ctx)).Scan(&status)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", nil
+		}
+		return "", errors.NewInternalServerError("Failed to get friendship status")
+	}
+	return status, nil
+}
+
+// CreateFriendRequest создает заявку или переоткрывает ранее отклоненную
+func (r *personRepository) CreateFriendRequest(ctx context.Context, fromID, toID int) error {
+	query := `
+		INSERT INTO relationships (person_id, related_id, type, status, tenant_id) VALUES ($1, $2, 'friend', 'pending', $3)
+		ON CONFLICT (person_id, related_id, type) DO UPDATE
+			SET status = 'pending', created_at = CURRENT_TIMESTAMP, responded_at = NULL
+			WHERE relationships.status = 'rejected'`
+
+	result, err := r.db.ExecContext(ctx, query, fromID, toID, reqctx.Tenant(ctx))
+	if err != nil {
+		return errors.NewInternalServerError("Failed to create friend request")
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return errors.NewInternalServerError("Failed to get rows affected")
+	}
+
+	if rowsAffected == 0 {
+		return errors.NewConflictError("Friend request already exists")
+	}
+
+	return nil
+}
+
+// AcceptFriendRequest принимает заявку fromID -> toID и создает обратную связь в одной транзакции
+func (r *personRepository) AcceptFriendRequest(ctx context.Context, fromID, toID int) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return errors.NewInternalServerError("Failed to begin transaction")
+	}
+	defer tx.Rollback()
+
+	query := `
+		UPDATE relationships SET status = 'accepted', responded_at = CURRENT_TIMESTAMP
+		WHERE person_id = $1 AND related_id = $2 AND type = 'friend' AND status = 'pending' AND tenant_id = $3`
+
+	result, err := tx.ExecContext(ctx, query, fromID, toID, reqctx.Tenant(ctx))
+	if err != nil {
+		return errors.NewInternalServerError("Failed to accept friend request")
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return errors.NewInternalServerError("Failed to get rows affected")
+	}
+
+	if rowsAffected == 0 {
+		return errors.NewNotFoundError("Friend request not found")
+	}
+
+	reverseQuery := `
+		INSERT INTO relationships (person_id, related_id, type, status, responded_at, tenant_id)
+		VALUES ($1, $2, 'friend', 'accepted', CURRENT_TIMESTAMP, $3)
+		ON CONFLICT (person_id, related_id, type) DO UPDATE SET status = 'accepted', responded_at = CURRENT_TIMESTAMP`
+	if _, err := tx.ExecContext(ctx, reverseQuery, toID, fromID, reqctx.Tenant(ctx)); err != nil {
+		return errors.NewInternalServerError("Failed to add reciprocal friendship")
+	}
+
+	if err := tx.Commit(); err != nil {
+		return errors.NewInternalServerError("Failed to commit transaction")
+	}
+	return nil
+}
+
+func (r *personRepository) RejectFriendRequest(ctx context.Context, fromID, toID int) error {
+	query := `
+		UPDATE relationships SET status = 'rejected', responded_at = CURRENT_TIMESTAMP
+		WHERE person_id = $1 AND related_id = $2 AND type = 'friend' AND status = 'pending' AND tenant_id = $3`
+
+	return r.execFriendRequest(ctx, query, fromID, toID, "Failed to reject friend request")
+}
+
+func (r *personRepository) CancelFriendRequest(ctx context.Context, fromID, toID int) error {
+	query := `
+		DELETE FROM relationships
+		WHERE person_id = $1 AND related_id = $2 AND type = 'friend' AND status = 'pending' AND tenant_id = $3`
+
+	return r.execFriendRequest(ctx, query, fromID, toID, "Failed to cancel friend request")
+}
+
+func (r *personRepository) execFriendRequest(ctx context.Context, query string, fromID, toID int, failure string) error {
+	result, err := r.db.ExecContext(ctx, query, fromID, toID, reqctx.Tenant(ctx))
+	if err != nil {
+		return errors.NewInternalServerError(failure)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return errors.NewInternalServerError("Failed to get rows affected")
+	}
+
+	if rowsAffected == 0 {
+		return errors.NewNotFoundError("Friend request not found")
+	}
+
+	return nil
+}
+
+// GetFriendRequests возвращает ожидающие заявки: входящие (к personID) или исходящие (от personID)
+func (r *personRepository) GetFriendRequests(ctx context.Context, personID int, incoming bool) ([]models.FriendRequest, error) {
+	self, other := "f.person_id", "f.related_id"
+	if incoming {
+		self, other = other, self
+	}
+
+	query := `
+		SELECT f.person_id, f.related_id, f.status, f.created_at, f.responded_at,
+			p.id, p.first_name, p.last_name, p.middle_name, p.age, p.gender, p.nationality, p.created_at, p.updated_at
+		FROM relationships f
+		JOIN people p ON p.id = ` + other + ` AND p.deleted_at IS NULL
+		WHERE ` + self + ` = $1 AND f.type = 'friend' AND f.status = 'pending' AND f.tenant_id = $2
+		ORDER BY f.created_at DESC`
+
+	rows, err := r.db.QueryContext(ctx, query, personID, reqctx.Tenant(ctx))
+	if err != nil {
+		return nil, errors.NewInternalServerError("Failed to get friend requests")
+	}
+	defer rows.Close()
+
+	var requests []models.FriendRequest
+	for rows.Next() {
+		var request models.FriendRequest
+		person := &request.Person
+		err := rows.Scan(
+			&request.FromID, &request.ToID, &request.Status, &request.CreatedAt, &request.RespondedAt,
+			&person.ID, &person.FirstName, &person.LastName, &person.MiddleName,
+			&person.Age, &person.Gender, &person.Nationality, &person.CreatedAt, &person.UpdatedAt,
+		)
+		if err != nil {
+			return nil, errors.NewInternalServerError("Failed to scan friend request")
+		}
+		requests = append(requests, request)
+	}
+
+	return requests, nil
+}
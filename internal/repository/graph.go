@@ -0,0 +1,314 @@
+package repository
+
+import (
+	"PeopleCRUD/internal/models"
+	"PeopleCRUD/internal/reqctx"
+	"PeopleCRUD/pkg/errors"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/lib/pq"
+)
+
+func (r *personRepository) GetMutualFriends(ctx context.Context, personID, otherID int) ([]models.Person, error) {
+	query := `
+		SELECT p.id, p.first_name, p.last_name, p.middle_name, p.age, p.gender, p.nationality, p.created_at, p.updated_at
+		FROM people p
+		JOIN relationships a ON a.related_id = p.id AND a.person_id = $1 AND a.type = 'friend' AND a.status = 'accepted'
+		JOIN relationships b ON b.related_id = p.id AND b.person_id = $2 AND b.type = 'friend' AND b.status = 'accepted'
+		WHERE p.tenant_id = $3 AND p.deleted_at IS NULL
+		ORDER BY p.id`
+
+	rows, err := r.db.QueryContext(ctx, query, personID, otherID, reqctx.Tenant(ctx))
+	if err != nil {
+		return nil, errors.NewInternalServerError("Failed to get mutual friends")
+	}
+	defer rows.Close()
+
+	var friends []models.Person
+	for rows.Next() {
+		person := models.Person{}
+		err := rows.Scan(
+			&person.ID, &person.FirstName, &person.LastName, &person.MiddleName,
+			&person.Age, &person.Gender, &person.Nationality, &person.CreatedAt, &person.UpdatedAt,
+		)
+		if err != nil {
+			return nil, errors.NewInternalServerError("Failed to scan friend")
+		}
+		friends = append(friends, person)
+	}
+
+	return friends, nil
+}
+
+// FindFriendPath ищет кратчайшую цепочку дружбы из personID в otherID длиной не больше maxDepth.
+// Обход в ширину по уровням: каждый уровень - один запрос друзей текущего фронта, кроме уже посещенных,
+// поэтому каждый человек рассматривается один раз, а не на каждом пути к нему. nil - цепочки нет
+func (r *personRepository) FindFriendPath(ctx context.Context, personID, otherID, maxDepth int) ([]int, error) {
+	if personID == otherID {
+		return []int{personID}, nil
+	}
+
+	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true, Isolation: sql.LevelRepeatableRead})
+	if err != nil {
+		return nil, errors.NewInternalServerError("Failed to begin friend path transaction")
+	}
+	defer tx.Rollback()
+
+	query := `
+		SELECT DISTINCT ON (f.related_id) f.related_id, f.person_id
+		FROM relationships f
+		JOIN people p ON p.id = f.related_id AND p.tenant_id = $3 AND p.deleted_at IS NULL
+		WHERE f.person_id = ANY($1::int[]) AND f.type = 'friend' AND f.status = 'accepted' AND f.tenant_id = $3
+			AND f.related_id <> ALL($2::int[])
+		ORDER BY f.related_id, f.person_id`
+
+	tenant := reqctx.Tenant(ctx)
+	parent := map[int64]int64{int64(personID): 0}
+	visited := pq.Int64Array{int64(personID)}
+	frontier := pq.Int64Array{int64(personID)}
+
+	for depth := 0; depth < maxDepth && len(frontier) > 0; depth++ {
+		rows, err := tx.QueryContext(ctx, query, frontier, visited, tenant)
+		if err != nil {
+			return nil, errors.NewInternalServerError("Failed to find friend path")
+		}
+
+		next := pq.Int64Array{}
+		for rows.Next() {
+			var id, from int64
+			if err := rows.Scan(&id, &from); err != nil {
+				rows.Close()
+				return nil, errors.NewInternalServerError("Failed to scan friend path step")
+			}
+			parent[id] = from
+			next = append(next, id)
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return nil, errors.NewInternalServerError("Failed to find friend path")
+		}
+
+		if _, found := parent[int64(otherID)]; found {
+			return friendPath(parent, int64(personID), int64(otherID)), nil
+		}
+		visited = append(visited, next...)
+		frontier = next
+	}
+
+	return nil, nil
+}
+
+// friendPath восстанавливает цепочку from -> to по родителям из обхода
+func friendPath(parent map[int64]int64, from, to int64) []int {
+	var path []int
+	for id := to; id != from; id = parent[id] {
+		path = append(path, int(id))
+	}
+	path = append(path, int(from))
+
+	for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+		path[i], path[j] = path[j], path[i]
+	}
+	return path
+}
+
+// GetFriendSuggestions ранжирует друзей друзей по числу общих друзей. Люди, с которыми у personID
+// уже есть дружба или заявка в любом статусе, не предлагаются
+func (r *personRepository) GetFriendSuggestions(ctx context.Context, personID, limit int) ([]models.FriendSuggestion, error) {
+	query := `
+		SELECT p.id, p.first_name, p.last_name, p.middle_name, p.age, p.gender, p.nationality, p.created_at, p.updated_at,
+			COUNT(*) AS mutual
+		FROM relationships f1
+		JOIN people m ON m.id = f1.related_id AND m.deleted_at IS NULL
+		JOIN relationships f2 ON f2.person_id = f1.related_id AND f2.type = 'friend' AND f2.status = 'accepted'
+		JOIN people p ON p.id = f2.related_id AND p.deleted_at IS NULL
+		WHERE f1.person_id = $1 AND f1.type = 'friend' AND f1.status = 'accepted' AND f1.tenant_id = $3
+			AND f2.related_id <> $1
+			AND NOT EXISTS (
+				SELECT 1 FROM relationships x
+				WHERE x.person_id = $1 AND x.related_id = f2.related_id AND x.type = 'friend'
+			)
+		GROUP BY p.id
+		ORDER BY mutual DESC, p.id
+		LIMIT $2`
+
+	rows, err := r.db.QueryContext(ctx, query, personID, limit, reqctx.Tenant(ctx))
+	if err != nil {
+		return nil, errors.NewInternalServerError("Failed to get friend suggestions")
+	}
+	defer rows.Close()
+
+	var suggestions []models.FriendSuggestion
+	for rows.Next() {
+		var suggestion models.FriendSuggestion
+		person := &suggestion.Person
+		err := rows.Scan(
+			&person.ID, &person.FirstName, &person.LastName, &person.MiddleName,
+			&person.Age, &person.Gender, &person.Nationality, &person.CreatedAt, &person.UpdatedAt,
+			&suggestion.MutualFriends,
+		)
+		if err != nil {
+			return nil, errors.NewInternalServerError("Failed to scan friend suggestion")
+		}
+		suggestions = append(suggestions, suggestion)
+	}
+
+	return suggestions, nil
+}
+
+// StreamGraph выдает в nodeFn живых людей, затем в edgeFn дружбы между ними (каждую пару один раз).
+// Если egoID задан, граф ограничен людьми не дальше depth шагов дружбы от него.
+// Обе выборки идут через курсоры в одной транзакции, поэтому ребра согласованы с вершинами.
+func (r *personRepository) StreamGraph(ctx context.Context, egoID *int, depth int, nodeFn func(*models.Person) error, edgeFn func(*models.GraphEdge) error) error {
+	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true, Isolation: sql.LevelRepeatableRead})
+	if err != nil {
+		return errors.NewInternalServerError("Failed to begin graph export transaction")
+	}
+	defer tx.Rollback()
+
+	tenant := reqctx.Tenant(ctx)
+
+	// NULL - весь граф тенанта
+	var members interface{}
+	if egoID != nil {
+		ids, err := egoNetwork(ctx, tx, tenant, *egoID, depth)
+		if err != nil {
+			return err
+		}
+		members = pq.Array(ids)
+	}
+
+	nodeQuery := `
+		DECLARE graph_nodes NO SCROLL CURSOR FOR
+		SELECT id, first_name, last_name, middle_name, age, gender, nationality, created_at, updated_at
+		FROM people
+		WHERE tenant_id = $2 AND deleted_at IS NULL AND ($1::int[] IS NULL OR id = ANY($1::int[]))
+		ORDER BY id`
+
+	err = streamCursor(ctx, tx, "graph_nodes", nodeQuery, []interface{}{members, tenant}, func(rows *sql.Rows) error {
+		person := models.Person{}
+		err := rows.Scan(
+			&person.ID, &person.FirstName, &person.LastName, &person.MiddleName,
+			&person.Age, &person.Gender, &person.Nationality, &person.CreatedAt, &person.UpdatedAt,
+		)
+		if err != nil {
+			return errors.NewInternalServerError("Failed to scan graph node")
+		}
+		return nodeFn(&person)
+	})
+	if err != nil {
+		return err
+	}
+
+	edgeQuery := `
+		DECLARE graph_edges NO SCROLL CURSOR FOR
+		SELECT f.person_id, f.related_id, f.weight
+		FROM relationships f
+		JOIN people a ON a.id = f.person_id AND a.deleted_at IS NULL
+		JOIN people b ON b.id = f.related_id AND b.deleted_at IS NULL
+		WHERE f.tenant_id = $2 AND f.type = 'friend' AND f.status = 'accepted' AND f.person_id < f.related_id
+			AND ($1::int[] IS NULL OR (f.person_id = ANY($1::int[]) AND f.related_id = ANY($1::int[])))
+		ORDER BY f.person_id, f.related_id`
+
+	return streamCursor(ctx, tx, "graph_edges", edgeQuery, []interface{}{members, tenant}, func(rows *sql.Rows) error {
+		edge := models.GraphEdge{}
+		if err := rows.Scan(&edge.Source, &edge.Target, &edge.Weight); err != nil {
+			return errors.NewInternalServerError("Failed to scan graph edge")
+		}
+		return edgeFn(&edge)
+	})
+}
+
+// egoNetwork возвращает id живых людей тенанта не дальше depth шагов дружбы от egoID, включая его самого
+func egoNetwork(ctx context.Context, tx *sql.Tx, tenant string, egoID, depth int) ([]int64, error) {
+	query := `
+		WITH RECURSIVE ego(id, depth) AS (
+			SELECT $1::int, 0
+			UNION
+			SELECT f.related_id, e.depth + 1
+			FROM ego e
+			JOIN relationships f ON f.person_id = e.id AND f.type = 'friend' AND f.status = 'accepted' AND f.tenant_id = $3
+			JOIN people p ON p.id = f.related_id AND p.deleted_at IS NULL
+			WHERE e.depth < $2::int
+		)
+		SELECT COALESCE(array_agg(DISTINCT id), '{}') FROM ego`
+
+	var ids pq.Int64Array
+	if err := tx.QueryRowContext(ctx, query, egoID, depth, tenant).Scan(&ids); err != nil {
+		return nil, errors.NewInternalServerError("Failed to build ego network")
+	}
+	return ids, nil
+}
+
+// streamCursor открывает курсор запросом declare и читает его порциями по exportFetchSize
+func streamCursor(ctx context.Context, tx *sql.Tx, name, declare string, args []interface{}, fn func(*sql.Rows) error) error {
+	if _, err := tx.ExecContext(ctx, declare, args...); err != nil {
+		return errors.NewInternalServerError("Failed to open cursor " + name)
+	}
+
+	fetch := fmt.Sprintf("FETCH %d FROM %s", exportFetchSize, name)
+	for {
+		rows, err := tx.QueryContext(ctx, fetch)
+		if err != nil {
+			return errors.NewInternalServerError("Failed to fetch rows from " + name)
+		}
+
+		fetched := 0
+		for rows.Next() {
+			if err := fn(rows); err != nil {
+				rows.Close()
+				return err
+			}
+			fetched++
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return errors.NewInternalServerError("Failed to read rows from " + name)
+		}
+
+		if fetched < exportFetchSize {
+			return nil
+		}
+	}
+}
+
+// SaveGraphAnalytics заменяет сохраненный результат аналитики графа тенанта
+func (r *personRepository) SaveGraphAnalytics(ctx context.Context, result *models.GraphAnalytics) error {
+	data, err := json.Marshal(result)
+	if err != nil {
+		return errors.NewInternalServerError("Failed to encode graph analytics")
+	}
+
+	query := `
+		INSERT INTO graph_analytics (tenant_id, result, computed_at) VALUES ($1, $2, $3)
+		ON CONFLICT (tenant_id) DO UPDATE SET result = EXCLUDED.result, computed_at = EXCLUDED.computed_at`
+
+	if _, err := r.db.ExecContext(ctx, query, reqctx.Tenant(ctx), data, result.ComputedAt.UTC()); err != nil {
+		return errors.NewInternalServerError("Failed to save graph analytics")
+	}
+	return nil
+}
+
+// GetGraphAnalytics возвращает последний сохраненный результат аналитики графа тенанта
+func (r *personRepository) GetGraphAnalytics(ctx context.Context) (*models.GraphAnalytics, error) {
+	query := `SELECT result FROM graph_analytics WHERE tenant_id = $1`
+
+	var data []byte
+	if err := r.db.QueryRowContext(ctx, query, reqctx.Tenant(ctx)).Scan(&data); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.NewNotFoundError("Graph analytics not found")
+		}
+		return nil, errors.NewInternalServerError("Failed to get graph analytics")
+	}
+
+	var result models.GraphAnalytics
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, errors.NewInternalServerError("Failed to decode graph analytics")
+	}
+	return &result, nil
+}
@@ -0,0 +1,141 @@
+package repository
+
+import (
+	"PeopleCRUD/internal/models"
+	"PeopleCRUD/internal/reqctx"
+	"PeopleCRUD/pkg/errors"
+	"context"
+	"database/sql"
+	"time"
+)
+
+// Чтение версионированной истории, которую ведут триггеры *_history_changes.
+// Тенант хранится только в people_history, история email'ов и связей проверяется через нее
+
+func (r *personRepository) GetByIDAsOf(ctx context.Context, id int, asOf time.Time) (*models.Person, error) {
+	query := `
+		SELECT person_id, first_name, last_name, middle_name, age, gender, nationality, created_at, updated_at
+		FROM people_history
+		WHERE person_id = $1 AND tenant_id = $3 AND valid_from <= $2 AND (valid_to IS NULL OR valid_to > $2)
+			AND (deleted_at IS NULL OR deleted_at > $2)`
+
+	person := &models.Person{}
+	err := r.db.QueryRowContext(ctx, query, id, asOf, reqctx.Tenant(ctx)).Scan(
+		&person.ID, &person.FirstName, &person.LastName, &person.MiddleName,
+		&person.Age, &person.Gender, &person.Nationality, &person.CreatedAt, &person.UpdatedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.NewNotFoundError("Person did not exist at the requested time")
+		}
+		return nil, errors.NewInternalServerError("Failed to get person history")
+	}
+	return person, nil
+}
+
+func (r *personRepository) GetEmailsAsOf(ctx context.Context, personID int, asOf time.Time) ([]models.Email, error) {
+	query := `
+		SELECT email_id, person_id, email, is_primary
+		FROM emails_history
+		WHERE person_id = $1 AND valid_from <= $2 AND (valid_to IS NULL OR valid_to > $2)
+			AND EXISTS (SELECT 1 FROM people_history h WHERE h.person_id = $1 AND h.tenant_id = $3)
+		ORDER BY email_id`
+
+	rows, err := r.db.QueryContext(ctx, query, personID, asOf, reqctx.Tenant(ctx))
+	if err != nil {
+		return nil, errors.NewInternalServerError("Failed to get email history")
+	}
+	defer rows.Close()
+
+	var emails []models.Email
+	for rows.Next() {
+		var email models.Email
+		if err := rows.Scan(&email.ID, &email.PersonID, &email.Email, &email.IsPrimary); err != nil {
+			return nil, errors.NewInternalServerError("Failed to scan email")
+		}
+		emails = append(emails, email)
+	}
+
+	return emails, nil
+}
+
+func (r *personRepository) GetFriendsAsOf(ctx context.Context, personID int, asOf time.Time) ([]models.Person, error) {
+	query := `
+		SELECT p.person_id, p.first_name, p.last_name, p.middle_name, p.age, p.gender, p.nationality, p.created_at, p.updated_at
+		FROM relationships_history f
+		JOIN people_history p ON p.person_id = f.related_id AND p.tenant_id = $3
+			AND p.valid_from <= $2 AND (p.valid_to IS NULL OR p.valid_to > $2)
+			AND (p.deleted_at IS NULL OR p.deleted_at > $2)
+		WHERE f.person_id = $1 AND f.type = 'friend' AND f.valid_from <= $2 AND (f.valid_to IS NULL OR f.valid_to > $2)`
+
+	rows, err := r.db.QueryContext(ctx, query, personID, asOf, reqctx.Tenant(ctx))
+	if err != nil {
+		return nil, errors.NewInternalServerError("Failed to get friendship history")
+	}
+	defer rows.Close()
+
+	var friends []models.Person
+	for rows.Next() {
+		person := models.Person{}
+		err := rows.Scan(
+			&person.ID, &person.FirstName, &person.LastName, &person.MiddleName,
+			&person.Age, &person.Gender, &person.Nationality, &person.CreatedAt, &person.UpdatedAt,
+		)
+		if err != nil {
+			return nil, errors.NewInternalServerError("Failed to scan friend")
+		}
+		friends = append(friends, person)
+	}
+
+	return friends, nil
+}
+
+func (r *personRepository) GetVersions(ctx context.Context, personID int) ([]models.PersonVersion, error) {
+	query := `
+		SELECT version, person_id, first_name, last_name, middle_name, age, gender, nationality,
+			created_at, updated_at, deleted_at, valid_from, valid_to
+		FROM people_history WHERE person_id = $1 AND tenant_id = $2 ORDER BY version`
+
+	rows, err := r.db.QueryContext(ctx, query, personID, reqctx.Tenant(ctx))
+	if err != nil {
+		return nil, errors.NewInternalServerError("Failed to get person versions")
+	}
+	defer rows.Close()
+
+	var versions []models.PersonVersion
+	for rows.Next() {
+		var v models.PersonVersion
+		err := rows.Scan(
+			&v.Version, &v.Person.ID, &v.Person.FirstName, &v.Person.LastName, &v.Person.MiddleName,
+			&v.Person.Age, &v.Person.Gender, &v.Person.Nationality, &v.Person.CreatedAt, &v.Person.UpdatedAt,
+			&v.Person.DeletedAt, &v.ValidFrom, &v.ValidTo,
+		)
+		if err != nil {
+			return nil, errors.NewInternalServerError("Failed to scan person version")
+		}
+		versions = append(versions, v)
+	}
+
+	return versions, nil
+}
+
+func (r *personRepository) GetVersion(ctx context.Context, personID, version int) (*models.PersonVersion, error) {
+	query := `
+		SELECT version, person_id, first_name, last_name, middle_name, age, gender, nationality,
+			created_at, updated_at, deleted_at, valid_from, valid_to
+		FROM people_history WHERE person_id = $1 AND version = $2 AND tenant_id = $3`
+
+	var v models.PersonVersion
+	err := r.db.QueryRowContext(ctx, query, personID, version, reqctx.Tenant(ctx)).Scan(
+		&v.Version, &v.Person.ID, &v.Person.FirstName, &v.Person.LastName, &v.Person.MiddleName,
+		&v.Person.Age, &v.Person.Gender, &v.Person.Nationality, &v.Person.CreatedAt, &v.Person.UpdatedAt,
+		&v.Person.DeletedAt, &v.ValidFrom, &v.ValidTo,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.NewNotFoundError("Person version not found")
+		}
+		return nil, errors.NewInternalServerError("Failed to get person version")
+	}
+	return &v, nil
+}
@@ -0,0 +1,108 @@
+package repository
+
+import (
+	"PeopleCRUD/internal/models"
+	"PeopleCRUD/pkg/errors"
+	"context"
+	"database/sql"
+	"time"
+)
+
+// IdempotencyRepository хранит ответы на запросы с ключом идемпотентности
+type IdempotencyRepository interface {
+	// Begin занимает ключ под запрос с fingerprint на lockTTL. false - ключ уже занят или хранит ответ
+	Begin(ctx context.Context, scope, key, fingerprint string, lockTTL time.Duration) (bool, error)
+	// Get возвращает запись по ключу или nil, если ее нет или она истекла
+	Get(ctx context.Context, scope, key string) (*models.IdempotencyRecord, error)
+	// Complete сохраняет ответ на ttl
+	Complete(ctx context.Context, scope, key string, record *models.IdempotencyRecord, ttl time.Duration) error
+	// Release освобождает ключ без ответа, чтобы повтор выполнил запрос заново
+	Release(ctx context.Context, scope, key string) error
+	DeleteExpired(ctx context.Context) (int, error)
+}
+
+type idempotencyRepository struct {
+	db *sql.DB
+}
+
+func NewIdempotencyRepository(db *sql.DB) IdempotencyRepository {
+	return &idempotencyRepository{db: db}
+}
+
+// Begin вставляет запись или перезанимает истекшую: ответ и блокировка с прошедшим сроком не мешают новому запросу
+func (r *idempotencyRepository) Begin(ctx context.Context, scope, key, fingerprint string, lockTTL time.Duration) (bool, error) {
+	query := `
+		INSERT INTO idempotency_keys (scope, idempotency_key, fingerprint, expires_at)
+		VALUES ($1, $2, $3, CURRENT_TIMESTAMP + make_interval(secs => $4::float8))
+		ON CONFLICT (scope, idempotency_key) DO UPDATE SET
+			fingerprint = EXCLUDED.fingerprint, status_code = NULL, content_type = NULL, body = NULL,
+			created_at = CURRENT_TIMESTAMP, expires_at = EXCLUDED.expires_at
+			WHERE idempotency_keys.expires_at < CURRENT_TIMESTAMP`
+
+	result, err := r.db.ExecContext(ctx, query, scope, key, fingerprint, lockTTL.Seconds())
+	if err != nil {
+		return false, errors.NewInternalServerError("Failed to lock idempotency key")
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, errors.NewInternalServerError("Failed to get rows affected")
+	}
+	return rowsAffected > 0, nil
+}
+
+func (r *idempotencyRepository) Get(ctx context.Context, scope, key string) (*models.IdempotencyRecord, error) {
+	query := `
+		SELECT fingerprint, status_code, COALESCE(content_type, ''), body
+		FROM idempotency_keys
+		WHERE scope = $1 AND idempotency_key = $2 AND expires_at >= CURRENT_TIMESTAMP`
+
+	var record models.IdempotencyRecord
+	var statusCode sql.NullInt64
+	err := r.db.QueryRowContext(ctx, query, scope, key).Scan(&record.Fingerprint, &statusCode, &record.ContentType, &record.Body)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, errors.NewInternalServerError("Failed to get idempotency key")
+	}
+	record.StatusCode = int(statusCode.Int64)
+	return &record, nil
+}
+
+func (r *idempotencyRepository) Complete(ctx context.Context, scope, key string, record *models.IdempotencyRecord, ttl time.Duration) error {
+	query := `
+		UPDATE idempotency_keys SET
+			status_code = $4, content_type = NULLIF($5, ''), body = $6,
+			expires_at = CURRENT_TIMESTAMP + make_interval(secs => $7::float8)
+		WHERE scope = $1 AND idempotency_key = $2 AND fingerprint = $3 AND status_code IS NULL`
+
+	_, err := r.db.ExecContext(ctx, query, scope, key, record.Fingerprint, record.StatusCode, record.ContentType,
+		record.Body, ttl.Seconds())
+	if err != nil {
+		return errors.NewInternalServerError("Failed to save idempotent response")
+	}
+	return nil
+}
+
+func (r *idempotencyRepository) Release(ctx context.Context, scope, key string) error {
+	query := `DELETE FROM idempotency_keys WHERE scope = $1 AND idempotency_key = $2 AND status_code IS NULL`
+
+	if _, err := r.db.ExecContext(ctx, query, scope, key); err != nil {
+		return errors.NewInternalServerError("Failed to release idempotency key")
+	}
+	return nil
+}
+
+func (r *idempotencyRepository) DeleteExpired(ctx context.Context) (int, error) {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM idempotency_keys WHERE expires_at < CURRENT_TIMESTAMP`)
+	if err != nil {
+		return 0, errors.NewInternalServerError("Failed to delete expired idempotency keys")
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, errors.NewInternalServerError("Failed to get rows affected")
+	}
+	return int(rowsAffected), nil
+}
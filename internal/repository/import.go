@@ -0,0 +1,57 @@
+package repository
+
+import (
+	"PeopleCRUD/internal/models"
+	"PeopleCRUD/internal/reqctx"
+	"PeopleCRUD/pkg/errors"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+)
+
+// SaveImportReport сохраняет отклоненные строки импорта тенанта под id
+func (r *personRepository) SaveImportReport(ctx context.Context, id string, rejections []models.ImportRejection) error {
+	data, err := json.Marshal(rejections)
+	if err != nil {
+		return errors.NewInternalServerError("Failed to encode import report")
+	}
+
+	query := `INSERT INTO import_reports (tenant_id, id, rejections) VALUES ($1, $2, $3)`
+	if _, err := r.db.ExecContext(ctx, query, reqctx.Tenant(ctx), id, data); err != nil {
+		return errors.NewInternalServerError("Failed to save import report")
+	}
+	return nil
+}
+
+func (r *personRepository) GetImportReport(ctx context.Context, id string) ([]models.ImportRejection, error) {
+	query := `SELECT rejections FROM import_reports WHERE tenant_id = $1 AND id = $2`
+
+	var data []byte
+	if err := r.db.QueryRowContext(ctx, query, reqctx.Tenant(ctx), id).Scan(&data); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.NewNotFoundError("Import report not found or expired")
+		}
+		return nil, errors.NewInternalServerError("Failed to get import report")
+	}
+
+	var rejections []models.ImportRejection
+	if err := json.Unmarshal(data, &rejections); err != nil {
+		return nil, errors.NewInternalServerError("Failed to decode import report")
+	}
+	return rejections, nil
+}
+
+// DeleteImportReports удаляет отчеты всех тенантов, созданные раньше before
+func (r *personRepository) DeleteImportReports(ctx context.Context, before time.Time) (int, error) {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM import_reports WHERE created_at < $1`, before)
+	if err != nil {
+		return 0, errors.NewInternalServerError("Failed to delete import reports")
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, errors.NewInternalServerError("Failed to get rows affected")
+	}
+	return int(rowsAffected), nil
+}
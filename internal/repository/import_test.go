@@ -0,0 +1,37 @@
+package repository
+
+import (
+	"PeopleCRUD/internal/models"
+	"PeopleCRUD/internal/reqctx"
+	"context"
+	"testing"
+	"time"
+)
+
+func TestImportReports(t *testing.T) {
+	repo := NewPersonRepository(testDB(t))
+	ctxA := reqctx.WithTenant(context.Background(), "team-a")
+	ctxB := reqctx.WithTenant(context.Background(), "team-b")
+
+	rejections := []models.ImportRejection{{Line: 3, Reason: "first_name is required", Raw: ",Petrov"}}
+	if err := repo.SaveImportReport(ctxA, "0123456789abcdef0123456789abcdef", rejections); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	got, err := repo.GetImportReport(ctxA, "0123456789abcdef0123456789abcdef")
+	if err != nil || len(got) != 1 || got[0] != rejections[0] {
+		t.Fatalf("get: %+v, %v", got, err)
+	}
+	_, err = repo.GetImportReport(ctxB, "0123456789abcdef0123456789abcdef")
+	requireNotFound(t, "GetImportReport of another tenant", err)
+
+	if deleted, err := repo.DeleteImportReports(ctxB, time.Now().UTC().Add(-time.Hour)); err != nil || deleted != 0 {
+		t.Errorf("delete before retention: %d, %v, want nothing deleted", deleted, err)
+	}
+	// Очистка общая для всех тенантов
+	if deleted, err := repo.DeleteImportReports(ctxB, time.Now().UTC().Add(time.Hour)); err != nil || deleted != 1 {
+		t.Errorf("delete after retention: %d, %v, want 1", deleted, err)
+	}
+	_, err = repo.GetImportReport(ctxA, "0123456789abcdef0123456789abcdef")
+	requireNotFound(t, "GetImportReport after cleanup", err)
+}
@@ -0,0 +1,174 @@
+package repository
+
+import (
+	"PeopleCRUD/internal/models"
+	"PeopleCRUD/internal/reqctx"
+	"PeopleCRUD/pkg/errors"
+	"context"
+	"database/sql"
+
+	"github.com/lib/pq"
+)
+
+const relationshipColumns = `
+	r.person_id, r.related_id, r.type, r.weight,
+	to_char(r.started_on, 'YYYY-MM-DD'), to_char(r.ended_on, 'YYYY-MM-DD'), r.created_at`
+
+// CreateRelationship создает связь и ее обратную сторону. Существующую строку, в том числе заявку в друзья, не меняет
+func (r *personRepository) CreateRelationship(ctx context.Context, personID int, req *models.CreateRelationshipRequest) error {
+	weight := 1.0
+	if req.Weight != nil {
+		weight = *req.Weight
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return errors.NewInternalServerError("Failed to begin transaction")
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO relationships (person_id, related_id, type, status, weight, started_on, ended_on, tenant_id)
+		VALUES ($1, $2, $3, 'accepted', $4, $5::date, $6::date, $7)
+		ON CONFLICT (person_id, related_id, type) DO NOTHING`
+
+	sides := [][2]interface{}{
+		{personID, req.RelatedID},
+		{req.RelatedID, personID},
+	}
+	types := []string{req.Type, models.RelationshipInverse[req.Type]}
+
+	for i, side := range sides {
+		result, err := tx.ExecContext(ctx, query, side[0], side[1], types[i], weight, req.StartedOn, req.EndedOn, reqctx.Tenant(ctx))
+		if err != nil {
+			return errors.NewInternalServerError("Failed to create relationship")
+		}
+
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return errors.NewInternalServerError("Failed to get rows affected")
+		}
+
+		if rowsAffected == 0 && i == 0 {
+			return errors.NewConflictError("Relationship already exists")
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return errors.NewInternalServerError("Failed to commit transaction")
+	}
+	return nil
+}
+
+func (r *personRepository) GetRelationship(ctx context.Context, personID, relatedID int, relType string) (*models.Relationship, error) {
+	query := `SELECT ` + relationshipColumns + `
+		FROM relationships r
+		WHERE r.person_id = $1 AND r.related_id = $2 AND r.type = $3 AND r.status = 'accepted' AND r.tenant_id = $4`
+
+	relationship := &models.Relationship{}
+	err := r.db.QueryRowContext(ctx, query, personID, relatedID, relType, reqctx.Tenant(ctx)).Scan(
+		&relationship.PersonID, &relationship.RelatedID, &relationship.Type, &relationship.Weight,
+		&relationship.StartedOn, &relationship.EndedOn, &relationship.CreatedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.NewNotFoundError("Relationship not found")
+		}
+		return nil, errors.NewInternalServerError("Failed to get relationship")
+	}
+
+	relationship.Directed = models.IsDirectedRelationship(relationship.Type)
+	return relationship, nil
+}
+
+// GetRelationships возвращает связи человека с живыми людьми, relType == "" - все типы
+func (r *personRepository) GetRelationships(ctx context.Context, personID int, relType string) ([]models.Relationship, error) {
+	query := `SELECT ` + relationshipColumns + `,
+			p.id, p.first_name, p.last_name, p.middle_name, p.age, p.gender, p.nationality, p.created_at, p.updated_at
+		FROM relationships r
+		JOIN people p ON p.id = r.related_id AND p.deleted_at IS NULL
+		WHERE r.person_id = $1 AND r.tenant_id = $3 AND r.status = 'accepted' AND ($2::text = '' OR r.type = $2::text)
+		ORDER BY r.type, r.weight DESC, r.related_id`
+
+	rows, err := r.db.QueryContext(ctx, query, personID, relType, reqctx.Tenant(ctx))
+	if err != nil {
+		return nil, errors.NewInternalServerError("Failed to get relationships")
+	}
+	defer rows.Close()
+
+	var relationships []models.Relationship
+	for rows.Next() {
+		relationship := models.Relationship{Person: &models.Person{}}
+		person := relationship.Person
+		err := rows.Scan(
+			&relationship.PersonID, &relationship.RelatedID, &relationship.Type, &relationship.Weight,
+			&relationship.StartedOn, &relationship.EndedOn, &relationship.CreatedAt,
+			&person.ID, &person.FirstName, &person.LastName, &person.MiddleName,
+			&person.Age, &person.Gender, &person.Nationality, &person.CreatedAt, &person.UpdatedAt,
+		)
+		if err != nil {
+			return nil, errors.NewInternalServerError("Failed to scan relationship")
+		}
+		relationship.Directed = models.IsDirectedRelationship(relationship.Type)
+		relationships = append(relationships, relationship)
+	}
+
+	return relationships, nil
+}
+
+// UpdateRelationship меняет вес и даты сразу у обеих сторон связи
+func (r *personRepository) UpdateRelationship(ctx context.Context, personID, relatedID int, relType string, req *models.UpdateRelationshipRequest) error {
+	query := `
+		UPDATE relationships SET
+			weight = COALESCE($4::real, weight),
+			started_on = COALESCE($5::date, started_on),
+			ended_on = COALESCE($6::date, ended_on)
+		WHERE status = 'accepted' AND tenant_id = $8
+			AND ((person_id = $1 AND related_id = $2 AND type = $3)
+				OR (person_id = $2 AND related_id = $1 AND type = $7))`
+
+	result, err := r.db.ExecContext(ctx, query, personID, relatedID, relType, req.Weight, req.StartedOn, req.EndedOn,
+		models.RelationshipInverse[relType], reqctx.Tenant(ctx))
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23514" {
+			return errors.NewValidationError("ended_on cannot be before started_on")
+		}
+		return errors.NewInternalServerError("Failed to update relationship")
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return errors.NewInternalServerError("Failed to get rows affected")
+	}
+
+	if rowsAffected == 0 {
+		return errors.NewNotFoundError("Relationship not found")
+	}
+
+	return nil
+}
+
+func (r *personRepository) DeleteRelationship(ctx context.Context, personID, relatedID int, relType string) error {
+	query := `
+		DELETE FROM relationships
+		WHERE status = 'accepted' AND tenant_id = $5
+			AND ((person_id = $1 AND related_id = $2 AND type = $3)
+				OR (person_id = $2 AND related_id = $1 AND type = $4))`
+
+	result, err := r.db.ExecContext(ctx, query, personID, relatedID, relType, models.RelationshipInverse[relType],
+		reqctx.Tenant(ctx))
+	if err != nil {
+		return errors.NewInternalServerError("Failed to delete relationship")
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return errors.NewInternalServerError("Failed to get rows affected")
+	}
+
+	if rowsAffected == 0 {
+		return errors.NewNotFoundError("Relationship not found")
+	}
+
+	return nil
+}
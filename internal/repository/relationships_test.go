@@ -0,0 +1,38 @@
+package repository
+
+import (
+	"PeopleCRUD/internal/models"
+	"PeopleCRUD/internal/reqctx"
+	"PeopleCRUD/pkg/errors"
+	"context"
+	"net/http"
+	"testing"
+)
+
+func TestCreateRelationshipKeepsPendingFriendRequest(t *testing.T) {
+	repo := NewPersonRepository(testDB(t))
+	ctx := reqctx.WithTenant(context.Background(), "team-a")
+
+	alice := &models.Person{FirstName: "Alice", LastName: "Pending"}
+	bob := &models.Person{FirstName: "Bob", LastName: "Pending"}
+	for _, person := range []*models.Person{alice, bob} {
+		if err := repo.Create(ctx, person); err != nil {
+			t.Fatalf("create %s: %v", person.FirstName, err)
+		}
+	}
+	if err := repo.CreateFriendRequest(ctx, alice.ID, bob.ID); err != nil {
+		t.Fatalf("friend request: %v", err)
+	}
+
+	err := repo.CreateRelationship(ctx, alice.ID, &models.CreateRelationshipRequest{RelatedID: bob.ID, Type: models.RelationshipFriend})
+	if appErr, ok := err.(*errors.AppError); !ok || appErr.Code != http.StatusConflict {
+		t.Errorf("CreateRelationship over a pending request: expected 409, got %v", err)
+	}
+
+	if status, err := repo.GetFriendshipStatus(ctx, alice.ID, bob.ID); err != nil || status != models.FriendshipPending {
+		t.Errorf("alice -> bob: %q, %v, want pending", status, err)
+	}
+	if status, err := repo.GetFriendshipStatus(ctx, bob.ID, alice.ID); err != nil || status != "" {
+		t.Errorf("bob -> alice: %q, %v, want no row", status, err)
+	}
+}
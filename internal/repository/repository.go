@@ -2,28 +2,72 @@ package repository
 
 import (
 	"PeopleCRUD/internal/models"
+	"PeopleCRUD/internal/reqctx"
 	"PeopleCRUD/pkg/errors"
+	"context"
 	"database/sql"
 	"fmt"
 	"strings"
+	"time"
 )
 
 type PersonRepository interface {
-	Create(person *models.Person) error
-	CreateWithTransaction(person *models.Person, emails []string) error
-	GetByID(id int) (*models.Person, error)
-	GetByLastName(lastName string) ([]*models.Person, error)
-	GetAll(limit, offset int) ([]*models.Person, error)
-	GetCount() (int, error)
-	Update(id int, req *models.UpdatePersonRequest) error
-	Delete(id int) error
-	AddEmail(personID int, email string, isPrimary bool) error
-	UpdateEmail(emailID int, email string, isPrimary bool) error
-	DeleteEmail(emailID int) error
-	GetEmails(personID int) ([]models.Email, error)
-	AddFriend(personID, friendID int) error
-	RemoveFriend(personID, friendID int) error
-	GetFriends(personID int) ([]models.Person, error)
+	Create(ctx context.Context, person *models.Person) error
+	CreateWithTransaction(ctx context.Context, person *models.Person, emails []string) error
+	GetByID(ctx context.Context, id int) (*models.Person, error)
+	GetByLastName(ctx context.Context, lastName string) ([]*models.Person, error)
+	GetAll(ctx context.Context, filter models.PeopleFilter, limit, offset int) ([]*models.Person, error)
+	GetCount(ctx context.Context, filter models.PeopleFilter) (int, error)
+	Update(ctx context.Context, id int, req *models.UpdatePersonRequest) error
+	Replace(ctx context.Context, id int, person *models.Person) error
+	Delete(ctx context.Context, id int) error
+	AddEmail(ctx context.Context, personID int, email string, isPrimary bool) (int, error)
+	UpdateEmail(ctx context.Context, emailID int, email string, isPrimary bool) error
+	DeleteEmail(ctx context.Context, emailID int) error
+	GetEmails(ctx context.Context, personID int) ([]models.Email, error)
+	AddFriend(ctx context.Context, personID, friendID int) error
+	RemoveFriend(ctx context.Context, personID, friendID int) error
+	GetFriends(ctx context.Context, personID int) ([]models.Person, error)
+	GetDeleted(ctx context.Context, limit, offset int) ([]*models.Person, error)
+	GetDeletedCount(ctx context.Context) (int, error)
+	Restore(ctx context.Context, id int) error
+	PurgeDeleted(ctx context.Context, before time.Time) ([]int, error)
+	GetByIDAsOf(ctx context.Context, id int, asOf time.Time) (*models.Person, error)
+	GetEmailsAsOf(ctx context.Context, personID int, asOf time.Time) ([]models.Email, error)
+	GetFriendsAsOf(ctx context.Context, personID int, asOf time.Time) ([]models.Person, error)
+	GetVersions(ctx context.Context, personID int) ([]models.PersonVersion, error)
+	GetVersion(ctx context.Context, personID, version int) (*models.PersonVersion, error)
+	BulkCreate(ctx context.Context, people []*models.Person, emails [][]string, atomic bool) []error
+	BulkUpdate(ctx context.Context, ids []int, reqs []*models.UpdatePersonRequest, atomic bool) []error
+	BulkDelete(ctx context.Context, ids []int, atomic bool) []error
+	StreamPeople(ctx context.Context, filter models.PeopleFilter, fn func(*models.PersonExport) error) error
+	FindDuplicateCandidates(ctx context.Context, normalizedName string, emails []string, threshold float64, limit int) ([]models.DuplicateCandidate, error)
+	FindDuplicatePairs(ctx context.Context, threshold float64, limit int) ([]models.DuplicatePair, error)
+	GetByIDs(ctx context.Context, ids []int) (map[int]models.Person, error)
+	Merge(ctx context.Context, keepID, otherID int) ([]int, error)
+	GetFriendshipStatus(ctx context.Context, personID, friendID int) (string, error)
+	CreateFriendRequest(ctx context.Context, fromID, toID int) error
+	AcceptFriendRequest(ctx context.Context, fromID, toID int) error
+	RejectFriendRequest(ctx context.Context, fromID, toID int) error
+	CancelFriendRequest(ctx context.Context, fromID, toID int) error
+	GetFriendRequests(ctx context.Context, personID int, incoming bool) ([]models.FriendRequest, error)
+	CreateRelationship(ctx context.Context, personID int, req *models.CreateRelationshipRequest) error
+	GetRelationship(ctx context.Context, personID, relatedID int, relType string) (*models.Relationship, error)
+	GetRelationships(ctx context.Context, personID int, relType string) ([]models.Relationship, error)
+	UpdateRelationship(ctx context.Context, personID, relatedID int, relType string, req *models.UpdateRelationshipRequest) error
+	DeleteRelationship(ctx context.Context, personID, relatedID int, relType string) error
+	GetMutualFriends(ctx context.Context, personID, otherID int) ([]models.Person, error)
+	FindFriendPath(ctx context.Context, personID, otherID, maxDepth int) ([]int, error)
+	GetFriendSuggestions(ctx context.Context, personID, limit int) ([]models.FriendSuggestion, error)
+	StreamGraph(ctx context.Context, egoID *int, depth int, nodeFn func(*models.Person) error, edgeFn func(*models.GraphEdge) error) error
+	SaveGraphAnalytics(ctx context.Context, result *models.GraphAnalytics) error
+	GetGraphAnalytics(ctx context.Context) (*models.GraphAnalytics, error)
+	SaveImportReport(ctx context.Context, id string, rejections []models.ImportRejection) error
+	GetImportReport(ctx context.Context, id string) ([]models.ImportRejection, error)
+	DeleteImportReports(ctx context.Context, before time.Time) (int, error)
+	GetStats(ctx context.Context, filter models.PeopleFilter, ageBuckets []int, period string) (*models.PeopleStats, error)
+	GetTenants(ctx context.Context) ([]string, error)
+	CountAllPeople(ctx context.Context) (int, error)
 }
 
 type personRepository struct {
@@ -34,8 +78,8 @@ func NewPersonRepository(db *sql.DB) PersonRepository {
 	return &personRepository{db: db}
 }
 
-func (r *personRepository) CreateWithTransaction(person *models.Person, emails []string) error {
-	tx, err := r.db.Begin()
+func (r *personRepository) CreateWithTransaction(ctx context.Context, person *models.Person, emails []string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
 	if err != nil {
 		return fmt.Errorf("failed to begin transaction: %w", err)
 	}
@@ -43,12 +87,12 @@ func (r *personRepository) CreateWithTransaction(person *models.Person, emails [
 
 	// Создание человека
 	query := `
-		INSERT INTO people (first_name, last_name, middle_name, age, gender, nationality)
-		VALUES ($1, $2, $3, $4, $5, $6)
+		INSERT INTO people (first_name, last_name, middle_name, age, gender, nationality, tenant_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
 		RETURNING id, created_at, updated_at`
 
-	err = tx.QueryRow(query, person.FirstName, person.LastName, person.MiddleName,
-		person.Age, person.Gender, person.Nationality).Scan(&person.ID, &person.CreatedAt, &person.UpdatedAt)
+	err = tx.QueryRowContext(ctx, query, person.FirstName, person.LastName, person.MiddleName,
+		person.Age, person.Gender, person.Nationality, reqctx.Tenant(ctx)).Scan(&person.ID, &person.CreatedAt, &person.UpdatedAt)
 	if err != nil {
 		return fmt.Errorf("failed to create person: %w", err)
 	}
@@ -56,8 +100,8 @@ func (r *personRepository) CreateWithTransaction(person *models.Person, emails [
 	// Добавление email'ов
 	for i, email := range emails {
 		isPrimary := i == 0
-		emailQuery := `INSERT INTO emails (person_id, email, is_primary) VALUES ($1, $2, $3)`
-		if _, err := tx.Exec(emailQuery, person.ID, email, isPrimary); err != nil {
+		emailQuery := `INSERT INTO emails (person_id, email, is_primary, tenant_id) VALUES ($1, $2, $3, $4)`
+		if _, err := tx.ExecContext(ctx, emailQuery, person.ID, email, isPrimary, reqctx.Tenant(ctx)); err != nil {
 			return fmt.Errorf("failed to add email: %w", err)
 		}
 	}
@@ -65,27 +109,27 @@ func (r *personRepository) CreateWithTransaction(person *models.Person, emails [
 	return tx.Commit()
 }
 
-func (r *personRepository) Create(person *models.Person) error {
+func (r *personRepository) Create(ctx context.Context, person *models.Person) error {
 	query := `
-		INSERT INTO people (first_name, last_name, middle_name, age, gender, nationality)
-		VALUES ($1, $2, $3, $4, $5, $6)
+		INSERT INTO people (first_name, last_name, middle_name, age, gender, nationality, tenant_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
 		RETURNING id, created_at, updated_at`
 
-	err := r.db.QueryRow(query, person.FirstName, person.LastName, person.MiddleName,
-		person.Age, person.Gender, person.Nationality).Scan(&person.ID, &person.CreatedAt, &person.UpdatedAt)
+	err := r.db.QueryRowContext(ctx, query, person.FirstName, person.LastName, person.MiddleName,
+		person.Age, person.Gender, person.Nationality, reqctx.Tenant(ctx)).Scan(&person.ID, &person.CreatedAt, &person.UpdatedAt)
 	if err != nil {
 		return errors.NewInternalServerError("Failed to create person")
 	}
 	return nil
 }
 
-func (r *personRepository) GetByID(id int) (*models.Person, error) {
+func (r *personRepository) GetByID(ctx context.Context, id int) (*models.Person, error) {
 	query := `
 		SELECT id, first_name, last_name, middle_name, age, gender, nationality, created_at, updated_at
-		FROM people WHERE id = $1`
+		FROM people WHERE id = $1 AND tenant_id = $2 AND deleted_at IS NULL`
 
 	person := &models.Person{}
-	err := r.db.QueryRow(query, id).Scan(
+	err := r.db.QueryRowContext(ctx, query, id, reqctx.Tenant(ctx)).Scan(
 		&person.ID, &person.FirstName, &person.LastName, &person.MiddleName,
 		&person.Age, &person.Gender, &person.Nationality, &person.CreatedAt, &person.UpdatedAt,
 	)
@@ -98,8 +142,8 @@ func (r *personRepository) GetByID(id int) (*models.Person, error) {
 	return person, nil
 }
 
-func (r *personRepository) Update(id int, req *models.UpdatePersonRequest) error {
-	_, err := r.GetByID(id)
+func (r *personRepository) Update(ctx context.Context, id int, req *models.UpdatePersonRequest) error {
+	_, err := r.GetByID(ctx, id)
 	if err != nil {
 		return err
 	}
@@ -146,12 +190,12 @@ func (r *personRepository) Update(id int, req *models.UpdatePersonRequest) error
 	query := fmt.Sprintf(`
 		UPDATE people 
 		SET %s, updated_at = CURRENT_TIMESTAMP
-		WHERE id = $%d`,
-		strings.Join(setParts, ", "), argIndex)
+		WHERE id = $%d AND tenant_id = $%d AND deleted_at IS NULL`,
+		strings.Join(setParts, ", "), argIndex, argIndex+1)
 
-	args = append(args, id)
+	args = append(args, id, reqctx.Tenant(ctx))
 
-	_, err = r.db.Exec(query, args...)
+	_, err = r.db.ExecContext(ctx, query, args...)
 	if err != nil {
 		return errors.NewInternalServerError("Failed to update person")
 	}
@@ -159,12 +203,36 @@ func (r *personRepository) Update(id int, req *models.UpdatePersonRequest) error
 	return nil
 }
 
-func (r *personRepository) GetByLastName(lastName string) ([]*models.Person, error) {
+// Replace записывает все поля человека, в том числе пустые как NULL, в отличие от Update
+func (r *personRepository) Replace(ctx context.Context, id int, person *models.Person) error {
+	query := `
+		UPDATE people
+		SET first_name = $1, last_name = $2, middle_name = $3, age = $4, gender = $5, nationality = $6,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = $7 AND tenant_id = $8 AND deleted_at IS NULL`
+
+	result, err := r.db.ExecContext(ctx, query, person.FirstName, person.LastName, person.MiddleName,
+		person.Age, person.Gender, person.Nationality, id, reqctx.Tenant(ctx))
+	if err != nil {
+		return errors.NewInternalServerError("Failed to update person")
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return errors.NewInternalServerError("Failed to update person")
+	}
+	if affected == 0 {
+		return errors.NewNotFoundError("Person not found")
+	}
+	return nil
+}
+
+func (r *personRepository) GetByLastName(ctx context.Context, lastName string) ([]*models.Person, error) {
 	query := `
 		SELECT id, first_name, last_name, middle_name, age, gender, nationality, created_at, updated_at
-		FROM people WHERE last_name = $1`
+		FROM people WHERE last_name = $1 AND tenant_id = $2 AND deleted_at IS NULL`
 
-	rows, err := r.db.Query(query, lastName)
+	rows, err := r.db.QueryContext(ctx, query, lastName, reqctx.Tenant(ctx))
 	if err != nil {
 		return nil, errors.NewInternalServerError("Failed to get people by last name")
 	}
@@ -186,12 +254,13 @@ func (r *personRepository) GetByLastName(lastName string) ([]*models.Person, err
 	return people, nil
 }
 
-func (r *personRepository) GetAll(limit, offset int) ([]*models.Person, error) {
-	query := `
+func (r *personRepository) GetAll(ctx context.Context, filter models.PeopleFilter, limit, offset int) ([]*models.Person, error) {
+	where, args := peopleFilterClause(ctx, filter, "", nil)
+	query := fmt.Sprintf(`
 		SELECT id, first_name, last_name, middle_name, age, gender, nationality, created_at, updated_at
-		FROM people ORDER BY id LIMIT $1 OFFSET $2`
+		FROM people WHERE %s ORDER BY id LIMIT $%d OFFSET $%d`, where, len(args)+1, len(args)+2)
 
-	rows, err := r.db.Query(query, limit, offset)
+	rows, err := r.db.QueryContext(ctx, query, append(args, limit, offset)...)
 	if err != nil {
 		return nil, errors.NewInternalServerError("Failed to get all people")
 	}
@@ -213,11 +282,12 @@ func (r *personRepository) GetAll(limit, offset int) ([]*models.Person, error) {
 	return people, nil
 }
 
-func (r *personRepository) GetCount() (int, error) {
-	query := `SELECT COUNT(*) FROM people`
+func (r *personRepository) GetCount(ctx context.Context, filter models.PeopleFilter) (int, error) {
+	where, args := peopleFilterClause(ctx, filter, "", nil)
+	query := `SELECT COUNT(*) FROM people WHERE ` + where
 
 	var count int
-	err := r.db.QueryRow(query).Scan(&count)
+	err := r.db.QueryRowContext(ctx, query, args...).Scan(&count)
 	if err != nil {
 		return 0, errors.NewInternalServerError("Failed to get people count")
 	}
@@ -225,10 +295,11 @@ func (r *personRepository) GetCount() (int, error) {
 	return count, nil
 }
 
-func (r *personRepository) Delete(id int) error {
-	query := `DELETE FROM people WHERE id = $1`
+func (r *personRepository) Delete(ctx context.Context, id int) error {
+	// Мягкое удаление: email'ы и дружбы остаются на месте до восстановления или очистки
+	query := `UPDATE people SET deleted_at = CURRENT_TIMESTAMP WHERE id = $1 AND tenant_id = $2 AND deleted_at IS NULL`
 
-	result, err := r.db.Exec(query, id)
+	result, err := r.db.ExecContext(ctx, query, id, reqctx.Tenant(ctx))
 	if err != nil {
 		return errors.NewInternalServerError("Failed to delete person")
 	}
@@ -245,21 +316,22 @@ func (r *personRepository) Delete(id int) error {
 	return nil
 }
 
-func (r *personRepository) AddEmail(personID int, email string, isPrimary bool) error {
-	query := `INSERT INTO emails (person_id, email, is_primary) VALUES ($1, $2, $3)`
+func (r *personRepository) AddEmail(ctx context.Context, personID int, email string, isPrimary bool) (int, error) {
+	query := `INSERT INTO emails (person_id, email, is_primary, tenant_id) VALUES ($1, $2, $3, $4) RETURNING id`
 
-	_, err := r.db.Exec(query, personID, email, isPrimary)
+	var id int
+	err := r.db.QueryRowContext(ctx, query, personID, email, isPrimary, reqctx.Tenant(ctx)).Scan(&id)
 	if err != nil {
-		return errors.NewInternalServerError("Failed to add email")
+		return 0, errors.NewInternalServerError("Failed to add email")
 	}
 
-	return nil
+	return id, nil
 }
 
-func (r *personRepository) UpdateEmail(emailID int, email string, isPrimary bool) error {
-	query := `UPDATE emails SET email = $1, is_primary = $2 WHERE id = $3`
+func (r *personRepository) UpdateEmail(ctx context.Context, emailID int, email string, isPrimary bool) error {
+	query := `UPDATE emails SET email = $1, is_primary = $2 WHERE id = $3 AND tenant_id = $4`
 
-	result, err := r.db.Exec(query, email, isPrimary, emailID)
+	result, err := r.db.ExecContext(ctx, query, email, isPrimary, emailID, reqctx.Tenant(ctx))
 	if err != nil {
 		return errors.NewInternalServerError("Failed to update email")
 	}
@@ -276,10 +348,10 @@ func (r *personRepository) UpdateEmail(emailID int, email string, isPrimary bool
 	return nil
 }
 
-func (r *personRepository) DeleteEmail(emailID int) error {
-	query := `DELETE FROM emails WHERE id = $1`
+func (r *personRepository) DeleteEmail(ctx context.Context, emailID int) error {
+	query := `DELETE FROM emails WHERE id = $1 AND tenant_id = $2`
 
-	result, err := r.db.Exec(query, emailID)
+	result, err := r.db.ExecContext(ctx, query, emailID, reqctx.Tenant(ctx))
 	if err != nil {
 		return errors.NewInternalServerError("Failed to delete email")
 	}
@@ -296,10 +368,10 @@ func (r *personRepository) DeleteEmail(emailID int) error {
 	return nil
 }
 
-func (r *personRepository) GetEmails(personID int) ([]models.Email, error) {
-	query := `SELECT id, person_id, email, is_primary FROM emails WHERE person_id = $1`
+func (r *personRepository) GetEmails(ctx context.Context, personID int) ([]models.Email, error) {
+	query := `SELECT id, person_id, email, is_primary FROM emails WHERE person_id = $1 AND tenant_id = $2`
 
-	rows, err := r.db.Query(query, personID)
+	rows, err := r.db.QueryContext(ctx, query, personID, reqctx.Tenant(ctx))
 	if err != nil {
 		return nil, errors.NewInternalServerError("Failed to get emails")
 	}
@@ -318,10 +390,13 @@ func (r *personRepository) GetEmails(personID int) ([]models.Email, error) {
 	return emails, nil
 }
 
-func (r *personRepository) AddFriend(personID, friendID int) error {
-	query := `INSERT INTO friendships (person_id, friend_id) VALUES ($1, $2)`
+func (r *personRepository) AddFriend(ctx context.Context, personID, friendID int) error {
+	// Прямое добавление в друзья закрывает и висящую заявку между этими людьми
+	query := `
+		INSERT INTO relationships (person_id, related_id, type, status, tenant_id) VALUES ($1, $2, 'friend', 'accepted', $3)
+		ON CONFLICT (person_id, related_id, type) DO UPDATE SET status = 'accepted', responded_at = CURRENT_TIMESTAMP`
 
-	_, err := r.db.Exec(query, personID, friendID)
+	_, err := r.db.ExecContext(ctx, query, personID, friendID, reqctx.Tenant(ctx))
 	if err != nil {
 		return errors.NewInternalServerError("Failed to add friend")
 	}
@@ -329,10 +404,12 @@ func (r *personRepository) AddFriend(personID, friendID int) error {
 	return nil
 }
 
-func (r *personRepository) RemoveFriend(personID, friendID int) error {
-	query := `DELETE FROM friendships WHERE person_id = $1 AND friend_id = $2`
+func (r *personRepository) RemoveFriend(ctx context.Context, personID, friendID int) error {
+	query := `
+		DELETE FROM relationships
+		WHERE person_id = $1 AND related_id = $2 AND type = 'friend' AND status = 'accepted' AND tenant_id = $3`
 
-	result, err := r.db.Exec(query, personID, friendID)
+	result, err := r.db.ExecContext(ctx, query, personID, friendID, reqctx.Tenant(ctx))
 	if err != nil {
 		return errors.NewInternalServerError("Failed to remove friend")
 	}
@@ -349,14 +426,15 @@ func (r *personRepository) RemoveFriend(personID, friendID int) error {
 	return nil
 }
 
-func (r *personRepository) GetFriends(personID int) ([]models.Person, error) {
+func (r *personRepository) GetFriends(ctx context.Context, personID int) ([]models.Person, error) {
 	query := `
 		SELECT p.id, p.first_name, p.last_name, p.middle_name, p.age, p.gender, p.nationality, p.created_at, p.updated_at
 		FROM people p
-		JOIN friendships f ON p.id = f.friend_id
-		WHERE f.person_id = $1`
+		JOIN relationships f ON p.id = f.related_id
+		WHERE f.person_id = $1 AND f.type = 'friend' AND f.status = 'accepted' AND f.tenant_id = $2
+			AND p.deleted_at IS NULL`
 
-	rows, err := r.db.Query(query, personID)
+	rows, err := r.db.QueryContext(ctx, query, personID, reqctx.Tenant(ctx))
 	if err != nil {
 		return nil, errors.NewInternalServerError("Failed to get friends")
 	}
@@ -377,3 +455,146 @@ func (r *personRepository) GetFriends(personID int) ([]models.Person, error) {
 
 	return friends, nil
 }
+
+func (r *personRepository) GetDeleted(ctx context.Context, limit, offset int) ([]*models.Person, error) {
+	query := `
+		SELECT id, first_name, last_name, middle_name, age, gender, nationality, created_at, updated_at, deleted_at
+		FROM people WHERE tenant_id = $1 AND deleted_at IS NOT NULL ORDER BY deleted_at DESC, id LIMIT $2 OFFSET $3`
+
+	rows, err := r.db.QueryContext(ctx, query, reqctx.Tenant(ctx), limit, offset)
+	if err != nil {
+		return nil, errors.NewInternalServerError("Failed to get deleted people")
+	}
+	defer rows.Close()
+
+	var people []*models.Person
+	for rows.Next() {
+		person := &models.Person{}
+		err := rows.Scan(
+			&person.ID, &person.FirstName, &person.LastName, &person.MiddleName,
+			&person.Age, &person.Gender, &person.Nationality, &person.CreatedAt, &person.UpdatedAt,
+			&person.DeletedAt,
+		)
+		if err != nil {
+			return nil, errors.NewInternalServerError("Failed to scan person")
+		}
+		people = append(people, person)
+	}
+
+	return people, nil
+}
+
+func (r *personRepository) GetDeletedCount(ctx context.Context) (int, error) {
+	query := `SELECT COUNT(*) FROM people WHERE tenant_id = $1 AND deleted_at IS NOT NULL`
+
+	var count int
+	err := r.db.QueryRowContext(ctx, query, reqctx.Tenant(ctx)).Scan(&count)
+	if err != nil {
+		return 0, errors.NewInternalServerError("Failed to get deleted people count")
+	}
+
+	return count, nil
+}
+
+func (r *personRepository) Restore(ctx context.Context, id int) error {
+	query := `UPDATE people SET deleted_at = NULL WHERE id = $1 AND tenant_id = $2 AND deleted_at IS NOT NULL`
+
+	result, err := r.db.ExecContext(ctx, query, id, reqctx.Tenant(ctx))
+	if err != nil {
+		return errors.NewInternalServerError("Failed to restore person")
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return errors.NewInternalServerError("Failed to get rows affected")
+	}
+
+	if rowsAffected == 0 {
+		return errors.NewNotFoundError("Deleted person not found")
+	}
+
+	return nil
+}
+
+// PurgeDeleted окончательно удаляет людей тенанта, помеченных удаленными раньше before,
+// и возвращает их id. Email'ы и дружбы удаляются каскадом.
+func (r *personRepository) PurgeDeleted(ctx context.Context, before time.Time) ([]int, error) {
+	query := `DELETE FROM people WHERE tenant_id = $1 AND deleted_at IS NOT NULL AND deleted_at < $2 RETURNING id`
+
+	rows, err := r.db.QueryContext(ctx, query, reqctx.Tenant(ctx), before)
+	if err != nil {
+		return nil, errors.NewInternalServerError("Failed to purge deleted people")
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, errors.NewInternalServerError("Failed to scan purged person id")
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, nil
+}
+
+// GetTenants возвращает всех тенантов, у которых есть люди, в том числе удаленные. Запрос не ограничен
+// тенантом из контекста: он нужен фоновым задачам, которые обходят тенантов по очереди
+func (r *personRepository) GetTenants(ctx context.Context) ([]string, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT DISTINCT tenant_id FROM people ORDER BY tenant_id`)
+	if err != nil {
+		return nil, errors.NewInternalServerError("Failed to get tenants")
+	}
+	defer rows.Close()
+
+	var tenants []string
+	for rows.Next() {
+		var tenant string
+		if err := rows.Scan(&tenant); err != nil {
+			return nil, errors.NewInternalServerError("Failed to scan tenant")
+		}
+		tenants = append(tenants, tenant)
+	}
+
+	return tenants, nil
+}
+
+// CountAllPeople возвращает число живых людей всех тенантов для метрик. Запрос не ограничен тенантом
+func (r *personRepository) CountAllPeople(ctx context.Context) (int, error) {
+	var count int
+	if err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM people WHERE deleted_at IS NULL`).Scan(&count); err != nil {
+		return 0, errors.NewInternalServerError("Failed to count people")
+	}
+	return count, nil
+}
+
+// peopleFilterClause строит условие WHERE для живых (не удаленных) людей тенанта по фильтру.
+// alias - префикс таблицы people в запросе ("" или "p."), args - уже накопленные параметры.
+func peopleFilterClause(ctx context.Context, filter models.PeopleFilter, alias string, args []interface{}) (string, []interface{}) {
+	conditions := []string{alias + "deleted_at IS NULL"}
+
+	add := func(condition string, value interface{}) {
+		args = append(args, value)
+		conditions = append(conditions, fmt.Sprintf(alias+condition, len(args)))
+	}
+
+	add("tenant_id = $%d", reqctx.Tenant(ctx))
+	if filter.LastName != "" {
+		add("last_name = $%d", filter.LastName)
+	}
+	if filter.Gender != "" {
+		add("gender = $%d", filter.Gender)
+	}
+	if filter.Nationality != "" {
+		add("nationality = $%d", filter.Nationality)
+	}
+	if filter.MinAge != nil {
+		add("age >= $%d", *filter.MinAge)
+	}
+	if filter.MaxAge != nil {
+		add("age <= $%d", *filter.MaxAge)
+	}
+
+	return strings.Join(conditions, " AND "), args
+}
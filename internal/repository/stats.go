@@ -0,0 +1,161 @@
+package repository
+
+import (
+	"PeopleCRUD/internal/models"
+	"PeopleCRUD/pkg/errors"
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/lib/pq"
+)
+
+// GetStats считает агрегаты по людям под фильтром. Все запросы идут в одном снимке данных.
+// ageBuckets - возрастающие границы интервалов, начинающиеся с 0; period - day или week
+func (r *personRepository) GetStats(ctx context.Context, filter models.PeopleFilter, ageBuckets []int, period string) (*models.PeopleStats, error) {
+	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true, Isolation: sql.LevelRepeatableRead})
+	if err != nil {
+		return nil, errors.NewInternalServerError("Failed to begin stats transaction")
+	}
+	defer tx.Rollback()
+
+	where, args := peopleFilterClause(ctx, filter, "p.", nil)
+	stats := &models.PeopleStats{Period: period}
+
+	groupQuery := `SELECT COALESCE(p.%s, 'unknown'), COUNT(*) FROM people p WHERE %s GROUP BY 1 ORDER BY 2 DESC, 1`
+	if stats.ByGender, err = queryGroupCounts(ctx, tx, fmt.Sprintf(groupQuery, "gender", where), args); err != nil {
+		return nil, err
+	}
+	if stats.ByNationality, err = queryGroupCounts(ctx, tx, fmt.Sprintf(groupQuery, "nationality", where), args); err != nil {
+		return nil, err
+	}
+	for _, group := range stats.ByGender {
+		stats.Total += group.Count
+	}
+
+	if stats.AgeHistogram, stats.UnknownAge, err = queryAgeHistogram(ctx, tx, where, args, ageBuckets); err != nil {
+		return nil, err
+	}
+
+	if stats.AverageAgeByNationality, err = queryAverageAge(ctx, tx, where, args); err != nil {
+		return nil, err
+	}
+
+	if stats.CreatedPerPeriod, err = queryCreatedPerPeriod(ctx, tx, where, args, period); err != nil {
+		return nil, err
+	}
+
+	coverageQuery := fmt.Sprintf(`
+		SELECT COUNT(*) FILTER (WHERE EXISTS (SELECT 1 FROM emails e WHERE e.person_id = p.id))
+		FROM people p WHERE %s`, where)
+	if err := tx.QueryRowContext(ctx, coverageQuery, args...).Scan(&stats.EmailCoverage.WithEmail); err != nil {
+		return nil, errors.NewInternalServerError("Failed to get email coverage")
+	}
+	stats.EmailCoverage.WithoutEmail = stats.Total - stats.EmailCoverage.WithEmail
+	if stats.Total > 0 {
+		stats.EmailCoverage.Ratio = float64(stats.EmailCoverage.WithEmail) / float64(stats.Total)
+	}
+
+	return stats, nil
+}
+
+func queryGroupCounts(ctx context.Context, tx *sql.Tx, query string, args []interface{}) ([]models.GroupCount, error) {
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, errors.NewInternalServerError("Failed to get group counts")
+	}
+	defer rows.Close()
+
+	groups := []models.GroupCount{}
+	for rows.Next() {
+		var group models.GroupCount
+		if err := rows.Scan(&group.Value, &group.Count); err != nil {
+			return nil, errors.NewInternalServerError("Failed to scan group count")
+		}
+		groups = append(groups, group)
+	}
+	return groups, nil
+}
+
+// queryAgeHistogram раскладывает возраст по интервалам через width_bucket, пустые интервалы тоже попадают в результат
+func queryAgeHistogram(ctx context.Context, tx *sql.Tx, where string, args []interface{}, bounds []int) ([]models.AgeBucket, int, error) {
+	query := fmt.Sprintf(`
+		SELECT CASE WHEN p.age IS NULL THEN -1 ELSE width_bucket(p.age, $%d::int[]) END, COUNT(*)
+		FROM people p WHERE %s GROUP BY 1`, len(args)+1, where)
+
+	rows, err := tx.QueryContext(ctx, query, append(args, pq.Array(bounds))...)
+	if err != nil {
+		return nil, 0, errors.NewInternalServerError("Failed to get age histogram")
+	}
+	defer rows.Close()
+
+	buckets := make([]models.AgeBucket, len(bounds))
+	for i, from := range bounds {
+		buckets[i].From = from
+		if i+1 < len(bounds) {
+			to := bounds[i+1]
+			buckets[i].To = &to
+		}
+	}
+
+	unknown := 0
+	for rows.Next() {
+		var bucket, count int
+		if err := rows.Scan(&bucket, &count); err != nil {
+			return nil, 0, errors.NewInternalServerError("Failed to scan age bucket")
+		}
+		// width_bucket нумерует интервалы с 1, 0 - возраст меньше первой границы
+		if bucket >= 1 && bucket <= len(buckets) {
+			buckets[bucket-1].Count += count
+		} else {
+			unknown += count
+		}
+	}
+	return buckets, unknown, nil
+}
+
+func queryAverageAge(ctx context.Context, tx *sql.Tx, where string, args []interface{}) ([]models.NationalityAge, error) {
+	query := fmt.Sprintf(`
+		SELECT COALESCE(p.nationality, 'unknown'), AVG(p.age)::float8, COUNT(*)
+		FROM people p WHERE %s AND p.age IS NOT NULL
+		GROUP BY 1 ORDER BY 3 DESC, 1`, where)
+
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, errors.NewInternalServerError("Failed to get average age")
+	}
+	defer rows.Close()
+
+	result := []models.NationalityAge{}
+	for rows.Next() {
+		var item models.NationalityAge
+		if err := rows.Scan(&item.Nationality, &item.AverageAge, &item.Count); err != nil {
+			return nil, errors.NewInternalServerError("Failed to scan average age")
+		}
+		result = append(result, item)
+	}
+	return result, nil
+}
+
+func queryCreatedPerPeriod(ctx context.Context, tx *sql.Tx, where string, args []interface{}, period string) ([]models.PeriodCount, error) {
+	query := fmt.Sprintf(`
+		SELECT date_trunc($%d::text, p.created_at) AS period, COUNT(*)
+		FROM people p WHERE %s
+		GROUP BY 1 ORDER BY 1`, len(args)+1, where)
+
+	rows, err := tx.QueryContext(ctx, query, append(args, period)...)
+	if err != nil {
+		return nil, errors.NewInternalServerError("Failed to get creation stats")
+	}
+	defer rows.Close()
+
+	result := []models.PeriodCount{}
+	for rows.Next() {
+		var item models.PeriodCount
+		if err := rows.Scan(&item.Start, &item.Count); err != nil {
+			return nil, errors.NewInternalServerError("Failed to scan creation stats")
+		}
+		result = append(result, item)
+	}
+	return result, nil
+}
@@ -0,0 +1,148 @@
+package repository
+
+import (
+	"PeopleCRUD/internal/models"
+	"PeopleCRUD/internal/reqctx"
+	"context"
+	"testing"
+	"time"
+)
+
+func TestPersonRepositoryTenantIsolation(t *testing.T) {
+	repo := NewPersonRepository(testDB(t))
+	ctxA := reqctx.WithTenant(context.Background(), "team-a")
+	ctxB := reqctx.WithTenant(context.Background(), "team-b")
+
+	alice := &models.Person{FirstName: "Alice", LastName: "Isolated"}
+	if err := repo.CreateWithTransaction(ctxA, alice, []string{"alice@example.com"}); err != nil {
+		t.Fatalf("create alice: %v", err)
+	}
+	bob := &models.Person{FirstName: "Bob", LastName: "Isolated"}
+	if err := repo.Create(ctxA, bob); err != nil {
+		t.Fatalf("create bob: %v", err)
+	}
+	if err := repo.CreateRelationship(ctxA, alice.ID, &models.CreateRelationshipRequest{RelatedID: bob.ID, Type: "friend"}); err != nil {
+		t.Fatalf("befriend: %v", err)
+	}
+	emails, err := repo.GetEmails(ctxA, alice.ID)
+	if err != nil || len(emails) != 1 {
+		t.Fatalf("emails of alice: %v, %v", emails, err)
+	}
+	emailID := emails[0].ID
+
+	// Человек тенанта B, от имени которого пытаются дотянуться до данных A
+	carol := &models.Person{FirstName: "Carol", LastName: "Isolated"}
+	if err := repo.Create(ctxB, carol); err != nil {
+		t.Fatalf("create carol: %v", err)
+	}
+
+	t.Run("reads", func(t *testing.T) {
+		_, err := repo.GetByID(ctxB, alice.ID)
+		requireNotFound(t, "GetByID", err)
+
+		if people, err := repo.GetAll(ctxB, models.PeopleFilter{}, 100, 0); err != nil || len(people) != 1 || people[0].ID != carol.ID {
+			t.Errorf("GetAll: expected only carol, got %v, %v", people, err)
+		}
+		if count, err := repo.GetCount(ctxB, models.PeopleFilter{LastName: "Isolated"}); err != nil || count != 1 {
+			t.Errorf("GetCount: expected 1, got %d, %v", count, err)
+		}
+		if people, err := repo.GetByLastName(ctxB, "Isolated"); err != nil || len(people) != 1 {
+			t.Errorf("GetByLastName: expected only carol, got %v, %v", people, err)
+		}
+		if people, err := repo.GetByIDs(ctxB, []int{alice.ID, bob.ID}); err != nil || len(people) != 0 {
+			t.Errorf("GetByIDs: expected nothing, got %v, %v", people, err)
+		}
+		if emails, err := repo.GetEmails(ctxB, alice.ID); err != nil || len(emails) != 0 {
+			t.Errorf("GetEmails: expected nothing, got %v, %v", emails, err)
+		}
+		if friends, err := repo.GetFriends(ctxB, alice.ID); err != nil || len(friends) != 0 {
+			t.Errorf("GetFriends: expected nothing, got %v, %v", friends, err)
+		}
+		if rels, err := repo.GetRelationships(ctxB, alice.ID, ""); err != nil || len(rels) != 0 {
+			t.Errorf("GetRelationships: expected nothing, got %v, %v", rels, err)
+		}
+		if status, err := repo.GetFriendshipStatus(ctxB, alice.ID, bob.ID); err != nil || status != "" {
+			t.Errorf("GetFriendshipStatus: expected none, got %q, %v", status, err)
+		}
+		if path, _ := repo.FindFriendPath(ctxB, alice.ID, bob.ID, 3); len(path) != 0 {
+			t.Errorf("FindFriendPath: expected no path, got %v", path)
+		}
+		if versions, err := repo.GetVersions(ctxB, alice.ID); err != nil || len(versions) != 0 {
+			t.Errorf("GetVersions: expected nothing, got %v, %v", versions, err)
+		}
+		_, err = repo.GetByIDAsOf(ctxB, alice.ID, time.Now().UTC())
+		requireNotFound(t, "GetByIDAsOf", err)
+	})
+
+	t.Run("writes", func(t *testing.T) {
+		name := "Mallory"
+		requireNotFound(t, "Update", repo.Update(ctxB, alice.ID, &models.UpdatePersonRequest{FirstName: &name}))
+		requireNotFound(t, "Replace", repo.Replace(ctxB, alice.ID, &models.Person{FirstName: name, LastName: "Isolated"}))
+		requireNotFound(t, "UpdateEmail", repo.UpdateEmail(ctxB, emailID, "mallory@example.com", true))
+		requireNotFound(t, "DeleteEmail", repo.DeleteEmail(ctxB, emailID))
+		requireNotFound(t, "RemoveFriend", repo.RemoveFriend(ctxB, alice.ID, bob.ID))
+		requireNotFound(t, "Delete", repo.Delete(ctxB, alice.ID))
+
+		if _, err := repo.AddEmail(ctxB, alice.ID, "mallory@example.com", false); err == nil {
+			t.Error("AddEmail: expected an error for a person of another tenant")
+		}
+		if err := repo.CreateFriendRequest(ctxB, carol.ID, alice.ID); err == nil {
+			t.Error("CreateFriendRequest: expected an error for a person of another tenant")
+		}
+		if err := repo.CreateRelationship(ctxB, carol.ID, &models.CreateRelationshipRequest{RelatedID: alice.ID, Type: "colleague"}); err == nil {
+			t.Error("CreateRelationship: expected an error for a person of another tenant")
+		}
+		if _, err := repo.Merge(ctxB, carol.ID, alice.ID); err == nil {
+			t.Error("Merge: expected an error for a person of another tenant")
+		}
+		if errs := repo.BulkDelete(ctxB, []int{alice.ID}, false); len(errs) != 1 || errs[0] == nil {
+			t.Errorf("BulkDelete: expected a per-item error, got %v", errs)
+		}
+	})
+
+	t.Run("owner data intact", func(t *testing.T) {
+		got, err := repo.GetByID(ctxA, alice.ID)
+		if err != nil || got.FirstName != "Alice" {
+			t.Fatalf("alice changed: %+v, %v", got, err)
+		}
+		if emails, err := repo.GetEmails(ctxA, alice.ID); err != nil || len(emails) != 1 || emails[0].Email != "alice@example.com" {
+			t.Errorf("alice emails changed: %v, %v", emails, err)
+		}
+		if friends, err := repo.GetFriends(ctxA, alice.ID); err != nil || len(friends) != 1 || friends[0].ID != bob.ID {
+			t.Errorf("alice friends changed: %v, %v", friends, err)
+		}
+	})
+}
+
+func TestAPIKeyRepositoryTenantIsolation(t *testing.T) {
+	db := testDB(t)
+	people := NewPersonRepository(db)
+	keys := NewAPIKeyRepository(db)
+	ctxA := reqctx.WithTenant(context.Background(), "team-a")
+	ctxB := reqctx.WithTenant(context.Background(), "team-b")
+
+	alice := &models.Person{FirstName: "Alice", LastName: "Keys"}
+	if err := people.Create(ctxA, alice); err != nil {
+		t.Fatalf("create alice: %v", err)
+	}
+	key := &models.APIKey{Name: "team-a", Prefix: "aaaaaaaaaaaa", Role: "admin", TenantID: "team-a", PersonID: &alice.ID}
+	if err := keys.Create(ctxA, key, "hash-a"); err != nil {
+		t.Fatalf("create key: %v", err)
+	}
+
+	if list, err := keys.List(ctxB); err != nil || len(list) != 0 {
+		t.Errorf("List: expected no keys of team-b, got %v, %v", list, err)
+	}
+	requireNotFound(t, "Revoke", keys.Revoke(ctxB, key.ID))
+
+	// Составной внешний ключ не дает связать ключ тенанта B с человеком тенанта A
+	foreign := &models.APIKey{Name: "team-b", Prefix: "bbbbbbbbbbbb", Role: "reader", TenantID: "team-b", PersonID: &alice.ID}
+	if err := keys.Create(ctxB, foreign, "hash-b"); err == nil {
+		t.Error("Create: expected an error for a person of another tenant")
+	}
+
+	list, err := keys.List(ctxA)
+	if err != nil || len(list) != 1 || list[0].RevokedAt != nil {
+		t.Errorf("List: expected the active team-a key, got %v, %v", list, err)
+	}
+}
@@ -0,0 +1,84 @@
+package reqctx
+
+import (
+	"context"
+	"regexp"
+
+	"github.com/sirupsen/logrus"
+)
+
+type contextKey int
+
+const (
+	actorKey contextKey = iota
+	requestIDKey
+	tenantKey
+	loggerKey
+)
+
+// AnonymousActor используется, когда вызывающий не представился
+const AnonymousActor = "anonymous"
+
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey, actor)
+}
+
+// Actor возвращает того, от чьего имени выполняется запрос
+func Actor(ctx context.Context) string {
+	if actor, ok := ctx.Value(actorKey).(string); ok && actor != "" {
+		return actor
+	}
+	return AnonymousActor
+}
+
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey, requestID)
+}
+
+func RequestID(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDKey).(string)
+	return requestID
+}
+
+// WithLogger кладет в контекст запись лога с полями запроса (request_id, principal, tenant)
+func WithLogger(ctx context.Context, entry *logrus.Entry) context.Context {
+	return context.WithValue(ctx, loggerKey, entry)
+}
+
+// WithLogFields добавляет поля к записи лога запроса. Вне запроса контекст не меняется
+func WithLogFields(ctx context.Context, fields logrus.Fields) context.Context {
+	if entry, ok := ctx.Value(loggerKey).(*logrus.Entry); ok {
+		return WithLogger(ctx, entry.WithFields(fields))
+	}
+	return ctx
+}
+
+// Logger возвращает запись лога запроса, а вне запроса (фоновые задачи) - запись базового логгера
+func Logger(ctx context.Context, base *logrus.Logger) *logrus.Entry {
+	if entry, ok := ctx.Value(loggerKey).(*logrus.Entry); ok {
+		return entry
+	}
+	return logrus.NewEntry(base)
+}
+
+// DefaultTenant - тенант запросов, для которых он не указан
+const DefaultTenant = "default"
+
+var tenantRegex = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,64}$`)
+
+// ValidTenant проверяет формат id тенанта. Двоеточие запрещено: id входит в ключи кэша
+func ValidTenant(tenant string) bool {
+	return tenantRegex.MatchString(tenant)
+}
+
+func WithTenant(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, tenantKey, tenant)
+}
+
+// Tenant возвращает тенанта, данными которого ограничен запрос
+func Tenant(ctx context.Context) string {
+	if tenant, ok := ctx.Value(tenantKey).(string); ok && tenant != "" {
+		return tenant
+	}
+	return DefaultTenant
+}
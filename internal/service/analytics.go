@@ -0,0 +1,82 @@
+package service
+
+import (
+	"PeopleCRUD/internal/cache"
+	"PeopleCRUD/internal/graph"
+	"PeopleCRUD/internal/models"
+	"PeopleCRUD/pkg/errors"
+	"context"
+	"net/http"
+	"time"
+)
+
+const (
+	graphAnalyticsKey     = "graph:analytics"
+	maxGraphAnalyticsTop  = 100
+	defaultGraphAnalytics = 10
+)
+
+// RefreshGraphAnalytics пересчитывает метрики графа дружбы тенанта и сохраняет их в базе.
+// Кэш только ускоряет чтение: вытеснение из него не теряет результат
+func (s *personService) RefreshGraphAnalytics(ctx context.Context) (*models.GraphAnalytics, error) {
+	started := time.Now()
+	g := graph.New()
+
+	nodeFn := func(person *models.Person) error {
+		g.AddNode(person)
+		return nil
+	}
+	edgeFn := func(edge *models.GraphEdge) error {
+		g.AddEdge(edge)
+		return nil
+	}
+
+	if err := s.repo.StreamGraph(ctx, nil, 0, nodeFn, edgeFn); err != nil {
+		s.log(ctx).WithError(err).Error("Failed to load graph for analytics")
+		return nil, err
+	}
+
+	result := g.Analyze(maxGraphAnalyticsTop)
+	if err := s.repo.SaveGraphAnalytics(ctx, result); err != nil {
+		s.log(ctx).WithError(err).Error("Failed to save graph analytics")
+		return nil, err
+	}
+	s.cache.Delete(tenantCacheKey(ctx, graphAnalyticsKey))
+
+	s.log(ctx).WithField("nodes", result.Nodes).WithField("edges", result.Edges).
+		WithField("duration", time.Since(started)).Info("Graph analytics refreshed")
+	return result, nil
+}
+
+// GetGraphAnalytics отдает последний результат задания, списки лидеров обрезаются до top.
+// Сам не считает: пока задание не отработало для тенанта, отвечает 503
+func (s *personService) GetGraphAnalytics(ctx context.Context, top int) (*models.GraphAnalytics, error) {
+	if top <= 0 {
+		top = defaultGraphAnalytics
+	}
+	if top > maxGraphAnalyticsTop {
+		top = maxGraphAnalyticsTop
+	}
+
+	result, err := cache.Fetch(ctx, s.cache, tenantCacheKey(ctx, graphAnalyticsKey), func(ctx context.Context) (*models.GraphAnalytics, []string, error) {
+		result, err := s.repo.GetGraphAnalytics(ctx)
+		return result, nil, err
+	})
+	if err != nil {
+		if appErr, ok := err.(*errors.AppError); ok && appErr.Code == http.StatusNotFound {
+			return nil, errors.NewAppError(http.StatusServiceUnavailable, "Graph analytics not ready",
+				"Metrics are computed by a background job, retry later")
+		}
+		s.log(ctx).WithError(err).Error("Failed to get graph analytics")
+		return nil, err
+	}
+
+	trimmed := *result
+	if len(trimmed.MostConnected) > top {
+		trimmed.MostConnected = trimmed.MostConnected[:top]
+	}
+	if len(trimmed.MostBetween) > top {
+		trimmed.MostBetween = trimmed.MostBetween[:top]
+	}
+	return &trimmed, nil
+}
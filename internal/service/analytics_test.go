@@ -0,0 +1,51 @@
+package service
+
+import (
+	"PeopleCRUD/internal/models"
+	"PeopleCRUD/pkg/errors"
+	"context"
+	"net/http"
+	"testing"
+)
+
+// savedAnalytics хранит результат задания аналитики. StreamGraph не реализован: чтение не должно считать граф
+type savedAnalytics struct {
+	*memoryPeople
+	result *models.GraphAnalytics
+}
+
+func (r *savedAnalytics) SaveGraphAnalytics(_ context.Context, result *models.GraphAnalytics) error {
+	r.result = result
+	return nil
+}
+
+func (r *savedAnalytics) GetGraphAnalytics(context.Context) (*models.GraphAnalytics, error) {
+	if r.result == nil {
+		return nil, errors.NewNotFoundError("Graph analytics not found")
+	}
+	return r.result, nil
+}
+
+func TestGetGraphAnalyticsReadsStoredResult(t *testing.T) {
+	repo := &savedAnalytics{memoryPeople: newMemoryPeople()}
+	svc := newCachedPersonService(t, repo)
+	ctx := context.Background()
+
+	_, err := svc.GetGraphAnalytics(ctx, 10)
+	if appErr, ok := err.(*errors.AppError); !ok || appErr.Code != http.StatusServiceUnavailable {
+		t.Fatalf("before the job: expected 503, got %v", err)
+	}
+
+	leaders := []models.CentralPerson{{}, {}, {}}
+	repo.result = &models.GraphAnalytics{Nodes: 3, MostConnected: leaders, MostBetween: leaders}
+	got, err := svc.GetGraphAnalytics(ctx, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Nodes != 3 || len(got.MostConnected) != 2 || len(got.MostBetween) != 2 {
+		t.Errorf("got %+v, want 3 nodes and top 2 leaders", got)
+	}
+	if len(repo.result.MostConnected) != 3 {
+		t.Error("trimming changed the stored result")
+	}
+}
@@ -0,0 +1,140 @@
+package service
+
+import (
+	"PeopleCRUD/internal/models"
+	"PeopleCRUD/internal/repository"
+	"PeopleCRUD/internal/reqctx"
+	"context"
+	"encoding/json"
+	"reflect"
+
+	"github.com/sirupsen/logrus"
+)
+
+const maxAuditPageSize = 500
+
+type AuditService interface {
+	GetPersonHistory(ctx context.Context, personID, limit, offset int) ([]models.AuditEvent, int, error)
+	GetEvents(ctx context.Context, filter models.AuditFilter) ([]models.AuditEvent, int, error)
+}
+
+type auditService struct {
+	repo   repository.AuditRepository
+	logger *logrus.Logger
+}
+
+func NewAuditService(repo repository.AuditRepository, logger *logrus.Logger) AuditService {
+	return &auditService{
+		repo:   repo,
+		logger: logger,
+	}
+}
+
+func (s *auditService) log(ctx context.Context) *logrus.Entry {
+	return reqctx.Logger(ctx, s.logger)
+}
+
+func (s *auditService) GetPersonHistory(ctx context.Context, personID, limit, offset int) ([]models.AuditEvent, int, error) {
+	return s.GetEvents(ctx, models.AuditFilter{
+		PersonID: &personID,
+		Limit:    limit,
+		Offset:   offset,
+	})
+}
+
+func (s *auditService) GetEvents(ctx context.Context, filter models.AuditFilter) ([]models.AuditEvent, int, error) {
+	if filter.Limit <= 0 || filter.Limit > maxAuditPageSize {
+		filter.Limit = maxAuditPageSize
+	}
+	if filter.Offset < 0 {
+		filter.Offset = 0
+	}
+
+	events, total, err := s.repo.List(ctx, filter)
+	if err != nil {
+		s.log(ctx).WithError(err).Error("Failed to get audit events")
+		return nil, 0, err
+	}
+	return events, total, nil
+}
+
+// auditIgnoredFields не попадают в diff: они меняются сами или не несут смысла для истории
+var auditIgnoredFields = map[string]bool{
+	"id":         true,
+	"created_at": true,
+	"updated_at": true,
+	"deleted_at": true,
+	"friends":    true,
+}
+
+// recordAudit пишет событие в журнал. Ошибка записи не отменяет уже выполненное изменение,
+// поэтому только логируется.
+func (s *personService) recordAudit(ctx context.Context, entityType, action string, entityID, personID int, before, after interface{}) {
+	s.recordAuditChanges(ctx, entityType, action, entityID, personID, diffFields(before, after))
+}
+
+// recordAuditChanges пишет событие с заранее подготовленным набором изменений
+func (s *personService) recordAuditChanges(ctx context.Context, entityType, action string, entityID, personID int, changes map[string]models.FieldChange) {
+	if s.audit == nil {
+		return
+	}
+
+	event := &models.AuditEvent{
+		EntityType: entityType,
+		EntityID:   entityID,
+		PersonID:   personID,
+		Action:     action,
+		Actor:      reqctx.Actor(ctx),
+		RequestID:  reqctx.RequestID(ctx),
+		Changes:    changes,
+	}
+
+	if err := s.audit.Create(ctx, event); err != nil {
+		s.log(ctx).WithError(err).WithFields(logrus.Fields{
+			"entity_type": entityType,
+			"entity_id":   entityID,
+			"action":      action,
+		}).Error("Failed to record audit event")
+	}
+}
+
+// diffFields сравнивает JSON-представления before и after по полям.
+// nil с любой стороны означает отсутствие объекта (создание или удаление).
+func diffFields(before, after interface{}) map[string]models.FieldChange {
+	oldFields := toFieldMap(before)
+	newFields := toFieldMap(after)
+
+	changes := make(map[string]models.FieldChange)
+	for key, oldValue := range oldFields {
+		if auditIgnoredFields[key] {
+			continue
+		}
+		if newValue := newFields[key]; !reflect.DeepEqual(oldValue, newValue) {
+			changes[key] = models.FieldChange{Old: oldValue, New: newValue}
+		}
+	}
+	for key, newValue := range newFields {
+		if auditIgnoredFields[key] {
+			continue
+		}
+		if _, seen := oldFields[key]; !seen && newValue != nil {
+			changes[key] = models.FieldChange{Old: nil, New: newValue}
+		}
+	}
+
+	return changes
+}
+
+func toFieldMap(value interface{}) map[string]interface{} {
+	fields := map[string]interface{}{}
+	if value == nil || reflect.ValueOf(value).Kind() == reflect.Ptr && reflect.ValueOf(value).IsNil() {
+		return fields
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fields
+	}
+	_ = json.Unmarshal(data, &fields)
+	return fields
+}
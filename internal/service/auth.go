@@ -0,0 +1,153 @@
+package service
+
+import (
+	"PeopleCRUD/internal/auth"
+	"PeopleCRUD/internal/models"
+	"PeopleCRUD/internal/repository"
+	"PeopleCRUD/internal/reqctx"
+	"PeopleCRUD/pkg/errors"
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Subject ключа начальной настройки, заданного в конфигурации, а не в базе. Он всегда admin
+const bootstrapSubject = "api_key:bootstrap"
+
+type AuthService interface {
+	CreateAPIKey(ctx context.Context, req *models.CreateAPIKeyRequest) (*models.CreatedAPIKey, error)
+	GetAPIKeys(ctx context.Context) ([]models.APIKey, error)
+	RevokeAPIKey(ctx context.Context, id int) error
+	VerifyAPIKey(ctx context.Context, key string) (*auth.Principal, error)
+}
+
+type authService struct {
+	repo          repository.APIKeyRepository
+	bootstrapHash string
+	logger        *logrus.Logger
+}
+
+// NewAuthService создает сервис ключей. bootstrapKey - необязательный ключ из конфигурации,
+// чтобы создать первые ключи в пустой базе
+func NewAuthService(repo repository.APIKeyRepository, bootstrapKey string, logger *logrus.Logger) AuthService {
+	service := &authService{
+		repo:   repo,
+		logger: logger,
+	}
+	if bootstrapKey != "" {
+		service.bootstrapHash = auth.HashAPIKey(bootstrapKey)
+	}
+	return service
+}
+
+func (s *authService) log(ctx context.Context) *logrus.Entry {
+	return reqctx.Logger(ctx, s.logger)
+}
+
+func (s *authService) CreateAPIKey(ctx context.Context, req *models.CreateAPIKeyRequest) (*models.CreatedAPIKey, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	if req.Role == "" {
+		req.Role = auth.RoleReader
+	}
+	if !auth.ValidRole(req.Role) {
+		return nil, errors.NewValidationError("Role must be reader, editor or admin")
+	}
+
+	// Ключ создается в тенанте запроса. Другой тенант может указать только вызывающий без привязки к тенанту
+	tenant := reqctx.Tenant(ctx)
+	if req.TenantID != nil && *req.TenantID != tenant {
+		if principal, ok := auth.FromContext(ctx); ok && !principal.CrossTenant {
+			return nil, errors.NewForbiddenError("Access to this tenant is not allowed")
+		}
+		tenant = *req.TenantID
+	}
+
+	key, prefix, hash, err := auth.GenerateAPIKey()
+	if err != nil {
+		s.log(ctx).WithError(err).Error("Failed to generate API key")
+		return nil, errors.NewInternalServerError("Failed to generate API key")
+	}
+
+	created := &models.CreatedAPIKey{
+		APIKey: models.APIKey{Name: req.Name, Prefix: prefix, Role: req.Role, TenantID: tenant, PersonID: req.PersonID},
+		Key:    key,
+	}
+	if err := s.repo.Create(ctx, &created.APIKey, hash); err != nil {
+		s.log(ctx).WithError(err).Error("Failed to create API key")
+		return nil, err
+	}
+
+	s.log(ctx).WithFields(logrus.Fields{"api_key_id": created.ID, "name": created.Name, "role": created.Role}).Info("API key created")
+	return created, nil
+}
+
+func (s *authService) GetAPIKeys(ctx context.Context) ([]models.APIKey, error) {
+	keys, err := s.repo.List(ctx)
+	if err != nil {
+		s.log(ctx).WithError(err).Error("Failed to get API keys")
+		return nil, err
+	}
+	return keys, nil
+}
+
+func (s *authService) RevokeAPIKey(ctx context.Context, id int) error {
+	if err := s.repo.Revoke(ctx, id); err != nil {
+		s.log(ctx).WithError(err).Error("Failed to revoke API key")
+		return err
+	}
+
+	s.log(ctx).WithField("api_key_id", id).Info("API key revoked")
+	return nil
+}
+
+// VerifyAPIKey находит действующий ключ. Неизвестный, поддельный и отозванный ключи неотличимы для вызывающего
+func (s *authService) VerifyAPIKey(ctx context.Context, key string) (*auth.Principal, error) {
+	hash := auth.HashAPIKey(key)
+	if s.bootstrapHash != "" && subtle.ConstantTimeCompare([]byte(hash), []byte(s.bootstrapHash)) == 1 {
+		return &auth.Principal{
+			Subject: bootstrapSubject,
+			Name:    "bootstrap",
+			Method:  auth.MethodAPIKey,
+			Roles:   []string{auth.RoleAdmin},
+			// Ключ из конфигурации создает первые ключи любого тенанта
+			CrossTenant: true,
+		}, nil
+	}
+
+	invalid := errors.NewUnauthorizedError("Invalid API key")
+	prefix, ok := auth.ParseAPIKey(key)
+	if !ok {
+		return nil, invalid
+	}
+
+	stored, storedHash, err := s.repo.GetByPrefix(ctx, prefix)
+	if err != nil {
+		if appErr, ok := err.(*errors.AppError); ok && appErr.Code == http.StatusNotFound {
+			return nil, invalid
+		}
+		s.log(ctx).WithError(err).Error("Failed to get API key")
+		return nil, err
+	}
+	if subtle.ConstantTimeCompare([]byte(hash), []byte(storedHash)) != 1 || stored.RevokedAt != nil {
+		return nil, invalid
+	}
+
+	if err := s.repo.TouchLastUsed(ctx, stored.ID); err != nil {
+		s.log(ctx).WithError(err).Warn("Failed to update API key usage")
+	}
+
+	principal := &auth.Principal{
+		Subject:  fmt.Sprintf("api_key:%d", stored.ID),
+		Name:     stored.Name,
+		Method:   auth.MethodAPIKey,
+		Roles:    []string{stored.Role},
+		PersonID: stored.PersonID,
+		TenantID: stored.TenantID,
+	}
+	return principal, nil
+}
@@ -0,0 +1,98 @@
+package service
+
+import (
+	"PeopleCRUD/internal/auth"
+	"PeopleCRUD/internal/models"
+	"PeopleCRUD/internal/reqctx"
+	"PeopleCRUD/pkg/errors"
+	"context"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+// memoryAPIKeys повторяет фильтрацию по тенанту из apiKeyRepository
+type memoryAPIKeys struct {
+	keys []models.APIKey
+}
+
+func (r *memoryAPIKeys) Create(_ context.Context, key *models.APIKey, _ string) error {
+	key.ID = len(r.keys) + 1
+	r.keys = append(r.keys, *key)
+	return nil
+}
+
+func (r *memoryAPIKeys) GetByPrefix(_ context.Context, prefix string) (*models.APIKey, string, error) {
+	for _, key := range r.keys {
+		if key.Prefix == prefix {
+			return &key, "", nil
+		}
+	}
+	return nil, "", errors.NewNotFoundError("API key not found")
+}
+
+func (r *memoryAPIKeys) List(ctx context.Context) ([]models.APIKey, error) {
+	keys := []models.APIKey{}
+	for _, key := range r.keys {
+		if key.TenantID == reqctx.Tenant(ctx) {
+			keys = append(keys, key)
+		}
+	}
+	return keys, nil
+}
+
+func (r *memoryAPIKeys) Revoke(ctx context.Context, id int) error {
+	for _, key := range r.keys {
+		if key.ID == id && key.TenantID == reqctx.Tenant(ctx) {
+			return nil
+		}
+	}
+	return errors.NewNotFoundError("API key not found")
+}
+
+func (r *memoryAPIKeys) TouchLastUsed(context.Context, int) error {
+	return nil
+}
+
+func callerContext(tenant string, principal *auth.Principal) context.Context {
+	return auth.WithPrincipal(reqctx.WithTenant(context.Background(), tenant), principal)
+}
+
+func TestAuthServiceAPIKeysAreTenantScoped(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	repo := &memoryAPIKeys{}
+	svc := NewAuthService(repo, "", logger)
+
+	ctxA := callerContext("team-a", &auth.Principal{Subject: "api_key:1", Roles: []string{auth.RoleAdmin}, TenantID: "team-a"})
+	ctxB := callerContext("team-b", &auth.Principal{Subject: "api_key:2", Roles: []string{auth.RoleAdmin}, TenantID: "team-b"})
+	bootstrap := callerContext(reqctx.DefaultTenant, &auth.Principal{Subject: bootstrapSubject, Roles: []string{auth.RoleAdmin}, CrossTenant: true})
+
+	created, err := svc.CreateAPIKey(ctxA, &models.CreateAPIKeyRequest{Name: "reader"})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if created.TenantID != "team-a" {
+		t.Errorf("key of a team-a caller got tenant %q", created.TenantID)
+	}
+
+	other := "team-b"
+	_, err = svc.CreateAPIKey(ctxA, &models.CreateAPIKeyRequest{Name: "escape", TenantID: &other})
+	if appErr, ok := err.(*errors.AppError); !ok || appErr.Code != http.StatusForbidden {
+		t.Errorf("team-a caller creating a team-b key: expected 403, got %v", err)
+	}
+
+	fromBootstrap, err := svc.CreateAPIKey(bootstrap, &models.CreateAPIKeyRequest{Name: "team-b admin", TenantID: &other})
+	if err != nil || fromBootstrap.TenantID != "team-b" {
+		t.Errorf("bootstrap key creating a team-b key: %+v, %v", fromBootstrap, err)
+	}
+
+	if keys, err := svc.GetAPIKeys(ctxB); err != nil || len(keys) != 1 || keys[0].ID != fromBootstrap.ID {
+		t.Errorf("team-b sees keys %v, %v", keys, err)
+	}
+	if err := svc.RevokeAPIKey(ctxB, created.ID); err == nil {
+		t.Error("team-b revoked a team-a key")
+	}
+}
@@ -0,0 +1,218 @@
+package service
+
+import (
+	"PeopleCRUD/internal/models"
+	"PeopleCRUD/pkg/errors"
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+func (s *personService) BulkCreatePeople(ctx context.Context, reqs []models.CreatePersonRequest, mode string) (*models.BulkResponse, error) {
+	if err := validateBulk(len(reqs), mode); err != nil {
+		return nil, err
+	}
+
+	results := newBulkResults(len(reqs))
+	var people []*models.Person
+	var emails [][]string
+	var indexes []int
+	for i := range reqs {
+		req := &reqs[i]
+		if err := req.Validate(); err != nil {
+			results[i].Error = toAppError(err)
+			continue
+		}
+		people = append(people, &models.Person{
+			FirstName:  strings.TrimSpace(req.FirstName),
+			LastName:   strings.TrimSpace(req.LastName),
+			MiddleName: req.MiddleName,
+		})
+		emails = append(emails, req.Emails)
+		indexes = append(indexes, i)
+	}
+
+	if mode == models.BulkModeAtomic && len(indexes) < len(reqs) {
+		return finishBulk(mode, markRolledBack(results)), nil
+	}
+
+	if len(people) > 0 {
+		errs := s.repo.BulkCreate(ctx, people, emails, mode == models.BulkModeAtomic)
+		for j, i := range indexes {
+			if errs[j] != nil {
+				results[i].Error = toAppError(errs[j])
+				continue
+			}
+			results[i].ID = people[j].ID
+			s.recordAudit(ctx, models.AuditEntityPerson, models.AuditActionCreate, people[j].ID, people[j].ID, nil,
+				&models.PersonWithDetails{Person: *people[j], Emails: emailModels(people[j].ID, emails[j])})
+		}
+		s.invalidatePeopleLists(ctx)
+	}
+
+	return finishBulk(mode, results), nil
+}
+
+func (s *personService) BulkUpdatePeople(ctx context.Context, items []models.BulkUpdateItem, mode string) (*models.BulkResponse, error) {
+	if err := validateBulk(len(items), mode); err != nil {
+		return nil, err
+	}
+
+	results := newBulkResults(len(items))
+	seen := make(map[int]bool, len(items))
+	var ids []int
+	var reqs []*models.UpdatePersonRequest
+	var indexes []int
+	for i := range items {
+		item := &items[i]
+		results[i].ID = item.ID
+		if err := item.Validate(); err != nil {
+			results[i].Error = toAppError(err)
+			continue
+		}
+		if item.FirstName == nil && item.LastName == nil && item.MiddleName == nil &&
+			item.Age == nil && item.Gender == nil && item.Nationality == nil {
+			results[i].Error = errors.NewValidationError("No fields to update")
+			continue
+		}
+		if seen[item.ID] {
+			results[i].Error = errors.NewValidationError(fmt.Sprintf("Duplicate id %d in batch", item.ID))
+			continue
+		}
+		seen[item.ID] = true
+		ids = append(ids, item.ID)
+		reqs = append(reqs, &item.UpdatePersonRequest)
+		indexes = append(indexes, i)
+	}
+
+	if mode == models.BulkModeAtomic && len(indexes) < len(items) {
+		return finishBulk(mode, markRolledBack(results)), nil
+	}
+
+	if len(ids) > 0 {
+		// Снимок "до" нужен только для аудита, поэтому ошибки чтения не критичны
+		before := make(map[int]*models.Person, len(ids))
+		for _, id := range ids {
+			if person, err := s.repo.GetByID(ctx, id); err == nil {
+				before[id] = person
+			}
+		}
+
+		errs := s.repo.BulkUpdate(ctx, ids, reqs, mode == models.BulkModeAtomic)
+		for j, i := range indexes {
+			if errs[j] != nil {
+				results[i].Error = toAppError(errs[j])
+				continue
+			}
+			s.invalidatePersonCache(ctx, ids[j])
+			if after, err := s.repo.GetByID(ctx, ids[j]); err == nil {
+				s.recordAudit(ctx, models.AuditEntityPerson, models.AuditActionUpdate, ids[j], ids[j], before[ids[j]], after)
+			}
+		}
+	}
+
+	return finishBulk(mode, results), nil
+}
+
+func (s *personService) BulkDeletePeople(ctx context.Context, ids []int, mode string) (*models.BulkResponse, error) {
+	if err := validateBulk(len(ids), mode); err != nil {
+		return nil, err
+	}
+
+	results := newBulkResults(len(ids))
+	seen := make(map[int]bool, len(ids))
+	var unique []int
+	var indexes []int
+	for i, id := range ids {
+		results[i].ID = id
+		if seen[id] {
+			results[i].Error = errors.NewValidationError(fmt.Sprintf("Duplicate id %d in batch", id))
+			continue
+		}
+		seen[id] = true
+		unique = append(unique, id)
+		indexes = append(indexes, i)
+	}
+
+	if mode == models.BulkModeAtomic && len(indexes) < len(ids) {
+		return finishBulk(mode, markRolledBack(results)), nil
+	}
+
+	before := make(map[int]*models.Person, len(unique))
+	for _, id := range unique {
+		if person, err := s.repo.GetByID(ctx, id); err == nil {
+			before[id] = person
+		}
+	}
+
+	errs := s.repo.BulkDelete(ctx, unique, mode == models.BulkModeAtomic)
+	for j, i := range indexes {
+		if errs[j] != nil {
+			results[i].Error = toAppError(errs[j])
+			continue
+		}
+		s.invalidatePersonCache(ctx, unique[j])
+		s.recordAudit(ctx, models.AuditEntityPerson, models.AuditActionDelete, unique[j], unique[j], before[unique[j]], nil)
+	}
+
+	return finishBulk(mode, results), nil
+}
+
+func validateBulk(count int, mode string) error {
+	if mode != models.BulkModeAtomic && mode != models.BulkModeBestEffort {
+		return errors.NewValidationError("mode must be " + models.BulkModeAtomic + " or " + models.BulkModeBestEffort)
+	}
+	if count == 0 {
+		return errors.NewValidationError("Batch is empty")
+	}
+	if count > models.MaxBulkItems {
+		return errors.NewValidationError(fmt.Sprintf("Batch cannot contain more than %d items", models.MaxBulkItems))
+	}
+	return nil
+}
+
+func newBulkResults(n int) []models.BulkItemResult {
+	results := make([]models.BulkItemResult, n)
+	for i := range results {
+		results[i].Index = i
+	}
+	return results
+}
+
+// markRolledBack помечает успешные элементы атомарного пакета как откаченные
+func markRolledBack(results []models.BulkItemResult) []models.BulkItemResult {
+	for i := range results {
+		if results[i].Error == nil {
+			results[i].Error = errors.NewAppError(http.StatusConflict, "Rolled back", "Another item in the all-or-nothing batch failed")
+		}
+	}
+	return results
+}
+
+func finishBulk(mode string, results []models.BulkItemResult) *models.BulkResponse {
+	response := &models.BulkResponse{Mode: mode, Results: results}
+	for _, result := range results {
+		if result.Error != nil {
+			response.Failed++
+		} else {
+			response.Succeeded++
+		}
+	}
+	return response
+}
+
+func emailModels(personID int, emails []string) []models.Email {
+	result := make([]models.Email, len(emails))
+	for i, email := range emails {
+		result[i] = models.Email{PersonID: personID, Email: email, IsPrimary: i == 0}
+	}
+	return result
+}
+
+func toAppError(err error) *errors.AppError {
+	if appErr, ok := err.(*errors.AppError); ok {
+		return appErr
+	}
+	return errors.NewInternalServerError(err.Error())
+}
@@ -0,0 +1,422 @@
+package service
+
+import (
+	"PeopleCRUD/internal/cache"
+	"PeopleCRUD/internal/models"
+	"PeopleCRUD/internal/repository"
+	"PeopleCRUD/pkg/errors"
+	"context"
+	"io"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// memoryPeople - хранилище людей в памяти для проверки инвалидации кэша сервисом.
+// Методы, которые тесты не вызывают, достаются от nil-интерфейса и паникуют
+type memoryPeople struct {
+	repository.PersonRepository
+
+	mu      sync.Mutex
+	people  map[int]*models.Person
+	emails  map[int][]models.Email
+	links   map[[2]int]string
+	emailID int
+}
+
+func newMemoryPeople(people ...models.Person) *memoryPeople {
+	r := &memoryPeople{
+		people: make(map[int]*models.Person),
+		emails: make(map[int][]models.Email),
+		links:  make(map[[2]int]string),
+	}
+	for i := range people {
+		r.people[people[i].ID] = &people[i]
+	}
+	return r
+}
+
+func (r *memoryPeople) befriend(a, b int) {
+	r.links[[2]int{a, b}] = models.FriendshipAccepted
+	r.links[[2]int{b, a}] = models.FriendshipAccepted
+}
+
+func (r *memoryPeople) alive(id int) (*models.Person, bool) {
+	person, ok := r.people[id]
+	return person, ok && person.DeletedAt == nil
+}
+
+func (r *memoryPeople) GetByID(_ context.Context, id int) (*models.Person, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	person, ok := r.alive(id)
+	if !ok {
+		return nil, errors.NewNotFoundError("Person not found")
+	}
+	copied := *person
+	return &copied, nil
+}
+
+func (r *memoryPeople) GetAll(_ context.Context, _ models.PeopleFilter, limit, offset int) ([]*models.Person, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var people []*models.Person
+	for id := range r.people {
+		if person, ok := r.alive(id); ok {
+			copied := *person
+			people = append(people, &copied)
+		}
+	}
+	sort.Slice(people, func(i, j int) bool { return people[i].ID < people[j].ID })
+	if offset >= len(people) {
+		return nil, nil
+	}
+	return people[offset:min(len(people), offset+limit)], nil
+}
+
+func (r *memoryPeople) GetCount(ctx context.Context, filter models.PeopleFilter) (int, error) {
+	people, err := r.GetAll(ctx, filter, len(r.people), 0)
+	return len(people), err
+}
+
+func (r *memoryPeople) Update(_ context.Context, id int, req *models.UpdatePersonRequest) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	person, ok := r.alive(id)
+	if !ok {
+		return errors.NewNotFoundError("Person not found")
+	}
+	if req.FirstName != nil {
+		person.FirstName = *req.FirstName
+	}
+	if req.LastName != nil {
+		person.LastName = *req.LastName
+	}
+	return nil
+}
+
+func (r *memoryPeople) Delete(_ context.Context, id int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	person, ok := r.alive(id)
+	if !ok {
+		return errors.NewNotFoundError("Person not found")
+	}
+	now := time.Now()
+	person.DeletedAt = &now
+	return nil
+}
+
+func (r *memoryPeople) Restore(_ context.Context, id int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	person, ok := r.people[id]
+	if !ok || person.DeletedAt == nil {
+		return errors.NewNotFoundError("Deleted person not found")
+	}
+	person.DeletedAt = nil
+	return nil
+}
+
+func (r *memoryPeople) GetEmails(_ context.Context, personID int) ([]models.Email, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]models.Email{}, r.emails[personID]...), nil
+}
+
+func (r *memoryPeople) AddEmail(_ context.Context, personID int, email string, isPrimary bool) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.emailID++
+	r.emails[personID] = append(r.emails[personID], models.Email{ID: r.emailID, PersonID: personID, Email: email, IsPrimary: isPrimary})
+	return r.emailID, nil
+}
+
+// GetFriends, как и настоящий репозиторий, не отдает удаленных друзей
+func (r *memoryPeople) GetFriends(_ context.Context, personID int) ([]models.Person, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	friends := []models.Person{}
+	for link, status := range r.links {
+		if link[0] != personID || status != models.FriendshipAccepted {
+			continue
+		}
+		if friend, ok := r.alive(link[1]); ok {
+			friends = append(friends, *friend)
+		}
+	}
+	sort.Slice(friends, func(i, j int) bool { return friends[i].ID < friends[j].ID })
+	return friends, nil
+}
+
+func (r *memoryPeople) RemoveFriend(_ context.Context, personID, friendID int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	link := [2]int{personID, friendID}
+	if r.links[link] != models.FriendshipAccepted {
+		return errors.NewNotFoundError("Friendship not found")
+	}
+	delete(r.links, link)
+	return nil
+}
+
+func (r *memoryPeople) GetFriendshipStatus(_ context.Context, personID, friendID int) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.links[[2]int{personID, friendID}], nil
+}
+
+func (r *memoryPeople) CreateFriendRequest(_ context.Context, fromID, toID int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.links[[2]int{fromID, toID}] = models.FriendshipPending
+	return nil
+}
+
+func (r *memoryPeople) AcceptFriendRequest(_ context.Context, fromID, toID int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.links[[2]int{fromID, toID}] != models.FriendshipPending {
+		return errors.NewNotFoundError("Friend request not found")
+	}
+	r.befriend(fromID, toID)
+	return nil
+}
+
+// Merge переносит email'ы и дружбы otherID на keepID и удаляет otherID
+func (r *memoryPeople) Merge(_ context.Context, keepID, otherID int) ([]int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.emails[keepID] = append(r.emails[keepID], r.emails[otherID]...)
+	delete(r.emails, otherID)
+
+	var related []int
+	for link, status := range r.links {
+		if link[0] != otherID {
+			continue
+		}
+		delete(r.links, link)
+		delete(r.links, [2]int{link[1], otherID})
+		if link[1] != keepID {
+			r.links[[2]int{keepID, link[1]}] = status
+			r.links[[2]int{link[1], keepID}] = status
+			related = append(related, link[1])
+		}
+	}
+	now := time.Now()
+	r.people[otherID].DeletedAt = &now
+	return related, nil
+}
+
+func newCachedPersonService(t *testing.T, repo repository.PersonRepository) PersonService {
+	t.Helper()
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	memory := cache.NewMemoryCache(cache.Options{})
+	t.Cleanup(memory.Stop)
+	return NewPersonService(repo, nil, cache.NewLoader(memory, nil, logger), logger)
+}
+
+func staleTestPeople() *memoryPeople {
+	repo := newMemoryPeople(
+		models.Person{ID: 1, FirstName: "Ivan", LastName: "Petrov"},
+		models.Person{ID: 2, FirstName: "Anna", LastName: "Petrova"},
+		models.Person{ID: 3, FirstName: "Oleg", LastName: "Sidorov"},
+		models.Person{ID: 4, FirstName: "Ivan", LastName: "Petrov"},
+	)
+	repo.befriend(1, 2)
+	repo.befriend(4, 3)
+	repo.emails[4] = []models.Email{{ID: 100, PersonID: 4, Email: "ivan@example.com"}}
+	repo.emailID = 100
+	return repo
+}
+
+// warm загружает в кэш карточки людей и первую страницу списка
+func warm(t *testing.T, svc PersonService, ids ...int) {
+	t.Helper()
+	for _, id := range ids {
+		if _, err := svc.GetPersonByID(context.Background(), id); err != nil {
+			t.Fatalf("warm person %d: %v", id, err)
+		}
+	}
+	if _, _, err := svc.GetAllPeople(context.Background(), models.PeopleFilter{}, 10, 0); err != nil {
+		t.Fatalf("warm list: %v", err)
+	}
+}
+
+func card(t *testing.T, svc PersonService, id int) *models.PersonWithDetails {
+	t.Helper()
+	person, err := svc.GetPersonByID(context.Background(), id)
+	if err != nil {
+		t.Fatalf("get person %d: %v", id, err)
+	}
+	return person
+}
+
+func listed(t *testing.T, svc PersonService, id int) *models.PersonWithDetails {
+	t.Helper()
+	people, _, err := svc.GetAllPeople(context.Background(), models.PeopleFilter{}, 10, 0)
+	if err != nil {
+		t.Fatalf("list people: %v", err)
+	}
+	for _, person := range people {
+		if person != nil && person.ID == id {
+			return person
+		}
+	}
+	return nil
+}
+
+func friendIDs(person *models.PersonWithDetails) []int {
+	ids := []int{}
+	for _, friend := range person.Friends {
+		ids = append(ids, friend.ID)
+	}
+	return ids
+}
+
+func sameIDs(got []int, want ...int) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestPersonServiceUpdateInvalidatesCards(t *testing.T) {
+	repo := staleTestPeople()
+	svc := newCachedPersonService(t, repo)
+	warm(t, svc, 1, 2)
+
+	name := "Ivan-Updated"
+	if _, err := svc.UpdatePerson(context.Background(), 1, &models.UpdatePersonRequest{FirstName: &name}); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := card(t, svc, 1).FirstName; got != name {
+		t.Errorf("own card shows %q", got)
+	}
+	if friends := card(t, svc, 2).Friends; len(friends) != 1 || friends[0].FirstName != name {
+		t.Errorf("friend's card shows %+v", friends)
+	}
+	if person := listed(t, svc, 1); person == nil || person.FirstName != name {
+		t.Errorf("list shows %+v", person)
+	}
+}
+
+func TestPersonServiceAddEmailInvalidatesCards(t *testing.T) {
+	repo := staleTestPeople()
+	svc := newCachedPersonService(t, repo)
+	warm(t, svc, 1)
+
+	if err := svc.AddEmail(context.Background(), 1, "new@example.com", true); err != nil {
+		t.Fatal(err)
+	}
+
+	if emails := card(t, svc, 1).Emails; len(emails) != 1 || emails[0].Email != "new@example.com" {
+		t.Errorf("card shows emails %+v", emails)
+	}
+	if person := listed(t, svc, 1); person == nil || len(person.Emails) != 1 {
+		t.Errorf("list shows %+v", person)
+	}
+}
+
+func TestPersonServiceFriendChangesInvalidateCards(t *testing.T) {
+	repo := staleTestPeople()
+	svc := newCachedPersonService(t, repo)
+	ctx := context.Background()
+	warm(t, svc, 1, 2, 3)
+
+	if _, err := svc.SendFriendRequest(ctx, 1, 3); err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.AcceptFriendRequest(ctx, 3, 1); err != nil {
+		t.Fatal(err)
+	}
+	if ids := friendIDs(card(t, svc, 1)); !sameIDs(ids, 2, 3) {
+		t.Errorf("after accepting, person 1 has friends %v", ids)
+	}
+	if ids := friendIDs(card(t, svc, 3)); !sameIDs(ids, 1, 4) {
+		t.Errorf("after accepting, person 3 has friends %v", ids)
+	}
+
+	if err := svc.RemoveFriend(ctx, 1, 2); err != nil {
+		t.Fatal(err)
+	}
+	if ids := friendIDs(card(t, svc, 1)); !sameIDs(ids, 3) {
+		t.Errorf("after removal, person 1 has friends %v", ids)
+	}
+	if ids := friendIDs(card(t, svc, 2)); !sameIDs(ids) {
+		t.Errorf("after removal, person 2 has friends %v", ids)
+	}
+	if person := listed(t, svc, 2); person == nil || len(person.Friends) != 0 {
+		t.Errorf("list shows %+v", person)
+	}
+}
+
+func TestPersonServiceDeleteAndRestoreInvalidateCards(t *testing.T) {
+	repo := staleTestPeople()
+	svc := newCachedPersonService(t, repo)
+	ctx := context.Background()
+	warm(t, svc, 1, 2)
+
+	if err := svc.DeletePerson(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.GetPersonByID(ctx, 1); err == nil {
+		t.Error("deleted person is still served")
+	}
+	if ids := friendIDs(card(t, svc, 2)); !sameIDs(ids) {
+		t.Errorf("friend's card still lists the deleted person: %v", ids)
+	}
+	if listed(t, svc, 1) != nil {
+		t.Error("list still contains the deleted person")
+	}
+
+	// Карточка друга загружена без удаленного человека и не помечена его тегом
+	warm(t, svc, 2)
+	if _, err := svc.RestorePerson(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+	if ids := friendIDs(card(t, svc, 2)); !sameIDs(ids, 1) {
+		t.Errorf("friend's card misses the restored person: %v", ids)
+	}
+	if listed(t, svc, 1) == nil {
+		t.Error("list misses the restored person")
+	}
+}
+
+func TestPersonServiceMergeInvalidatesCards(t *testing.T) {
+	repo := staleTestPeople()
+	svc := newCachedPersonService(t, repo)
+	ctx := context.Background()
+	warm(t, svc, 1, 2, 3, 4)
+
+	if _, err := svc.MergePeople(ctx, 1, 4); err != nil {
+		t.Fatal(err)
+	}
+
+	kept := card(t, svc, 1)
+	if len(kept.Emails) != 1 || kept.Emails[0].Email != "ivan@example.com" {
+		t.Errorf("kept person has emails %+v", kept.Emails)
+	}
+	if ids := friendIDs(kept); !sameIDs(ids, 2, 3) {
+		t.Errorf("kept person has friends %v", ids)
+	}
+	if ids := friendIDs(card(t, svc, 3)); !sameIDs(ids, 1) {
+		t.Errorf("friend of the merged person has friends %v", ids)
+	}
+	if _, err := svc.GetPersonByID(ctx, 4); err == nil {
+		t.Error("merged person is still served")
+	}
+	if listed(t, svc, 4) != nil {
+		t.Error("list still contains the merged person")
+	}
+}
@@ -0,0 +1,152 @@
+package service
+
+import (
+	"PeopleCRUD/internal/models"
+	"PeopleCRUD/pkg/errors"
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	// Минимальное триграммное сходство нормализованных имен, при котором люди считаются похожими
+	duplicateNameThreshold = 0.6
+	maxDuplicateCandidates = 10
+	maxDuplicatePairs      = 5000
+)
+
+// normalizeName повторяет выражение колонки people.normalized_name
+func normalizeName(firstName, lastName string) string {
+	name := strings.ToLower(strings.TrimSpace(firstName) + " " + strings.TrimSpace(lastName))
+	return strings.ReplaceAll(name, "ё", "е")
+}
+
+func (s *personService) FindDuplicates(ctx context.Context, req *models.CreatePersonRequest) ([]models.DuplicateCandidate, error) {
+	candidates, err := s.repo.FindDuplicateCandidates(ctx, normalizeName(req.FirstName, req.LastName), req.Emails,
+		duplicateNameThreshold, maxDuplicateCandidates)
+	if err != nil {
+		s.log(ctx).WithError(err).Error("Failed to find duplicate candidates")
+		return nil, err
+	}
+	return candidates, nil
+}
+
+// GetDuplicateClusters объединяет попарно похожих людей в кластеры (транзитивно)
+func (s *personService) GetDuplicateClusters(ctx context.Context) ([]models.DuplicateCluster, error) {
+	pairs, err := s.repo.FindDuplicatePairs(ctx, duplicateNameThreshold, maxDuplicatePairs)
+	if err != nil {
+		s.log(ctx).WithError(err).Error("Failed to find duplicate pairs")
+		return nil, err
+	}
+
+	parent := map[int]int{}
+	var find func(id int) int
+	find = func(id int) int {
+		if _, ok := parent[id]; !ok {
+			parent[id] = id
+		}
+		if parent[id] != id {
+			parent[id] = find(parent[id])
+		}
+		return parent[id]
+	}
+
+	for _, pair := range pairs {
+		a, b := find(pair.PersonID), find(pair.OtherID)
+		if a != b {
+			parent[max(a, b)] = min(a, b)
+		}
+	}
+
+	members := map[int][]int{}
+	scores := map[int]float64{}
+	for _, pair := range pairs {
+		root := find(pair.PersonID)
+		scores[root] = max(scores[root], pair.Score)
+	}
+	ids := make([]int, 0, len(parent))
+	for id := range parent {
+		root := find(id)
+		members[root] = append(members[root], id)
+		ids = append(ids, id)
+	}
+
+	people, err := s.repo.GetByIDs(ctx, ids)
+	if err != nil {
+		s.log(ctx).WithError(err).Error("Failed to get duplicate people")
+		return nil, err
+	}
+
+	clusters := make([]models.DuplicateCluster, 0, len(members))
+	for root, memberIDs := range members {
+		sort.Ints(memberIDs)
+		cluster := models.DuplicateCluster{Score: scores[root]}
+		for _, id := range memberIDs {
+			if person, ok := people[id]; ok {
+				cluster.People = append(cluster.People, person)
+			}
+		}
+		if len(cluster.People) > 1 {
+			clusters = append(clusters, cluster)
+		}
+	}
+
+	sort.Slice(clusters, func(i, j int) bool {
+		if clusters[i].Score != clusters[j].Score {
+			return clusters[i].Score > clusters[j].Score
+		}
+		return clusters[i].People[0].ID < clusters[j].People[0].ID
+	})
+	return clusters, nil
+}
+
+// MergePeople сливает otherID в keepID: email'ы и дружбы переходят к keepID, otherID удаляется (мягко)
+func (s *personService) MergePeople(ctx context.Context, keepID, otherID int) (*models.PersonWithDetails, error) {
+	if keepID == otherID {
+		return nil, errors.NewValidationError("Cannot merge a person with themselves")
+	}
+
+	keepBefore, err := s.repo.GetByID(ctx, keepID)
+	if err != nil {
+		return nil, err
+	}
+	otherBefore, err := s.repo.GetByID(ctx, otherID)
+	if err != nil {
+		return nil, err
+	}
+
+	relatedIDs, err := s.repo.Merge(ctx, keepID, otherID)
+	if err != nil {
+		s.log(ctx).WithError(err).Error("Failed to merge people")
+		return nil, err
+	}
+
+	s.invalidatePersonCache(ctx, keepID, otherID)
+	s.invalidatePersonRefs(ctx, relatedIDs...)
+
+	result, err := s.GetPersonByID(ctx, keepID)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := diffFields(keepBefore, &result.Person)
+	changes["merged_from"] = models.FieldChange{New: otherID}
+	s.recordAuditChanges(ctx, models.AuditEntityPerson, models.AuditActionMerge, keepID, keepID, changes)
+	s.recordAuditChanges(ctx, models.AuditEntityPerson, models.AuditActionMerge, otherID, otherID,
+		map[string]models.FieldChange{"merged_into": {New: keepID}})
+	s.recordAudit(ctx, models.AuditEntityPerson, models.AuditActionDelete, otherID, otherID, otherBefore, nil)
+
+	return result, nil
+}
+
+func duplicateConflict(candidates []models.DuplicateCandidate) *errors.AppError {
+	ids := make([]string, len(candidates))
+	for i, candidate := range candidates {
+		ids[i] = strconv.Itoa(candidate.Person.ID)
+	}
+	return errors.NewAppError(http.StatusConflict, "Possible duplicate person",
+		fmt.Sprintf("Similar people already exist: %s", strings.Join(ids, ", ")))
+}
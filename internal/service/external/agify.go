@@ -1,11 +1,14 @@
 package external
 
 import (
+	"PeopleCRUD/internal/metrics"
 	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"time"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 )
 
 type AgifyResponse struct {
@@ -22,7 +25,8 @@ type AgifyClient struct {
 func NewAgifyClient() *AgifyClient {
 	return &AgifyClient{
 		client: &http.Client{
-			Timeout: 10 * time.Second,
+			Timeout:   10 * time.Second,
+			Transport: otelhttp.NewTransport(metrics.Transport("agify", nil), otelhttp.WithSpanNameFormatter(spanName)),
 		},
 		baseURL: "https://api.agify.io",
 	}
@@ -1,11 +1,14 @@
 package external
 
 import (
+	"PeopleCRUD/internal/metrics"
 	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"time"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 )
 
 type GenderizeResponse struct {
@@ -23,7 +26,8 @@ type GenderizeClient struct {
 func NewGenderizeClient() *GenderizeClient {
 	return &GenderizeClient{
 		client: &http.Client{
-			Timeout: 10 * time.Second,
+			Timeout:   10 * time.Second,
+			Transport: otelhttp.NewTransport(metrics.Transport("genderize", nil), otelhttp.WithSpanNameFormatter(spanName)),
 		},
 		baseURL: "https://api.genderize.io",
 	}
@@ -1,11 +1,14 @@
 package external
 
 import (
+	"PeopleCRUD/internal/metrics"
 	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"time"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 )
 
 type Country struct {
@@ -26,7 +29,8 @@ type NationalizeClient struct {
 func NewNationalizeClient() *NationalizeClient {
 	return &NationalizeClient{
 		client: &http.Client{
-			Timeout: 10 * time.Second,
+			Timeout:   10 * time.Second,
+			Transport: otelhttp.NewTransport(metrics.Transport("nationalize", nil), otelhttp.WithSpanNameFormatter(spanName)),
 		},
 		baseURL: "https://api.nationalize.io",
 	}
@@ -0,0 +1,10 @@
+package external
+
+import (
+	"net/http"
+)
+
+// spanName называет span исходящего запроса по сервису: "GET api.agify.io"
+func spanName(_ string, r *http.Request) string {
+	return r.Method + " " + r.URL.Host
+}
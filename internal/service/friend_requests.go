@@ -0,0 +1,135 @@
+package service
+
+import (
+	"PeopleCRUD/internal/models"
+	"PeopleCRUD/pkg/errors"
+	"context"
+)
+
+// SendFriendRequest создает заявку fromID -> toID. Если встречная заявка уже ждет ответа, она принимается
+func (s *personService) SendFriendRequest(ctx context.Context, fromID, toID int) (*models.FriendRequest, error) {
+	if fromID == toID {
+		return nil, errors.NewValidationError("Cannot send a friend request to yourself")
+	}
+
+	if _, err := s.repo.GetByID(ctx, fromID); err != nil {
+		return nil, err
+	}
+
+	target, err := s.repo.GetByID(ctx, toID)
+	if err != nil {
+		return nil, err
+	}
+
+	status, err := s.repo.GetFriendshipStatus(ctx, fromID, toID)
+	if err != nil {
+		s.log(ctx).WithError(err).Error("Failed to get friendship status")
+		return nil, err
+	}
+
+	switch status {
+	case models.FriendshipAccepted:
+		return nil, errors.NewConflictError("Friendship already exists")
+	case models.FriendshipPending:
+		return nil, errors.NewConflictError("Friend request already exists")
+	}
+
+	reverse, err := s.repo.GetFriendshipStatus(ctx, toID, fromID)
+	if err != nil {
+		s.log(ctx).WithError(err).Error("Failed to get friendship status")
+		return nil, err
+	}
+
+	request := &models.FriendRequest{FromID: fromID, ToID: toID, Person: *target}
+
+	if reverse == models.FriendshipPending {
+		if err := s.AcceptFriendRequest(ctx, fromID, toID); err != nil {
+			return nil, err
+		}
+		request.Status = models.FriendshipAccepted
+		return request, nil
+	}
+
+	if err := s.repo.CreateFriendRequest(ctx, fromID, toID); err != nil {
+		s.log(ctx).WithError(err).Error("Failed to create friend request")
+		return nil, err
+	}
+
+	request.Status = models.FriendshipPending
+	s.recordFriendRequestAudit(ctx, models.AuditActionCreate, fromID, toID, "", models.FriendshipPending)
+	return request, nil
+}
+
+// AcceptFriendRequest принимает входящую заявку от fromID к personID
+func (s *personService) AcceptFriendRequest(ctx context.Context, personID, fromID int) error {
+	if err := s.repo.AcceptFriendRequest(ctx, fromID, personID); err != nil {
+		s.log(ctx).WithError(err).Error("Failed to accept friend request")
+		return err
+	}
+
+	s.recordFriendRequestAudit(ctx, models.AuditActionUpdate, fromID, personID, models.FriendshipPending, models.FriendshipAccepted)
+	s.recordAudit(ctx, models.AuditEntityFriendship, models.AuditActionCreate, fromID, personID, nil,
+		map[string]interface{}{"person_id": personID, "friend_id": fromID, "status": models.FriendshipAccepted})
+	s.invalidatePersonRefs(ctx, personID, fromID)
+	return nil
+}
+
+// RejectFriendRequest отклоняет входящую заявку от fromID к personID
+func (s *personService) RejectFriendRequest(ctx context.Context, personID, fromID int) error {
+	if err := s.repo.RejectFriendRequest(ctx, fromID, personID); err != nil {
+		s.log(ctx).WithError(err).Error("Failed to reject friend request")
+		return err
+	}
+
+	s.recordFriendRequestAudit(ctx, models.AuditActionUpdate, fromID, personID, models.FriendshipPending, models.FriendshipRejected)
+	return nil
+}
+
+// CancelFriendRequest отзывает исходящую заявку personID -> toID
+func (s *personService) CancelFriendRequest(ctx context.Context, personID, toID int) error {
+	if err := s.repo.CancelFriendRequest(ctx, personID, toID); err != nil {
+		s.log(ctx).WithError(err).Error("Failed to cancel friend request")
+		return err
+	}
+
+	s.recordFriendRequestAudit(ctx, models.AuditActionDelete, personID, toID, models.FriendshipPending, "")
+	return nil
+}
+
+func (s *personService) GetIncomingFriendRequests(ctx context.Context, personID int) ([]models.FriendRequest, error) {
+	return s.getFriendRequests(ctx, personID, true)
+}
+
+func (s *personService) GetOutgoingFriendRequests(ctx context.Context, personID int) ([]models.FriendRequest, error) {
+	return s.getFriendRequests(ctx, personID, false)
+}
+
+func (s *personService) getFriendRequests(ctx context.Context, personID int, incoming bool) ([]models.FriendRequest, error) {
+	if _, err := s.repo.GetByID(ctx, personID); err != nil {
+		return nil, err
+	}
+
+	requests, err := s.repo.GetFriendRequests(ctx, personID, incoming)
+	if err != nil {
+		s.log(ctx).WithError(err).Error("Failed to get friend requests")
+		return nil, err
+	}
+
+	if requests == nil {
+		requests = []models.FriendRequest{}
+	}
+	return requests, nil
+}
+
+// recordFriendRequestAudit пишет изменение статуса связи fromID -> toID в историю отправителя
+func (s *personService) recordFriendRequestAudit(ctx context.Context, action string, fromID, toID int, oldStatus, newStatus string) {
+	var before, after interface{}
+	if oldStatus != "" {
+		before = map[string]interface{}{"person_id": fromID, "friend_id": toID, "status": oldStatus}
+	}
+	if newStatus != "" {
+		after = map[string]interface{}{"person_id": fromID, "friend_id": toID, "status": newStatus}
+	}
+
+	s.recordAudit(ctx, models.AuditEntityFriendship, action, toID, fromID, before, after)
+}
@@ -0,0 +1,129 @@
+package service
+
+import (
+	"PeopleCRUD/internal/models"
+	"PeopleCRUD/pkg/errors"
+	"context"
+	"fmt"
+)
+
+const (
+	defaultFriendPathDepth  = 4
+	maxFriendPathDepth      = 6
+	defaultSuggestionsLimit = 10
+	maxSuggestionsLimit     = 100
+	defaultEgoDepth         = 2
+	maxEgoDepth             = 6
+)
+
+func (s *personService) GetMutualFriends(ctx context.Context, personID, otherID int) ([]models.Person, error) {
+	if _, err := s.repo.GetByID(ctx, personID); err != nil {
+		return nil, err
+	}
+
+	if _, err := s.repo.GetByID(ctx, otherID); err != nil {
+		return nil, err
+	}
+
+	friends, err := s.repo.GetMutualFriends(ctx, personID, otherID)
+	if err != nil {
+		s.log(ctx).WithError(err).Error("Failed to get mutual friends")
+		return nil, err
+	}
+
+	if friends == nil {
+		friends = []models.Person{}
+	}
+	return friends, nil
+}
+
+// GetFriendPath возвращает кратчайшую цепочку дружбы, maxDepth <= 0 - глубина по умолчанию
+func (s *personService) GetFriendPath(ctx context.Context, personID, otherID, maxDepth int) (*models.FriendPath, error) {
+	if maxDepth <= 0 {
+		maxDepth = defaultFriendPathDepth
+	}
+	if maxDepth > maxFriendPathDepth {
+		return nil, errors.NewValidationError(fmt.Sprintf("max_depth cannot exceed %d", maxFriendPathDepth))
+	}
+
+	if _, err := s.repo.GetByID(ctx, personID); err != nil {
+		return nil, err
+	}
+
+	if _, err := s.repo.GetByID(ctx, otherID); err != nil {
+		return nil, err
+	}
+
+	ids, err := s.repo.FindFriendPath(ctx, personID, otherID, maxDepth)
+	if err != nil {
+		s.log(ctx).WithError(err).Error("Failed to find friend path")
+		return nil, err
+	}
+
+	if ids == nil {
+		return nil, errors.NewNotFoundError(fmt.Sprintf("No friendship path within %d steps", maxDepth))
+	}
+
+	people, err := s.repo.GetByIDs(ctx, ids)
+	if err != nil {
+		s.log(ctx).WithError(err).Error("Failed to get people on path")
+		return nil, err
+	}
+
+	path := &models.FriendPath{
+		Degrees: len(ids) - 1,
+		People:  make([]models.Person, 0, len(ids)),
+	}
+	for _, id := range ids {
+		path.People = append(path.People, people[id])
+	}
+
+	return path, nil
+}
+
+func (s *personService) GetFriendSuggestions(ctx context.Context, personID, limit int) ([]models.FriendSuggestion, error) {
+	if limit <= 0 {
+		limit = defaultSuggestionsLimit
+	}
+	if limit > maxSuggestionsLimit {
+		limit = maxSuggestionsLimit
+	}
+
+	if _, err := s.repo.GetByID(ctx, personID); err != nil {
+		return nil, err
+	}
+
+	suggestions, err := s.repo.GetFriendSuggestions(ctx, personID, limit)
+	if err != nil {
+		s.log(ctx).WithError(err).Error("Failed to get friend suggestions")
+		return nil, err
+	}
+
+	if suggestions == nil {
+		suggestions = []models.FriendSuggestion{}
+	}
+	return suggestions, nil
+}
+
+// ExportGraph потоково отдает граф дружбы. egoID != nil ограничивает его окрестностью человека радиусом depth.
+// Ошибки проверки возвращаются до первого вызова nodeFn
+func (s *personService) ExportGraph(ctx context.Context, egoID *int, depth int, nodeFn func(*models.Person) error, edgeFn func(*models.GraphEdge) error) error {
+	if egoID != nil {
+		if depth <= 0 {
+			depth = defaultEgoDepth
+		}
+		if depth > maxEgoDepth {
+			return errors.NewValidationError(fmt.Sprintf("depth cannot exceed %d", maxEgoDepth))
+		}
+
+		if _, err := s.repo.GetByID(ctx, *egoID); err != nil {
+			return err
+		}
+	}
+
+	if err := s.repo.StreamGraph(ctx, egoID, depth, nodeFn, edgeFn); err != nil {
+		s.log(ctx).WithError(err).Error("Failed to export graph")
+		return err
+	}
+	return nil
+}
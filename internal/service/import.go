@@ -0,0 +1,137 @@
+package service
+
+import (
+	"PeopleCRUD/internal/importer"
+	"PeopleCRUD/internal/models"
+	"PeopleCRUD/pkg/errors"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"io"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	importBatchSize = 500
+	// В отчете храним ограниченное число отказов, чтобы огромный битый файл не съел память
+	maxReportRejections = 10000
+)
+
+// ImportPeople читает файл построчно и создает людей пачками в режиме best-effort.
+// При dryRun только прогоняет валидацию. Отклоненные строки сохраняются в отчет.
+func (s *personService) ImportPeople(ctx context.Context, format string, source io.Reader, mapping map[string]string, dryRun bool) (*models.ImportResult, error) {
+	reader, err := importer.NewReader(format, source, mapping)
+	if err != nil {
+		return nil, errors.NewValidationError(err.Error())
+	}
+
+	result := &models.ImportResult{DryRun: dryRun}
+	var rejections []models.ImportRejection
+	reject := func(line int, reason, raw string) {
+		result.Rejected++
+		if len(rejections) < maxReportRejections {
+			rejections = append(rejections, models.ImportRejection{Line: line, Reason: reason, Raw: raw})
+		}
+	}
+
+	var batch []*importer.Record
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		reqs := make([]models.CreatePersonRequest, len(batch))
+		for i, record := range batch {
+			reqs[i] = record.Request
+		}
+
+		response, err := s.BulkCreatePeople(ctx, reqs, models.BulkModeBestEffort)
+		if err != nil {
+			return err
+		}
+		for _, item := range response.Results {
+			record := batch[item.Index]
+			if item.Error != nil {
+				reject(record.Line, bulkErrorReason(item.Error), record.Raw)
+			} else {
+				result.Accepted++
+			}
+		}
+		batch = batch[:0]
+		return nil
+	}
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, errors.NewInternalServerError("Import interrupted: " + err.Error())
+		}
+
+		record, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			s.log(ctx).WithError(err).Error("Failed to read import file")
+			return nil, errors.NewValidationError("Failed to read import file: " + err.Error())
+		}
+
+		result.Total++
+		if record.Err != nil {
+			reject(record.Line, record.Err.Error(), record.Raw)
+			continue
+		}
+		if err := record.Request.Validate(); err != nil {
+			reject(record.Line, bulkErrorReason(toAppError(err)), record.Raw)
+			continue
+		}
+		if dryRun {
+			result.Accepted++
+			continue
+		}
+
+		batch = append(batch, record)
+		if len(batch) >= importBatchSize {
+			if err := flush(); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+
+	if len(rejections) > 0 {
+		reportID := newReportID()
+		// Люди уже созданы: без отчета клиент все равно получает счетчики и не повторяет импорт
+		if err := s.repo.SaveImportReport(ctx, reportID, rejections); err != nil {
+			s.log(ctx).WithError(err).Error("Failed to save import report")
+		} else {
+			result.ReportID = reportID
+		}
+	}
+
+	s.log(ctx).WithFields(logrus.Fields{
+		"dry_run":  dryRun,
+		"total":    result.Total,
+		"accepted": result.Accepted,
+		"rejected": result.Rejected,
+	}).Info("People import finished")
+	return result, nil
+}
+
+func (s *personService) GetImportReport(ctx context.Context, reportID string) ([]models.ImportRejection, error) {
+	return s.repo.GetImportReport(ctx, reportID)
+}
+
+func bulkErrorReason(err *errors.AppError) string {
+	if err.Details != "" {
+		return err.Message + ": " + err.Details
+	}
+	return err.Message
+}
+
+func newReportID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,61 @@
+package service
+
+import (
+	"PeopleCRUD/internal/models"
+	"PeopleCRUD/internal/reqctx"
+	"PeopleCRUD/pkg/errors"
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// storedReports хранит отчеты импорта по тенантам, как таблица import_reports
+type storedReports struct {
+	*memoryPeople
+	reports map[string][]models.ImportRejection
+}
+
+func (r *storedReports) SaveImportReport(ctx context.Context, id string, rejections []models.ImportRejection) error {
+	r.reports[reqctx.Tenant(ctx)+"/"+id] = rejections
+	return nil
+}
+
+func (r *storedReports) GetImportReport(ctx context.Context, id string) ([]models.ImportRejection, error) {
+	rejections, ok := r.reports[reqctx.Tenant(ctx)+"/"+id]
+	if !ok {
+		return nil, errors.NewNotFoundError("Import report not found or expired")
+	}
+	return rejections, nil
+}
+
+func TestImportReportIsStoredInRepository(t *testing.T) {
+	repo := &storedReports{memoryPeople: newMemoryPeople(), reports: map[string][]models.ImportRejection{}}
+	svc := newCachedPersonService(t, repo)
+	ctx := reqctx.WithTenant(context.Background(), "team-a")
+
+	file := "first_name,last_name\nIvan,Ivanov\n,Petrov\nAnna,\n"
+	result, err := svc.ImportPeople(ctx, "csv", strings.NewReader(file), nil, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Rejected != 2 || result.ReportID == "" {
+		t.Fatalf("result = %+v, want 2 rejections and a report", result)
+	}
+	if len(repo.reports) != 1 {
+		t.Fatalf("repository holds %d reports, want 1", len(repo.reports))
+	}
+
+	rejections, err := svc.GetImportReport(ctx, result.ReportID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rejections) != 2 || rejections[0].Line != 3 || rejections[1].Line != 4 {
+		t.Errorf("rejections = %+v, want lines 3 and 4", rejections)
+	}
+
+	_, err = svc.GetImportReport(reqctx.WithTenant(context.Background(), "team-b"), result.ReportID)
+	if appErr, ok := err.(*errors.AppError); !ok || appErr.Code != http.StatusNotFound {
+		t.Errorf("report of another tenant: expected 404, got %v", err)
+	}
+}
@@ -0,0 +1,37 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// purgeCutoff запоминает границу, с которой сервис вызвал PurgeDeleted
+type purgeCutoff struct {
+	*memoryPeople
+	before time.Time
+}
+
+func (r *purgeCutoff) PurgeDeleted(_ context.Context, before time.Time) ([]int, error) {
+	r.before = before
+	return nil, nil
+}
+
+func TestPurgeDeletedPeopleUsesUTCCutoff(t *testing.T) {
+	local := time.Local
+	time.Local = time.FixedZone("UTC+5", 5*60*60)
+	t.Cleanup(func() { time.Local = local })
+
+	repo := &purgeCutoff{memoryPeople: newMemoryPeople()}
+	svc := newCachedPersonService(t, repo)
+
+	if _, err := svc.PurgeDeletedPeople(context.Background(), time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if repo.before.Location() != time.UTC {
+		t.Errorf("cutoff is in %v, want UTC: the column has no zone and the offset would be dropped", repo.before.Location())
+	}
+	if want := time.Now().Add(-time.Hour); repo.before.Sub(want).Abs() > time.Minute {
+		t.Errorf("cutoff = %v, want about %v", repo.before, want)
+	}
+}
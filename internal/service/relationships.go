@@ -0,0 +1,143 @@
+package service
+
+import (
+	"PeopleCRUD/internal/models"
+	"PeopleCRUD/pkg/errors"
+	"context"
+)
+
+func (s *personService) CreateRelationship(ctx context.Context, personID int, req *models.CreateRelationshipRequest) (*models.Relationship, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+
+	if personID == req.RelatedID {
+		return nil, errors.NewValidationError("Cannot create a relationship with yourself")
+	}
+
+	if _, err := s.repo.GetByID(ctx, personID); err != nil {
+		return nil, err
+	}
+
+	if _, err := s.repo.GetByID(ctx, req.RelatedID); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.CreateRelationship(ctx, personID, req); err != nil {
+		s.log(ctx).WithError(err).Error("Failed to create relationship")
+		return nil, err
+	}
+
+	relationship, err := s.repo.GetRelationship(ctx, personID, req.RelatedID, req.Type)
+	if err != nil {
+		return nil, err
+	}
+
+	s.recordRelationshipAudit(ctx, models.AuditActionCreate, nil, relationship)
+	s.invalidatePersonRefs(ctx, personID, req.RelatedID)
+	return relationship, nil
+}
+
+// GetRelationships возвращает связи человека, relType == "" - всех типов
+func (s *personService) GetRelationships(ctx context.Context, personID int, relType string) ([]models.Relationship, error) {
+	if relType != "" {
+		if _, ok := models.RelationshipInverse[relType]; !ok {
+			return nil, errors.NewValidationError("Invalid relationship type: " + relType)
+		}
+	}
+
+	if _, err := s.repo.GetByID(ctx, personID); err != nil {
+		return nil, err
+	}
+
+	relationships, err := s.repo.GetRelationships(ctx, personID, relType)
+	if err != nil {
+		s.log(ctx).WithError(err).Error("Failed to get relationships")
+		return nil, err
+	}
+
+	if relationships == nil {
+		relationships = []models.Relationship{}
+	}
+	return relationships, nil
+}
+
+func (s *personService) UpdateRelationship(ctx context.Context, personID, relatedID int, relType string, req *models.UpdateRelationshipRequest) (*models.Relationship, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+
+	before, err := s.repo.GetRelationship(ctx, personID, relatedID, relType)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.UpdateRelationship(ctx, personID, relatedID, relType, req); err != nil {
+		s.log(ctx).WithError(err).Error("Failed to update relationship")
+		return nil, err
+	}
+
+	after, err := s.repo.GetRelationship(ctx, personID, relatedID, relType)
+	if err != nil {
+		return nil, err
+	}
+
+	s.recordRelationshipAudit(ctx, models.AuditActionUpdate, before, after)
+	s.invalidatePersonRefs(ctx, personID, relatedID)
+	return after, nil
+}
+
+func (s *personService) DeleteRelationship(ctx context.Context, personID, relatedID int, relType string) error {
+	before, err := s.repo.GetRelationship(ctx, personID, relatedID, relType)
+	if err != nil {
+		return err
+	}
+
+	if err := s.repo.DeleteRelationship(ctx, personID, relatedID, relType); err != nil {
+		s.log(ctx).WithError(err).Error("Failed to delete relationship")
+		return err
+	}
+
+	s.recordRelationshipAudit(ctx, models.AuditActionDelete, before, nil)
+	s.invalidatePersonRefs(ctx, personID, relatedID)
+	return nil
+}
+
+// recordRelationshipAudit пишет событие в историю обеих сторон, у каждой - со своим типом связи.
+// Дружба пишется как friendship, чтобы история не зависела от того, через какой API она создана
+func (s *personService) recordRelationshipAudit(ctx context.Context, action string, before, after *models.Relationship) {
+	current := after
+	if current == nil {
+		current = before
+	}
+
+	entityType := models.AuditEntityRelationship
+	if current.Type == models.RelationshipFriend {
+		entityType = models.AuditEntityFriendship
+	}
+
+	s.recordAudit(ctx, entityType, action, current.RelatedID, current.PersonID,
+		relationshipAuditFields(before, false), relationshipAuditFields(after, false))
+	s.recordAudit(ctx, entityType, action, current.PersonID, current.RelatedID,
+		relationshipAuditFields(before, true), relationshipAuditFields(after, true))
+}
+
+func relationshipAuditFields(relationship *models.Relationship, reverse bool) interface{} {
+	if relationship == nil {
+		return nil
+	}
+
+	personID, relatedID, relType := relationship.PersonID, relationship.RelatedID, relationship.Type
+	if reverse {
+		personID, relatedID, relType = relatedID, personID, models.RelationshipInverse[relType]
+	}
+
+	return map[string]interface{}{
+		"person_id":  personID,
+		"related_id": relatedID,
+		"type":       relType,
+		"weight":     relationship.Weight,
+		"started_on": relationship.StartedOn,
+		"ended_on":   relationship.EndedOn,
+	}
+}
@@ -0,0 +1,34 @@
+package service
+
+import (
+	"PeopleCRUD/internal/models"
+	"PeopleCRUD/pkg/errors"
+	"context"
+	"net/http"
+	"testing"
+)
+
+func TestCreateRelationshipDoesNotAcceptFriendRequest(t *testing.T) {
+	repo := staleTestPeople()
+	svc := newCachedPersonService(t, repo)
+	ctx := context.Background()
+
+	if err := repo.CreateFriendRequest(ctx, 1, 3); err != nil {
+		t.Fatal(err)
+	}
+
+	// Получатель заявки не может принять ее от имени отправителя, и никто не может подружиться без заявки
+	for _, req := range []struct{ personID, relatedID int }{{3, 1}, {1, 3}, {2, 4}} {
+		_, err := svc.CreateRelationship(ctx, req.personID, &models.CreateRelationshipRequest{RelatedID: req.relatedID, Type: models.RelationshipFriend})
+		if appErr, ok := err.(*errors.AppError); !ok || appErr.Code != http.StatusBadRequest {
+			t.Errorf("friend %d -> %d: expected 400, got %v", req.personID, req.relatedID, err)
+		}
+	}
+
+	if status, _ := repo.GetFriendshipStatus(ctx, 1, 3); status != models.FriendshipPending {
+		t.Errorf("request status = %q, want it still pending", status)
+	}
+	if status, _ := repo.GetFriendshipStatus(ctx, 2, 4); status != "" {
+		t.Errorf("2 -> 4 status = %q, want no friendship", status)
+	}
+}
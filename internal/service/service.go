@@ -4,46 +4,111 @@ import (
 	"PeopleCRUD/internal/cache"
 	"PeopleCRUD/internal/models"
 	"PeopleCRUD/internal/repository"
+	"PeopleCRUD/internal/reqctx"
 	"PeopleCRUD/pkg/errors"
 	"context"
 	"fmt"
 	"github.com/sirupsen/logrus"
+	"io"
 	"strings"
 	"time"
 )
 
 type PersonService interface {
-	CreatePerson(ctx context.Context, req *models.CreatePersonRequest) (*models.PersonWithDetails, error)
+	CreatePerson(ctx context.Context, req *models.CreatePersonRequest, onDuplicate string) (*models.CreatePersonResult, error)
 	GetPersonByID(ctx context.Context, id int) (*models.PersonWithDetails, error)
 	GetPeopleByLastName(ctx context.Context, lastName string) ([]*models.PersonWithDetails, error)
-	GetAllPeople(ctx context.Context, limit, offset int) ([]*models.PersonWithDetails, int, error)
+	GetAllPeople(ctx context.Context, filter models.PeopleFilter, limit, offset int) ([]*models.PersonWithDetails, int, error)
 	UpdatePerson(ctx context.Context, id int, req *models.UpdatePersonRequest) (*models.PersonWithDetails, error)
 	DeletePerson(ctx context.Context, id int) error
 	AddEmail(ctx context.Context, personID int, email string, isPrimary bool) error
-	AddFriend(ctx context.Context, personID, friendID int) error
 	GetFriends(ctx context.Context, personID int) ([]models.Person, error)
 	RemoveFriend(ctx context.Context, personID, friendID int) error
+	GetDeletedPeople(ctx context.Context, limit, offset int) ([]*models.Person, int, error)
+	RestorePerson(ctx context.Context, id int) (*models.PersonWithDetails, error)
+	PurgeDeletedPeople(ctx context.Context, retention time.Duration) (int, error)
+	GetPersonAsOf(ctx context.Context, id int, asOf time.Time) (*models.PersonWithDetails, error)
+	GetPersonVersions(ctx context.Context, id int) ([]models.PersonVersion, error)
+	RevertPerson(ctx context.Context, id, version int) (*models.PersonWithDetails, error)
+	BulkCreatePeople(ctx context.Context, reqs []models.CreatePersonRequest, mode string) (*models.BulkResponse, error)
+	BulkUpdatePeople(ctx context.Context, items []models.BulkUpdateItem, mode string) (*models.BulkResponse, error)
+	BulkDeletePeople(ctx context.Context, ids []int, mode string) (*models.BulkResponse, error)
+	ImportPeople(ctx context.Context, format string, source io.Reader, mapping map[string]string, dryRun bool) (*models.ImportResult, error)
+	GetImportReport(ctx context.Context, reportID string) ([]models.ImportRejection, error)
+	ExportPeople(ctx context.Context, filter models.PeopleFilter, fn func(*models.PersonExport) error) error
+	FindDuplicates(ctx context.Context, req *models.CreatePersonRequest) ([]models.DuplicateCandidate, error)
+	GetDuplicateClusters(ctx context.Context) ([]models.DuplicateCluster, error)
+	MergePeople(ctx context.Context, keepID, otherID int) (*models.PersonWithDetails, error)
+	SendFriendRequest(ctx context.Context, fromID, toID int) (*models.FriendRequest, error)
+	AcceptFriendRequest(ctx context.Context, personID, fromID int) error
+	RejectFriendRequest(ctx context.Context, personID, fromID int) error
+	CancelFriendRequest(ctx context.Context, personID, toID int) error
+	GetIncomingFriendRequests(ctx context.Context, personID int) ([]models.FriendRequest, error)
+	GetOutgoingFriendRequests(ctx context.Context, personID int) ([]models.FriendRequest, error)
+	CreateRelationship(ctx context.Context, personID int, req *models.CreateRelationshipRequest) (*models.Relationship, error)
+	GetRelationships(ctx context.Context, personID int, relType string) ([]models.Relationship, error)
+	UpdateRelationship(ctx context.Context, personID, relatedID int, relType string, req *models.UpdateRelationshipRequest) (*models.Relationship, error)
+	DeleteRelationship(ctx context.Context, personID, relatedID int, relType string) error
+	GetMutualFriends(ctx context.Context, personID, otherID int) ([]models.Person, error)
+	GetFriendPath(ctx context.Context, personID, otherID, maxDepth int) (*models.FriendPath, error)
+	GetFriendSuggestions(ctx context.Context, personID, limit int) ([]models.FriendSuggestion, error)
+	ExportGraph(ctx context.Context, egoID *int, depth int, nodeFn func(*models.Person) error, edgeFn func(*models.GraphEdge) error) error
+	RefreshGraphAnalytics(ctx context.Context) (*models.GraphAnalytics, error)
+	GetGraphAnalytics(ctx context.Context, top int) (*models.GraphAnalytics, error)
+	GetStats(ctx context.Context, filter models.PeopleFilter, ageBuckets []int, period string) (*models.PeopleStats, error)
+	GetTenants(ctx context.Context) ([]string, error)
 }
 
 type personService struct {
 	repo   repository.PersonRepository
-	cache  *cache.MemoryCache
+	audit  repository.AuditRepository
+	cache  *cache.Loader
 	logger *logrus.Logger
 }
 
-func NewPersonService(repo repository.PersonRepository, cache *cache.MemoryCache, logger *logrus.Logger) PersonService {
+// Теги кэша для группового сброса
+const (
+	peopleListTag = "people"
+	statsTag      = "stats"
+)
+
+// peoplePage - страница листинга в кэше
+type peoplePage struct {
+	People []*models.PersonWithDetails `json:"people"`
+	Total  int                         `json:"total"`
+}
+
+func NewPersonService(repo repository.PersonRepository, audit repository.AuditRepository, cache *cache.Loader, logger *logrus.Logger) PersonService {
 	return &personService{
 		repo:   repo,
+		audit:  audit,
 		cache:  cache,
 		logger: logger,
 	}
 }
 
-func (s *personService) CreatePerson(ctx context.Context, req *models.CreatePersonRequest) (*models.PersonWithDetails, error) {
+// log возвращает запись лога запроса из ctx: с request_id, principal и tenant
+func (s *personService) log(ctx context.Context) *logrus.Entry {
+	return reqctx.Logger(ctx, s.logger)
+}
+
+func (s *personService) CreatePerson(ctx context.Context, req *models.CreatePersonRequest, onDuplicate string) (*models.CreatePersonResult, error) {
 	if err := req.Validate(); err != nil {
 		return nil, err
 	}
 
+	var duplicates []models.DuplicateCandidate
+	if onDuplicate != models.OnDuplicateIgnore {
+		var err error
+		duplicates, err = s.FindDuplicates(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		if len(duplicates) > 0 && onDuplicate == models.OnDuplicateReject {
+			return nil, duplicateConflict(duplicates)
+		}
+	}
+
 	person := &models.Person{
 		FirstName:  strings.TrimSpace(req.FirstName),
 		LastName:   strings.TrimSpace(req.LastName),
@@ -52,57 +117,63 @@ func (s *personService) CreatePerson(ctx context.Context, req *models.CreatePers
 
 	// Создаем человека с транзакцией для email
 	if len(req.Emails) > 0 {
-		err := s.repo.CreateWithTransaction(person, req.Emails)
+		err := s.repo.CreateWithTransaction(ctx, person, req.Emails)
 		if err != nil {
-			s.logger.WithError(err).Error("Failed to create person with emails")
+			s.log(ctx).WithError(err).Error("Failed to create person with emails")
 			return nil, errors.NewInternalServerError(err.Error())
 		}
 	} else {
-		if err := s.repo.Create(person); err != nil {
-			s.logger.WithError(err).Error("Failed to create person")
+		if err := s.repo.Create(ctx, person); err != nil {
+			s.log(ctx).WithError(err).Error("Failed to create person")
 			return nil, errors.NewInternalServerError(err.Error())
 		}
 	}
 
-	return s.GetPersonByID(ctx, person.ID)
+	s.invalidatePeopleLists(ctx)
+	result, err := s.GetPersonByID(ctx, person.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.recordAudit(ctx, models.AuditEntityPerson, models.AuditActionCreate, person.ID, person.ID, nil, result)
+	return &models.CreatePersonResult{PersonWithDetails: result, PossibleDuplicates: duplicates}, nil
 }
 
 func (s *personService) GetPersonByID(ctx context.Context, id int) (*models.PersonWithDetails, error) {
-	cacheKey := fmt.Sprintf("person:%d", id)
-
-	if cached, found := s.cache.Get(cacheKey); found {
-		if person, ok := cached.(*models.PersonWithDetails); ok {
-			s.logger.Debugf("Returning person %d from cache", id)
-			return person, nil
+	return cache.Fetch(ctx, s.cache, personCacheKey(ctx, id), func(ctx context.Context) (*models.PersonWithDetails, []string, error) {
+		s.log(ctx).Debugf("Loading person %d", id)
+		result, err := s.loadPerson(ctx, id)
+		if err != nil {
+			return nil, nil, err
 		}
-	}
+		return result, personRefTags(ctx, result), nil
+	})
+}
 
-	person, err := s.repo.GetByID(id)
+func (s *personService) loadPerson(ctx context.Context, id int) (*models.PersonWithDetails, error) {
+	person, err := s.repo.GetByID(ctx, id)
 	if err != nil {
-		s.logger.WithError(err).Error("Failed to get person by ID")
+		s.log(ctx).WithError(err).Error("Failed to get person by ID")
 		return nil, errors.NewInternalServerError(err.Error())
 	}
 
-	emails, err := s.repo.GetEmails(person.ID)
+	emails, err := s.repo.GetEmails(ctx, person.ID)
 	if err != nil {
-		s.logger.WithError(err).Error("Failed to get person emails")
+		s.log(ctx).WithError(err).Error("Failed to get person emails")
 		emails = []models.Email{}
 	}
 
-	friends, err := s.repo.GetFriends(person.ID)
+	friends, err := s.repo.GetFriends(ctx, person.ID)
 	if err != nil {
-		s.logger.WithError(err).Error("Failed to get person friends")
+		s.log(ctx).WithError(err).Error("Failed to get person friends")
 		friends = []models.Person{}
 	}
 
-	result := &models.PersonWithDetails{
+	return &models.PersonWithDetails{
 		Person:  *person,
 		Emails:  emails,
 		Friends: friends,
-	}
-
-	s.cache.Set(cacheKey, result, 5*time.Minute)
-	return result, nil
+	}, nil
 }
 
 func (s *personService) GetPeopleByLastName(ctx context.Context, lastName string) ([]*models.PersonWithDetails, error) {
@@ -110,9 +181,9 @@ func (s *personService) GetPeopleByLastName(ctx context.Context, lastName string
 		return nil, errors.NewValidationError("Last name cannot be empty")
 	}
 
-	people, err := s.repo.GetByLastName(lastName)
+	people, err := s.repo.GetByLastName(ctx, lastName)
 	if err != nil {
-		s.logger.WithError(err).Error("Failed to get people by last name")
+		s.log(ctx).WithError(err).Error("Failed to get people by last name")
 		return nil, errors.NewInternalServerError(err.Error())
 	}
 
@@ -120,7 +191,7 @@ func (s *personService) GetPeopleByLastName(ctx context.Context, lastName string
 	for i, person := range people {
 		details, err := s.GetPersonByID(ctx, person.ID)
 		if err != nil {
-			s.logger.WithError(err).Error("Failed to get person details")
+			s.log(ctx).WithError(err).Error("Failed to get person details")
 			continue
 		}
 		result[i] = details
@@ -129,51 +200,47 @@ func (s *personService) GetPeopleByLastName(ctx context.Context, lastName string
 	return result, nil
 }
 
-func (s *personService) GetAllPeople(ctx context.Context, limit, offset int) ([]*models.PersonWithDetails, int, error) {
-	cacheKey := fmt.Sprintf("people:limit=%d&offset=%d", limit, offset)
+func (s *personService) GetAllPeople(ctx context.Context, filter models.PeopleFilter, limit, offset int) ([]*models.PersonWithDetails, int, error) {
+	cacheKey := tenantCacheKey(ctx, fmt.Sprintf("people:limit=%d&offset=%d&%s", limit, offset, filter.CacheKey()))
 
-	if cached, found := s.cache.Get(cacheKey); found {
-		if data, ok := cached.(struct {
-			People []*models.PersonWithDetails
-			Total  int
-		}); ok {
-			s.logger.Debug("Returning people list from cache")
-			return data.People, data.Total, nil
+	page, err := cache.Fetch(ctx, s.cache, cacheKey, func(ctx context.Context) (peoplePage, []string, error) {
+		s.log(ctx).Debug("Loading people list")
+		page, err := s.loadPeoplePage(ctx, filter, limit, offset)
+		if err != nil {
+			return peoplePage{}, nil, err
 		}
+		return page, append(personRefTags(ctx, page.People...), tenantCacheKey(ctx, peopleListTag)), nil
+	})
+	if err != nil {
+		return nil, 0, err
 	}
+	return page.People, page.Total, nil
+}
 
-	people, err := s.repo.GetAll(limit, offset)
+func (s *personService) loadPeoplePage(ctx context.Context, filter models.PeopleFilter, limit, offset int) (peoplePage, error) {
+	people, err := s.repo.GetAll(ctx, filter, limit, offset)
 	if err != nil {
-		s.logger.WithError(err).Error("Failed to get all people")
-		return nil, 0, errors.NewInternalServerError(err.Error())
+		s.log(ctx).WithError(err).Error("Failed to get all people")
+		return peoplePage{}, errors.NewInternalServerError(err.Error())
 	}
 
-	total, err := s.repo.GetCount()
+	total, err := s.repo.GetCount(ctx, filter)
 	if err != nil {
-		s.logger.WithError(err).Error("Failed to get people count")
-		return nil, 0, errors.NewInternalServerError("Failed to get people count")
+		s.log(ctx).WithError(err).Error("Failed to get people count")
+		return peoplePage{}, errors.NewInternalServerError("Failed to get people count")
 	}
 
 	result := make([]*models.PersonWithDetails, len(people))
 	for i, person := range people {
 		details, err := s.GetPersonByID(ctx, person.ID)
 		if err != nil {
-			s.logger.WithError(err).Error("Failed to get person details")
+			s.log(ctx).WithError(err).Error("Failed to get person details")
 			continue
 		}
 		result[i] = details
 	}
 
-	cacheData := struct {
-		People []*models.PersonWithDetails
-		Total  int
-	}{
-		People: result,
-		Total:  total,
-	}
-
-	s.cache.Set(cacheKey, cacheData, 1*time.Minute)
-	return result, total, nil
+	return peoplePage{People: result, Total: total}, nil
 }
 
 func (s *personService) UpdatePerson(ctx context.Context, id int, req *models.UpdatePersonRequest) (*models.PersonWithDetails, error) {
@@ -181,138 +248,337 @@ func (s *personService) UpdatePerson(ctx context.Context, id int, req *models.Up
 		return nil, err
 	}
 
-	if _, err := s.repo.GetByID(id); err != nil {
-		s.logger.WithError(err).Error("Failed to check person existence")
+	return s.updatePerson(ctx, id, func() error {
+		return s.repo.Update(ctx, id, req)
+	})
+}
+
+// updatePerson выполняет запись полей человека write, сбрасывает кэш и пишет аудит изменения
+func (s *personService) updatePerson(ctx context.Context, id int, write func() error) (*models.PersonWithDetails, error) {
+	before, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		s.log(ctx).WithError(err).Error("Failed to check person existence")
 		return nil, errors.NewInternalServerError(err.Error())
 	}
 
-	if err := s.repo.Update(id, req); err != nil {
-		s.logger.WithError(err).Error("Failed to update person")
+	if err := write(); err != nil {
+		s.log(ctx).WithError(err).Error("Failed to update person")
 		return nil, errors.NewInternalServerError("Failed to update person")
 	}
 
-	s.invalidatePersonCache(id)
-	return s.GetPersonByID(ctx, id)
+	s.invalidatePersonCache(ctx, id)
+	result, err := s.GetPersonByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	s.recordAudit(ctx, models.AuditEntityPerson, models.AuditActionUpdate, id, id, before, &result.Person)
+	return result, nil
 }
 
 func (s *personService) DeletePerson(ctx context.Context, id int) error {
-	if _, err := s.repo.GetByID(id); err != nil {
-		s.logger.WithError(err).Error("Failed to check person existence")
+	before, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		s.log(ctx).WithError(err).Error("Failed to check person existence")
 		return errors.NewInternalServerError("Failed to delete person")
 	}
 
-	if err := s.repo.Delete(id); err != nil {
-		s.logger.WithError(err).Error("Failed to delete person")
+	if err := s.repo.Delete(ctx, id); err != nil {
+		s.log(ctx).WithError(err).Error("Failed to delete person")
 		return errors.NewInternalServerError(err.Error())
 	}
 
-	s.invalidatePersonCache(id)
+	s.invalidatePersonCache(ctx, id)
+	s.recordAudit(ctx, models.AuditEntityPerson, models.AuditActionDelete, id, id, before, nil)
 	return nil
 }
 
 func (s *personService) AddEmail(ctx context.Context, personID int, email string, isPrimary bool) error {
-	if _, err := s.repo.GetByID(personID); err != nil {
-		s.logger.WithError(err).Error("Failed to check person existence")
+	if _, err := s.repo.GetByID(ctx, personID); err != nil {
+		s.log(ctx).WithError(err).Error("Failed to check person existence")
 		return errors.NewInternalServerError("Failed to add email")
 	}
 
 	if isPrimary {
-		emails, err := s.repo.GetEmails(personID)
+		emails, err := s.repo.GetEmails(ctx, personID)
 		if err != nil {
-			s.logger.WithError(err).Error("Failed to get person emails")
+			s.log(ctx).WithError(err).Error("Failed to get person emails")
 			return errors.NewInternalServerError("Failed to update emails")
 		}
 
 		for _, e := range emails {
 			if e.IsPrimary {
-				if err := s.repo.UpdateEmail(e.ID, e.Email, false); err != nil {
-					s.logger.WithError(err).Error("Failed to update email")
+				if err := s.repo.UpdateEmail(ctx, e.ID, e.Email, false); err != nil {
+					s.log(ctx).WithError(err).Error("Failed to update email")
 					return errors.NewInternalServerError("Failed to update emails")
 				}
+				demoted := e
+				demoted.IsPrimary = false
+				s.recordAudit(ctx, models.AuditEntityEmail, models.AuditActionUpdate, e.ID, personID, e, demoted)
 			}
 		}
 	}
 
-	if err := s.repo.AddEmail(personID, email, isPrimary); err != nil {
-		s.logger.WithError(err).Error("Failed to add email")
+	emailID, err := s.repo.AddEmail(ctx, personID, email, isPrimary)
+	if err != nil {
+		s.log(ctx).WithError(err).Error("Failed to add email")
 		return errors.NewInternalServerError("Failed to add email")
 	}
 
+	s.invalidatePersonRefs(ctx, personID)
+	s.cache.InvalidateTag(tenantCacheKey(ctx, statsTag))
+	s.recordAudit(ctx, models.AuditEntityEmail, models.AuditActionCreate, emailID, personID, nil,
+		models.Email{ID: emailID, PersonID: personID, Email: email, IsPrimary: isPrimary})
 	return nil
 }
 
-func (s *personService) AddFriend(ctx context.Context, personID, friendID int) error {
-	if personID == friendID {
-		return errors.NewValidationError("Cannot add yourself as a friend")
+func (s *personService) GetFriends(ctx context.Context, personID int) ([]models.Person, error) {
+	if _, err := s.repo.GetByID(ctx, personID); err != nil {
+		s.log(ctx).WithError(err).Error("Failed to check person existence")
+		return nil, errors.NewInternalServerError("Failed to get friends")
 	}
 
-	if _, err := s.repo.GetByID(personID); err != nil {
-		s.logger.WithError(err).Error("Failed to check person existence")
-		return errors.NewInternalServerError("Failed to add friend")
+	friends, err := s.repo.GetFriends(ctx, personID)
+	if err != nil {
+		s.log(ctx).WithError(err).Error("Failed to get friends")
+		return nil, errors.NewInternalServerError("Failed to get friends")
 	}
 
-	if _, err := s.repo.GetByID(friendID); err != nil {
-		s.logger.WithError(err).Error("Failed to check friend existence")
-		return errors.NewInternalServerError("Failed to add friend")
+	return friends, nil
+}
+
+func (s *personService) RemoveFriend(ctx context.Context, personID, friendID int) error {
+	if err := s.repo.RemoveFriend(ctx, personID, friendID); err != nil {
+		s.log(ctx).WithError(err).Error("Failed to remove friend")
+		return errors.NewInternalServerError("Failed to remove friend")
 	}
 
-	friends, err := s.repo.GetFriends(personID)
+	if err := s.repo.RemoveFriend(ctx, friendID, personID); err != nil {
+		s.repo.AddFriend(ctx, personID, friendID)
+		s.log(ctx).WithError(err).Error("Failed to remove reciprocal friendship")
+		return errors.NewInternalServerError("Failed to remove friend")
+	}
+
+	s.invalidatePersonRefs(ctx, personID, friendID)
+	s.recordFriendshipAudit(ctx, models.AuditActionDelete, personID, friendID)
+	return nil
+}
+
+func (s *personService) GetDeletedPeople(ctx context.Context, limit, offset int) ([]*models.Person, int, error) {
+	people, err := s.repo.GetDeleted(ctx, limit, offset)
 	if err != nil {
-		s.logger.WithError(err).Error("Failed to get friends list")
-		return errors.NewInternalServerError("Failed to add friend")
+		s.log(ctx).WithError(err).Error("Failed to get deleted people")
+		return nil, 0, err
 	}
 
-	for _, friend := range friends {
-		if friend.ID == friendID {
-			return errors.NewValidationError("Friendship already exists")
-		}
+	total, err := s.repo.GetDeletedCount(ctx)
+	if err != nil {
+		s.log(ctx).WithError(err).Error("Failed to get deleted people count")
+		return nil, 0, err
 	}
 
-	if err := s.repo.AddFriend(personID, friendID); err != nil {
-		s.logger.WithError(err).Error("Failed to add friend")
-		return errors.NewInternalServerError("Failed to add friend")
+	return people, total, nil
+}
+
+// RestorePerson возвращает человека из корзины. Карточки его друзей загружались, пока он был удален,
+// поэтому не помечены его тегом и сбрасываются отдельно: иначе до конца TTL в них не будет восстановленного
+func (s *personService) RestorePerson(ctx context.Context, id int) (*models.PersonWithDetails, error) {
+	friends, err := s.repo.GetFriends(ctx, id)
+	if err != nil {
+		s.log(ctx).WithError(err).Error("Failed to get friends of restored person")
+		return nil, err
 	}
 
-	if err := s.repo.AddFriend(friendID, personID); err != nil {
-		s.repo.RemoveFriend(personID, friendID)
-		s.logger.WithError(err).Error("Failed to add reciprocal friendship")
-		return errors.NewInternalServerError("Failed to add friend")
+	if err := s.repo.Restore(ctx, id); err != nil {
+		s.log(ctx).WithError(err).Error("Failed to restore person")
+		return nil, err
 	}
 
-	return nil
+	friendIDs := make([]int, len(friends))
+	for i, friend := range friends {
+		friendIDs[i] = friend.ID
+	}
+	s.invalidatePersonRefs(ctx, friendIDs...)
+	s.invalidatePersonCache(ctx, id)
+	s.recordAudit(ctx, models.AuditEntityPerson, models.AuditActionRestore, id, id, nil, nil)
+	return s.GetPersonByID(ctx, id)
 }
 
-func (s *personService) GetFriends(ctx context.Context, personID int) ([]models.Person, error) {
-	if _, err := s.repo.GetByID(personID); err != nil {
-		s.logger.WithError(err).Error("Failed to check person existence")
-		return nil, errors.NewInternalServerError("Failed to get friends")
+func (s *personService) PurgeDeletedPeople(ctx context.Context, retention time.Duration) (int, error) {
+	// deleted_at - TIMESTAMP без зоны в UTC, смещение локального времени при сравнении отбросилось бы
+	purged, err := s.repo.PurgeDeleted(ctx, time.Now().UTC().Add(-retention))
+	if err != nil {
+		s.log(ctx).WithError(err).Error("Failed to purge deleted people")
+		return 0, err
 	}
 
-	friends, err := s.repo.GetFriends(personID)
+	for _, id := range purged {
+		s.recordAudit(ctx, models.AuditEntityPerson, models.AuditActionPurge, id, id, nil, nil)
+	}
+
+	if len(purged) > 0 {
+		s.invalidatePersonCache(ctx, purged...)
+		s.log(ctx).WithField("count", len(purged)).Info("Purged deleted people")
+	}
+	return len(purged), nil
+}
+
+// GetTenants возвращает тенантов с данными, для фоновых задач
+func (s *personService) GetTenants(ctx context.Context) ([]string, error) {
+	tenants, err := s.repo.GetTenants(ctx)
 	if err != nil {
-		s.logger.WithError(err).Error("Failed to get friends")
-		return nil, errors.NewInternalServerError("Failed to get friends")
+		s.log(ctx).WithError(err).Error("Failed to get tenants")
+		return nil, err
 	}
+	return tenants, nil
+}
 
-	return friends, nil
+func (s *personService) GetPersonAsOf(ctx context.Context, id int, asOf time.Time) (*models.PersonWithDetails, error) {
+	// Колонки истории - TIMESTAMP без зоны в UTC. Смещение из RFC3339 при сравнении с ними отбрасывается,
+	// поэтому момент переводится в UTC до запроса
+	asOf = asOf.UTC()
+	person, err := s.repo.GetByIDAsOf(ctx, id, asOf)
+	if err != nil {
+		s.log(ctx).WithError(err).Error("Failed to get person as of time")
+		return nil, err
+	}
+
+	emails, err := s.repo.GetEmailsAsOf(ctx, id, asOf)
+	if err != nil {
+		s.log(ctx).WithError(err).Error("Failed to get person emails as of time")
+		return nil, err
+	}
+
+	friends, err := s.repo.GetFriendsAsOf(ctx, id, asOf)
+	if err != nil {
+		s.log(ctx).WithError(err).Error("Failed to get person friends as of time")
+		return nil, err
+	}
+
+	return &models.PersonWithDetails{
+		Person:  *person,
+		Emails:  emails,
+		Friends: friends,
+	}, nil
 }
 
-func (s *personService) RemoveFriend(ctx context.Context, personID, friendID int) error {
-	if err := s.repo.RemoveFriend(personID, friendID); err != nil {
-		s.logger.WithError(err).Error("Failed to remove friend")
-		return errors.NewInternalServerError("Failed to remove friend")
+func (s *personService) GetPersonVersions(ctx context.Context, id int) ([]models.PersonVersion, error) {
+	versions, err := s.repo.GetVersions(ctx, id)
+	if err != nil {
+		s.log(ctx).WithError(err).Error("Failed to get person versions")
+		return nil, err
 	}
+	if len(versions) == 0 {
+		return nil, errors.NewNotFoundError("Person not found")
+	}
+	return versions, nil
+}
 
-	if err := s.repo.RemoveFriend(friendID, personID); err != nil {
-		s.repo.AddFriend(personID, friendID)
-		s.logger.WithError(err).Error("Failed to remove reciprocal friendship")
-		return errors.NewInternalServerError("Failed to remove friend")
+// RevertPerson возвращает поля человека к указанной версии целиком: поля, пустые в ней, очищаются.
+// Валидация, инвалидация кэша и аудит те же, что у UpdatePerson
+func (s *personService) RevertPerson(ctx context.Context, id, version int) (*models.PersonWithDetails, error) {
+	target, err := s.repo.GetVersion(ctx, id, version)
+	if err != nil {
+		s.log(ctx).WithError(err).Error("Failed to get person version")
+		return nil, err
+	}
+
+	req := &models.UpdatePersonRequest{
+		FirstName:   &target.Person.FirstName,
+		LastName:    &target.Person.LastName,
+		MiddleName:  target.Person.MiddleName,
+		Age:         target.Person.Age,
+		Gender:      target.Person.Gender,
+		Nationality: target.Person.Nationality,
+	}
+	if err := req.Validate(); err != nil {
+		return nil, err
 	}
 
+	return s.updatePerson(ctx, id, func() error {
+		return s.repo.Replace(ctx, id, &target.Person)
+	})
+}
+
+// ExportPeople потоково передает в fn всех людей по фильтру вместе с email'ами и id друзей
+func (s *personService) ExportPeople(ctx context.Context, filter models.PeopleFilter, fn func(*models.PersonExport) error) error {
+	if err := s.repo.StreamPeople(ctx, filter, fn); err != nil {
+		s.log(ctx).WithError(err).Error("Failed to export people")
+		return err
+	}
 	return nil
 }
 
-func (s *personService) invalidatePersonCache(id int) {
-	s.cache.Delete(fmt.Sprintf("person:%d", id))
-	s.cache.DeleteByPrefix("people:")
+// recordFriendshipAudit пишет событие для обеих сторон, чтобы оно попало в историю каждого
+func (s *personService) recordFriendshipAudit(ctx context.Context, action string, personID, friendID int) {
+	link := map[string]int{"person_id": personID, "friend_id": friendID}
+	reverse := map[string]int{"person_id": friendID, "friend_id": personID}
+
+	var before, after, reverseBefore, reverseAfter interface{}
+	if action == models.AuditActionDelete {
+		before, reverseBefore = link, reverse
+	} else {
+		after, reverseAfter = link, reverse
+	}
+
+	s.recordAudit(ctx, models.AuditEntityFriendship, action, friendID, personID, before, after)
+	s.recordAudit(ctx, models.AuditEntityFriendship, action, personID, friendID, reverseBefore, reverseAfter)
+}
+
+// invalidatePersonCache сбрасывает все, что зависит от полей этих людей: записи, где они встречаются,
+// списки (фильтр мог начать или перестать их выбирать) и статистику
+func (s *personService) invalidatePersonCache(ctx context.Context, ids ...int) {
+	s.invalidatePersonRefs(ctx, ids...)
+	s.invalidatePeopleLists(ctx)
+}
+
+// invalidatePersonRefs удаляет записи, в которые встроены эти люди: их карточки,
+// карточки их друзей и страницы списков. Подходит для правок email'ов и связей, не меняющих состав выборок
+func (s *personService) invalidatePersonRefs(ctx context.Context, ids ...int) {
+	for _, id := range ids {
+		s.cache.Delete(personCacheKey(ctx, id))
+		s.cache.InvalidateTag(personRefTag(ctx, id))
+	}
+}
+
+// invalidatePeopleLists сбрасывает списки и статистику, когда меняется состав людей
+func (s *personService) invalidatePeopleLists(ctx context.Context) {
+	s.cache.InvalidateTag(tenantCacheKey(ctx, peopleListTag))
+	s.cache.InvalidateTag(tenantCacheKey(ctx, statsTag))
+}
+
+// tenantCacheKey ограничивает ключ или тег кэша тенантом запроса
+func tenantCacheKey(ctx context.Context, key string) string {
+	return cache.TenantKey(reqctx.Tenant(ctx), key)
+}
+
+func personCacheKey(ctx context.Context, id int) string {
+	return tenantCacheKey(ctx, fmt.Sprintf("person:%d", id))
+}
+
+func personRefTag(ctx context.Context, id int) string {
+	return tenantCacheKey(ctx, fmt.Sprintf("person-ref:%d", id))
+}
+
+// personRefTags перечисляет теги всех людей, встроенных в значение: самих людей и их друзей
+func personRefTags(ctx context.Context, people ...*models.PersonWithDetails) []string {
+	seen := make(map[int]bool)
+	var tags []string
+	add := func(id int) {
+		if !seen[id] {
+			seen[id] = true
+			tags = append(tags, personRefTag(ctx, id))
+		}
+	}
+
+	for _, person := range people {
+		if person == nil {
+			continue
+		}
+		add(person.ID)
+		for _, friend := range person.Friends {
+			add(friend.ID)
+		}
+	}
+	return tags
 }
@@ -0,0 +1,54 @@
+package service
+
+import (
+	"PeopleCRUD/internal/cache"
+	"PeopleCRUD/internal/models"
+	"PeopleCRUD/pkg/errors"
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const maxAgeBucketNum = 50
+
+// GetStats возвращает демографические агрегаты по людям под фильтром. ageBuckets == nil - границы по умолчанию
+func (s *personService) GetStats(ctx context.Context, filter models.PeopleFilter, ageBuckets []int, period string) (*models.PeopleStats, error) {
+	if period == "" {
+		period = models.StatsPeriodDay
+	}
+	if period != models.StatsPeriodDay && period != models.StatsPeriodWeek {
+		return nil, errors.NewValidationError("period must be day or week")
+	}
+
+	if ageBuckets == nil {
+		ageBuckets = models.DefaultAgeBuckets
+	}
+	if len(ageBuckets) > maxAgeBucketNum {
+		return nil, errors.NewValidationError(fmt.Sprintf("At most %d age buckets are allowed", maxAgeBucketNum))
+	}
+	for i, bound := range ageBuckets {
+		if bound < 0 || i > 0 && bound <= ageBuckets[i-1] {
+			return nil, errors.NewValidationError("age_buckets must be non-negative and strictly increasing")
+		}
+	}
+	// Возраст младше первой границы попал бы мимо гистограммы
+	if len(ageBuckets) == 0 || ageBuckets[0] != 0 {
+		ageBuckets = append([]int{0}, ageBuckets...)
+	}
+
+	bounds := make([]string, len(ageBuckets))
+	for i, bound := range ageBuckets {
+		bounds[i] = strconv.Itoa(bound)
+	}
+	cacheKey := tenantCacheKey(ctx, fmt.Sprintf("stats:buckets=%s&period=%s&%s", strings.Join(bounds, ","), period, filter.CacheKey()))
+
+	return cache.Fetch(ctx, s.cache, cacheKey, func(ctx context.Context) (*models.PeopleStats, []string, error) {
+		stats, err := s.repo.GetStats(ctx, filter, ageBuckets, period)
+		if err != nil {
+			s.log(ctx).WithError(err).Error("Failed to get stats")
+			return nil, nil, err
+		}
+		return stats, []string{tenantCacheKey(ctx, statsTag)}, nil
+	})
+}
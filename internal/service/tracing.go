@@ -0,0 +1,337 @@
+package service
+
+import (
+	"PeopleCRUD/internal/models"
+	"PeopleCRUD/internal/reqctx"
+	"PeopleCRUD/pkg/errors"
+	"context"
+	"io"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("PeopleCRUD/internal/service")
+
+// WithTracing оборачивает сервис так, что каждый вызов метода создает дочерний span текущего запроса.
+// Вызовы методов изнутри сервиса отдельных span не получают
+func WithTracing(next PersonService) PersonService {
+	return &tracedPersonService{next: next}
+}
+
+type tracedPersonService struct {
+	next PersonService
+}
+
+func startSpan(ctx context.Context, method string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	attrs = append(attrs, attribute.String("tenant", reqctx.Tenant(ctx)))
+	return tracer.Start(ctx, "PersonService."+method, trace.WithAttributes(attrs...))
+}
+
+// endSpan закрывает span. Ошибки клиента (4xx) записываются событием, но span ошибочным не помечают
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		if appErr, ok := err.(*errors.AppError); !ok || appErr.Code >= 500 {
+			span.SetStatus(codes.Error, err.Error())
+		}
+	}
+	span.End()
+}
+
+func (s *tracedPersonService) CreatePerson(ctx context.Context, req *models.CreatePersonRequest, onDuplicate string) (*models.CreatePersonResult, error) {
+	ctx, span := startSpan(ctx, "CreatePerson")
+	result, err := s.next.CreatePerson(ctx, req, onDuplicate)
+	endSpan(span, err)
+	return result, err
+}
+
+func (s *tracedPersonService) GetPersonByID(ctx context.Context, id int) (*models.PersonWithDetails, error) {
+	ctx, span := startSpan(ctx, "GetPersonByID", attribute.Int("id", id))
+	result, err := s.next.GetPersonByID(ctx, id)
+	endSpan(span, err)
+	return result, err
+}
+
+func (s *tracedPersonService) GetPeopleByLastName(ctx context.Context, lastName string) ([]*models.PersonWithDetails, error) {
+	ctx, span := startSpan(ctx, "GetPeopleByLastName")
+	result, err := s.next.GetPeopleByLastName(ctx, lastName)
+	endSpan(span, err)
+	return result, err
+}
+
+func (s *tracedPersonService) GetAllPeople(ctx context.Context, filter models.PeopleFilter, limit, offset int) ([]*models.PersonWithDetails, int, error) {
+	ctx, span := startSpan(ctx, "GetAllPeople")
+	result, total, err := s.next.GetAllPeople(ctx, filter, limit, offset)
+	endSpan(span, err)
+	return result, total, err
+}
+
+func (s *tracedPersonService) UpdatePerson(ctx context.Context, id int, req *models.UpdatePersonRequest) (*models.PersonWithDetails, error) {
+	ctx, span := startSpan(ctx, "UpdatePerson", attribute.Int("id", id))
+	result, err := s.next.UpdatePerson(ctx, id, req)
+	endSpan(span, err)
+	return result, err
+}
+
+func (s *tracedPersonService) DeletePerson(ctx context.Context, id int) error {
+	ctx, span := startSpan(ctx, "DeletePerson", attribute.Int("id", id))
+	err := s.next.DeletePerson(ctx, id)
+	endSpan(span, err)
+	return err
+}
+
+func (s *tracedPersonService) AddEmail(ctx context.Context, personID int, email string, isPrimary bool) error {
+	ctx, span := startSpan(ctx, "AddEmail", attribute.Int("person_id", personID))
+	err := s.next.AddEmail(ctx, personID, email, isPrimary)
+	endSpan(span, err)
+	return err
+}
+
+func (s *tracedPersonService) GetFriends(ctx context.Context, personID int) ([]models.Person, error) {
+	ctx, span := startSpan(ctx, "GetFriends", attribute.Int("person_id", personID))
+	result, err := s.next.GetFriends(ctx, personID)
+	endSpan(span, err)
+	return result, err
+}
+
+func (s *tracedPersonService) RemoveFriend(ctx context.Context, personID, friendID int) error {
+	ctx, span := startSpan(ctx, "RemoveFriend", attribute.Int("person_id", personID), attribute.Int("friend_id", friendID))
+	err := s.next.RemoveFriend(ctx, personID, friendID)
+	endSpan(span, err)
+	return err
+}
+
+func (s *tracedPersonService) GetDeletedPeople(ctx context.Context, limit, offset int) ([]*models.Person, int, error) {
+	ctx, span := startSpan(ctx, "GetDeletedPeople")
+	result, total, err := s.next.GetDeletedPeople(ctx, limit, offset)
+	endSpan(span, err)
+	return result, total, err
+}
+
+func (s *tracedPersonService) RestorePerson(ctx context.Context, id int) (*models.PersonWithDetails, error) {
+	ctx, span := startSpan(ctx, "RestorePerson", attribute.Int("id", id))
+	result, err := s.next.RestorePerson(ctx, id)
+	endSpan(span, err)
+	return result, err
+}
+
+func (s *tracedPersonService) PurgeDeletedPeople(ctx context.Context, retention time.Duration) (int, error) {
+	ctx, span := startSpan(ctx, "PurgeDeletedPeople")
+	result, err := s.next.PurgeDeletedPeople(ctx, retention)
+	endSpan(span, err)
+	return result, err
+}
+
+func (s *tracedPersonService) GetPersonAsOf(ctx context.Context, id int, asOf time.Time) (*models.PersonWithDetails, error) {
+	ctx, span := startSpan(ctx, "GetPersonAsOf", attribute.Int("id", id))
+	result, err := s.next.GetPersonAsOf(ctx, id, asOf)
+	endSpan(span, err)
+	return result, err
+}
+
+func (s *tracedPersonService) GetPersonVersions(ctx context.Context, id int) ([]models.PersonVersion, error) {
+	ctx, span := startSpan(ctx, "GetPersonVersions", attribute.Int("id", id))
+	result, err := s.next.GetPersonVersions(ctx, id)
+	endSpan(span, err)
+	return result, err
+}
+
+func (s *tracedPersonService) RevertPerson(ctx context.Context, id, version int) (*models.PersonWithDetails, error) {
+	ctx, span := startSpan(ctx, "RevertPerson", attribute.Int("id", id))
+	result, err := s.next.RevertPerson(ctx, id, version)
+	endSpan(span, err)
+	return result, err
+}
+
+func (s *tracedPersonService) BulkCreatePeople(ctx context.Context, reqs []models.CreatePersonRequest, mode string) (*models.BulkResponse, error) {
+	ctx, span := startSpan(ctx, "BulkCreatePeople")
+	result, err := s.next.BulkCreatePeople(ctx, reqs, mode)
+	endSpan(span, err)
+	return result, err
+}
+
+func (s *tracedPersonService) BulkUpdatePeople(ctx context.Context, items []models.BulkUpdateItem, mode string) (*models.BulkResponse, error) {
+	ctx, span := startSpan(ctx, "BulkUpdatePeople")
+	result, err := s.next.BulkUpdatePeople(ctx, items, mode)
+	endSpan(span, err)
+	return result, err
+}
+
+func (s *tracedPersonService) BulkDeletePeople(ctx context.Context, ids []int, mode string) (*models.BulkResponse, error) {
+	ctx, span := startSpan(ctx, "BulkDeletePeople")
+	result, err := s.next.BulkDeletePeople(ctx, ids, mode)
+	endSpan(span, err)
+	return result, err
+}
+
+func (s *tracedPersonService) ImportPeople(ctx context.Context, format string, source io.Reader, mapping map[string]string, dryRun bool) (*models.ImportResult, error) {
+	ctx, span := startSpan(ctx, "ImportPeople")
+	result, err := s.next.ImportPeople(ctx, format, source, mapping, dryRun)
+	endSpan(span, err)
+	return result, err
+}
+
+func (s *tracedPersonService) GetImportReport(ctx context.Context, reportID string) ([]models.ImportRejection, error) {
+	ctx, span := startSpan(ctx, "GetImportReport")
+	result, err := s.next.GetImportReport(ctx, reportID)
+	endSpan(span, err)
+	return result, err
+}
+
+func (s *tracedPersonService) ExportPeople(ctx context.Context, filter models.PeopleFilter, fn func(*models.PersonExport) error) error {
+	ctx, span := startSpan(ctx, "ExportPeople")
+	err := s.next.ExportPeople(ctx, filter, fn)
+	endSpan(span, err)
+	return err
+}
+
+func (s *tracedPersonService) FindDuplicates(ctx context.Context, req *models.CreatePersonRequest) ([]models.DuplicateCandidate, error) {
+	ctx, span := startSpan(ctx, "FindDuplicates")
+	result, err := s.next.FindDuplicates(ctx, req)
+	endSpan(span, err)
+	return result, err
+}
+
+func (s *tracedPersonService) GetDuplicateClusters(ctx context.Context) ([]models.DuplicateCluster, error) {
+	ctx, span := startSpan(ctx, "GetDuplicateClusters")
+	result, err := s.next.GetDuplicateClusters(ctx)
+	endSpan(span, err)
+	return result, err
+}
+
+func (s *tracedPersonService) MergePeople(ctx context.Context, keepID, otherID int) (*models.PersonWithDetails, error) {
+	ctx, span := startSpan(ctx, "MergePeople", attribute.Int("keep_id", keepID), attribute.Int("other_id", otherID))
+	result, err := s.next.MergePeople(ctx, keepID, otherID)
+	endSpan(span, err)
+	return result, err
+}
+
+func (s *tracedPersonService) SendFriendRequest(ctx context.Context, fromID, toID int) (*models.FriendRequest, error) {
+	ctx, span := startSpan(ctx, "SendFriendRequest", attribute.Int("from_id", fromID), attribute.Int("to_id", toID))
+	result, err := s.next.SendFriendRequest(ctx, fromID, toID)
+	endSpan(span, err)
+	return result, err
+}
+
+func (s *tracedPersonService) AcceptFriendRequest(ctx context.Context, personID, fromID int) error {
+	ctx, span := startSpan(ctx, "AcceptFriendRequest", attribute.Int("person_id", personID), attribute.Int("from_id", fromID))
+	err := s.next.AcceptFriendRequest(ctx, personID, fromID)
+	endSpan(span, err)
+	return err
+}
+
+func (s *tracedPersonService) RejectFriendRequest(ctx context.Context, personID, fromID int) error {
+	ctx, span := startSpan(ctx, "RejectFriendRequest", attribute.Int("person_id", personID), attribute.Int("from_id", fromID))
+	err := s.next.RejectFriendRequest(ctx, personID, fromID)
+	endSpan(span, err)
+	return err
+}
+
+func (s *tracedPersonService) CancelFriendRequest(ctx context.Context, personID, toID int) error {
+	ctx, span := startSpan(ctx, "CancelFriendRequest", attribute.Int("person_id", personID), attribute.Int("to_id", toID))
+	err := s.next.CancelFriendRequest(ctx, personID, toID)
+	endSpan(span, err)
+	return err
+}
+
+func (s *tracedPersonService) GetIncomingFriendRequests(ctx context.Context, personID int) ([]models.FriendRequest, error) {
+	ctx, span := startSpan(ctx, "GetIncomingFriendRequests", attribute.Int("person_id", personID))
+	result, err := s.next.GetIncomingFriendRequests(ctx, personID)
+	endSpan(span, err)
+	return result, err
+}
+
+func (s *tracedPersonService) GetOutgoingFriendRequests(ctx context.Context, personID int) ([]models.FriendRequest, error) {
+	ctx, span := startSpan(ctx, "GetOutgoingFriendRequests", attribute.Int("person_id", personID))
+	result, err := s.next.GetOutgoingFriendRequests(ctx, personID)
+	endSpan(span, err)
+	return result, err
+}
+
+func (s *tracedPersonService) CreateRelationship(ctx context.Context, personID int, req *models.CreateRelationshipRequest) (*models.Relationship, error) {
+	ctx, span := startSpan(ctx, "CreateRelationship", attribute.Int("person_id", personID))
+	result, err := s.next.CreateRelationship(ctx, personID, req)
+	endSpan(span, err)
+	return result, err
+}
+
+func (s *tracedPersonService) GetRelationships(ctx context.Context, personID int, relType string) ([]models.Relationship, error) {
+	ctx, span := startSpan(ctx, "GetRelationships", attribute.Int("person_id", personID))
+	result, err := s.next.GetRelationships(ctx, personID, relType)
+	endSpan(span, err)
+	return result, err
+}
+
+func (s *tracedPersonService) UpdateRelationship(ctx context.Context, personID, relatedID int, relType string, req *models.UpdateRelationshipRequest) (*models.Relationship, error) {
+	ctx, span := startSpan(ctx, "UpdateRelationship", attribute.Int("person_id", personID), attribute.Int("related_id", relatedID))
+	result, err := s.next.UpdateRelationship(ctx, personID, relatedID, relType, req)
+	endSpan(span, err)
+	return result, err
+}
+
+func (s *tracedPersonService) DeleteRelationship(ctx context.Context, personID, relatedID int, relType string) error {
+	ctx, span := startSpan(ctx, "DeleteRelationship", attribute.Int("person_id", personID), attribute.Int("related_id", relatedID))
+	err := s.next.DeleteRelationship(ctx, personID, relatedID, relType)
+	endSpan(span, err)
+	return err
+}
+
+func (s *tracedPersonService) GetMutualFriends(ctx context.Context, personID, otherID int) ([]models.Person, error) {
+	ctx, span := startSpan(ctx, "GetMutualFriends", attribute.Int("person_id", personID), attribute.Int("other_id", otherID))
+	result, err := s.next.GetMutualFriends(ctx, personID, otherID)
+	endSpan(span, err)
+	return result, err
+}
+
+func (s *tracedPersonService) GetFriendPath(ctx context.Context, personID, otherID, maxDepth int) (*models.FriendPath, error) {
+	ctx, span := startSpan(ctx, "GetFriendPath", attribute.Int("person_id", personID), attribute.Int("other_id", otherID))
+	result, err := s.next.GetFriendPath(ctx, personID, otherID, maxDepth)
+	endSpan(span, err)
+	return result, err
+}
+
+func (s *tracedPersonService) GetFriendSuggestions(ctx context.Context, personID, limit int) ([]models.FriendSuggestion, error) {
+	ctx, span := startSpan(ctx, "GetFriendSuggestions", attribute.Int("person_id", personID))
+	result, err := s.next.GetFriendSuggestions(ctx, personID, limit)
+	endSpan(span, err)
+	return result, err
+}
+
+func (s *tracedPersonService) ExportGraph(ctx context.Context, egoID *int, depth int, nodeFn func(*models.Person) error, edgeFn func(*models.GraphEdge) error) error {
+	ctx, span := startSpan(ctx, "ExportGraph")
+	err := s.next.ExportGraph(ctx, egoID, depth, nodeFn, edgeFn)
+	endSpan(span, err)
+	return err
+}
+
+func (s *tracedPersonService) RefreshGraphAnalytics(ctx context.Context) (*models.GraphAnalytics, error) {
+	ctx, span := startSpan(ctx, "RefreshGraphAnalytics")
+	result, err := s.next.RefreshGraphAnalytics(ctx)
+	endSpan(span, err)
+	return result, err
+}
+
+func (s *tracedPersonService) GetGraphAnalytics(ctx context.Context, top int) (*models.GraphAnalytics, error) {
+	ctx, span := startSpan(ctx, "GetGraphAnalytics")
+	result, err := s.next.GetGraphAnalytics(ctx, top)
+	endSpan(span, err)
+	return result, err
+}
+
+func (s *tracedPersonService) GetStats(ctx context.Context, filter models.PeopleFilter, ageBuckets []int, period string) (*models.PeopleStats, error) {
+	ctx, span := startSpan(ctx, "GetStats")
+	result, err := s.next.GetStats(ctx, filter, ageBuckets, period)
+	endSpan(span, err)
+	return result, err
+}
+
+func (s *tracedPersonService) GetTenants(ctx context.Context) ([]string, error) {
+	ctx, span := startSpan(ctx, "GetTenants")
+	result, err := s.next.GetTenants(ctx)
+	endSpan(span, err)
+	return result, err
+}
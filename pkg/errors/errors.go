@@ -8,12 +8,21 @@ type AppError struct {
 	Code    int    `json:"code"`
 	Message string `json:"message"`
 	Details string `json:"details,omitempty"`
+	// RequestID связывает ответ с записями лога запроса
+	RequestID string `json:"request_id,omitempty"`
 }
 
 func (e *AppError) Error() string {
 	return e.Message
 }
 
+// WithRequestID возвращает копию ошибки с id запроса. Сама ошибка может быть общей переменной и не меняется
+func (e *AppError) WithRequestID(requestID string) *AppError {
+	copied := *e
+	copied.RequestID = requestID
+	return &copied
+}
+
 func NewAppError(code int, message, details string) *AppError {
 	return &AppError{
 		Code:    code,
@@ -33,3 +42,15 @@ func NewInternalServerError(message string) *AppError {
 func NewValidationError(details string) *AppError {
 	return NewAppError(http.StatusBadRequest, "Validation failed", details)
 }
+
+func NewConflictError(message string) *AppError {
+	return NewAppError(http.StatusConflict, message, "")
+}
+
+func NewUnauthorizedError(message string) *AppError {
+	return NewAppError(http.StatusUnauthorized, message, "")
+}
+
+func NewForbiddenError(message string) *AppError {
+	return NewAppError(http.StatusForbidden, message, "")
+}